@@ -0,0 +1,178 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How client certificates are checked against the client CA
+const (
+	CLIENT_AUTH_NONE     string = "none"
+	CLIENT_AUTH_OPTIONAL string = "optional"
+	CLIENT_AUTH_REQUIRE  string = "require"
+)
+
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// PEM bundle of the CAs that sign internal clients' certificates
+	ClientCAFile string
+	// none, optional or require. optional verifies certificates that clients
+	// send but also accepts clients without one
+	ClientAuth string
+}
+
+// Serves the certificate in CertFile and KeyFile, replacing it when the files
+// change without restarting the server
+type Reloader struct {
+	cert_file string
+	key_file  string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	mod_time time.Time
+}
+
+func NewReloader(cert_file string, key_file string) (*Reloader, error) {
+	r := &Reloader{cert_file: cert_file, key_file: key_file}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Loads the key pair again. The previous certificate stays in use when the
+// files are invalid, e.g. while they are halfway through being replaced.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.cert_file, r.key_file)
+	if err != nil {
+		return fmt.Errorf("TLS -> %s", err)
+	}
+	mod_time, err := r.modTime()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.mod_time = mod_time
+	return nil
+}
+
+// Latest modification time of the certificate and the key
+func (r *Reloader) modTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.cert_file, r.key_file} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return latest, fmt.Errorf("TLS -> %s", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Checks the files every interval and reloads them when they changed, until stop is closed
+func (r *Reloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		mod_time, err := r.modTime()
+		if err != nil {
+			slog.Warn("Could not check the TLS certificate", "error", err)
+			continue
+		}
+		r.mu.RLock()
+		changed := !mod_time.Equal(r.mod_time)
+		r.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err = r.Reload(); err != nil {
+			slog.Error("Could not reload the TLS certificate", "error", err)
+			continue
+		}
+		slog.Info("Reloaded the TLS certificate", "cert_file", r.cert_file)
+	}
+}
+
+// Used as tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Builds the server's TLS configuration. Its certificate comes from the
+// returned Reloader, which must be watched for the certificate to be reloaded.
+func ServerConfig(opts Options) (*tls.Config, *Reloader, error) {
+	reloader, err := NewReloader(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	switch strings.ToLower(opts.ClientAuth) {
+	case CLIENT_AUTH_NONE, "":
+		return cfg, reloader, nil
+	case CLIENT_AUTH_OPTIONAL:
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case CLIENT_AUTH_REQUIRE:
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, nil, fmt.Errorf("Unknown client auth %q, expected none, optional or require", opts.ClientAuth)
+	}
+
+	if opts.ClientCAFile == "" {
+		return nil, nil, errors.New("Client certificates can not be verified without a client CA file")
+	}
+	pem, err := os.ReadFile(opts.ClientCAFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("TLS -> %s", err)
+	}
+	cfg.ClientCAs = x509.NewCertPool()
+	if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, nil, fmt.Errorf("No certificates found in %s", opts.ClientCAFile)
+	}
+	return cfg, reloader, nil
+}
+
+// Redirects plain HTTP requests to the same URL over HTTPS. https_port is
+// added to the host unless it is empty or 443.
+func RedirectHandler(https_port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if https_port != "" && https_port != "443" {
+			host = net.JoinHostPort(host, https_port)
+		}
+		target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+}
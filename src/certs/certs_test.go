@@ -0,0 +1,207 @@
+package certs_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vl0000/gomessenger/certs"
+)
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	tls      tls.Certificate
+	cert_pem []byte
+	key_pem  []byte
+}
+
+// Issues a certificate for localhost signed by parent, or self-signed when parent is nil
+func issueCert(t *testing.T, serial int64, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signer, signer_key := template, key
+	if parent != nil {
+		signer, signer_key = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signer_key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key_der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testCert{key: key}
+	c.cert, _ = x509.ParseCertificate(der)
+	c.cert_pem = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	c.key_pem = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key_der})
+	if c.tls, err = tls.X509KeyPair(c.cert_pem, c.key_pem); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func writeCert(t *testing.T, dir string, c *testCert, mod_time time.Time) (string, string) {
+	cert_file, key_file := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	for path, content := range map[string][]byte{cert_file: c.cert_pem, key_file: c.key_pem} {
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod_time, mod_time); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cert_file, key_file
+}
+
+func TestCerts(t *testing.T) {
+	t.Run("Certificate is reloaded when its files change", func(t *testing.T) {
+		// SETUP
+		dir := t.TempDir()
+		cert_file, key_file := writeCert(t, dir, issueCert(t, 1, nil), time.Now().Add(-time.Minute))
+		reloader, err := certs.NewReloader(cert_file, key_file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stop := make(chan struct{})
+		defer close(stop)
+		// END SETUP
+
+		go reloader.Watch(10*time.Millisecond, stop)
+		writeCert(t, dir, issueCert(t, 2, nil), time.Now())
+
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			cert, _ := reloader.GetCertificate(nil)
+			if cert.Leaf.SerialNumber.Int64() == 2 {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("Certificate was not reloaded")
+	})
+
+	t.Run("Invalid files keep the previous certificate", func(t *testing.T) {
+		// SETUP
+		dir := t.TempDir()
+		cert_file, key_file := writeCert(t, dir, issueCert(t, 1, nil), time.Now())
+		reloader, err := certs.NewReloader(cert_file, key_file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		if err = os.WriteFile(cert_file, []byte("not a certificate"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err = reloader.Reload(); err == nil {
+			t.Fatal("Expected an error")
+		}
+		if cert, _ := reloader.GetCertificate(nil); cert.Leaf.SerialNumber.Int64() != 1 {
+			t.Fatal("Previous certificate was replaced")
+		}
+	})
+
+	t.Run("Client certificates are required", func(t *testing.T) {
+		// SETUP
+		dir := t.TempDir()
+		server_cert := issueCert(t, 1, nil)
+		cert_file, key_file := writeCert(t, dir, server_cert, time.Now())
+		client_ca := issueCert(t, 2, nil)
+		ca_file := filepath.Join(dir, "client-ca.pem")
+		if err := os.WriteFile(ca_file, client_ca.cert_pem, 0600); err != nil {
+			t.Fatal(err)
+		}
+		tls_cfg, _, err := certs.ServerConfig(certs.Options{
+			CertFile:     cert_file,
+			KeyFile:      key_file,
+			ClientCAFile: ca_file,
+			ClientAuth:   certs.CLIENT_AUTH_REQUIRE,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// httptest's TLS server would replace the certificate with its own
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := &http.Server{
+			Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			TLSConfig: tls_cfg,
+			ErrorLog:  log.New(io.Discard, "", 0),
+		}
+		go srv.ServeTLS(listener, "", "")
+		defer srv.Close()
+		url := "https://" + listener.Addr().String()
+
+		roots := x509.NewCertPool()
+		roots.AddCert(server_cert.cert)
+		client := func(certificates ...tls.Certificate) *http.Client {
+			return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      roots,
+				Certificates: certificates,
+			}}}
+		}
+		// END SETUP
+
+		if _, err = client().Get(url); err == nil {
+			t.Fatal("Client without a certificate was accepted")
+		}
+		if _, err = client(issueCert(t, 3, nil).tls).Get(url); err == nil {
+			t.Fatal("Client with an unknown certificate was accepted")
+		}
+		res, err := client(issueCert(t, 4, client_ca).tls).Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	})
+
+	t.Run("Plain HTTP is redirected to HTTPS", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		certs.RedirectHandler("8443").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com:8080/chat?user=1", nil))
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Fatalf("Expected 308, got %d", rec.Code)
+		}
+		if location := rec.Header().Get("Location"); location != "https://example.com:8443/chat?user=1" {
+			t.Fatalf("Redirected to %s", location)
+		}
+
+		rec = httptest.NewRecorder()
+		certs.RedirectHandler("443").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+		if location := rec.Header().Get("Location"); location != "https://example.com/" {
+			t.Fatalf("Redirected to %s", location)
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+)
+
+// How long follow waits before reopening a stream the server ended
+const RECONNECT_DELAY time.Duration = time.Second
+
+var errNoSession = errors.New("Not logged in, run login first")
+
+func (c *cli) readPassword() (string, error) {
+	if password := os.Getenv("GOMESSENGER_PASSWORD"); password != "" {
+		return password, nil
+	}
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("No password given: %v", err)
+	}
+	return password, nil
+}
+
+// Adds the cached token to req
+func authorize[T any](c *cli, req *connect.Request[T]) (*connect.Request[T], error) {
+	if c.session.Token == "" {
+		return nil, errNoSession
+	}
+	req.Header().Set("Authorization", c.session.Token)
+	return req, nil
+}
+
+func (c *cli) saveToken(phone_number string, token string) error {
+	c.session.PhoneNumber = phone_number
+	c.session.Token = token
+	return c.session.save(c.session_path)
+}
+
+func (c *cli) register(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	password, err := c.readPassword()
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.RegisterUser(ctx, connect.NewRequest(&messagingv1.RegisterUserRequest{
+		PhoneNumber: args[0],
+		Username:    args[1],
+		Password:    password,
+	}))
+	if err != nil {
+		return err
+	}
+	if err = c.saveToken(args[0], res.Msg.JwtToken); err != nil {
+		return err
+	}
+	return c.out.message(res.Msg, "Registered and logged in as "+args[0])
+}
+
+func (c *cli) login(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	password, err := c.readPassword()
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.Login(ctx, connect.NewRequest(&messagingv1.LoginRequest{
+		PhoneNumber: args[0],
+		Password:    password,
+	}))
+	if err != nil {
+		return err
+	}
+	if err = c.saveToken(args[0], res.Msg.JwtToken); err != nil {
+		return err
+	}
+	return c.out.message(res.Msg, "Logged in as "+args[0])
+}
+
+func (c *cli) send(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	ttl := flags.Duration("ttl", 0, "Delete the message after this long")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 2 {
+		return errUsage
+	}
+
+	req := &messagingv1.SendDirectMessageRequest{
+		Message: &messagingv1.Message{
+			Sender:   c.session.PhoneNumber,
+			Receiver: flags.Arg(0),
+			Content:  strings.Join(flags.Args()[1:], " "),
+		},
+	}
+	if *ttl > 0 {
+		seconds := uint32(ttl.Seconds())
+		req.TtlSeconds = &seconds
+	}
+
+	authorized, err := authorize(c, connect.NewRequest(req))
+	if err != nil {
+		return err
+	}
+	res, err := c.client.SendDirectMessage(ctx, authorized)
+	if err != nil {
+		return err
+	}
+	return c.out.chatMessage(res.Msg.Message)
+}
+
+// Prints the conversation since -since. When follow is set it keeps printing
+// new and removed messages until interrupted, reconnecting when the server restarts.
+func (c *cli) history(ctx context.Context, args []string, follow bool) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	default_since := 24 * time.Hour
+	if follow {
+		default_since = 0
+	}
+	since := flags.Duration("since", default_since, "How far back to start")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	from := time.Now().UTC().Add(-*since)
+	var last_id uint64
+	for {
+		err := c.stream(ctx, flags.Arg(0), from, follow, &last_id)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !follow || connect.CodeOf(err) != connect.CodeUnavailable {
+			return err
+		}
+
+		// Messages sent while reconnecting are in the history of the next stream
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(RECONNECT_DELAY):
+		}
+		from = time.Now().UTC().Add(-RECONNECT_DELAY - time.Minute)
+	}
+}
+
+// Opens one GetDMs stream. Messages up to last_id were already printed.
+func (c *cli) stream(ctx context.Context, other string, from time.Time, follow bool, last_id *uint64) error {
+	req, err := authorize(c, connect.NewRequest(&messagingv1.GetDMsRequest{
+		UserA:    c.session.PhoneNumber,
+		UserB:    other,
+		FromDate: from.Format(time.DateTime),
+	}))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.client.GetDMs(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	for stream.Receive() {
+		res := stream.Msg()
+		for _, message := range res.Messages {
+			if message.GetId() <= *last_id {
+				continue
+			}
+			*last_id = message.GetId()
+			if err = c.out.chatMessage(message); err != nil {
+				return err
+			}
+		}
+		if err = c.out.removed(res); err != nil {
+			return err
+		}
+
+		// The first response holds the history
+		if !follow {
+			return nil
+		}
+	}
+	return stream.Err()
+}
+
+func (c *cli) whois(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	req, err := authorize(c, connect.NewRequest(&messagingv1.GetUserInfoRequest{PhoneNumber: args[0]}))
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.GetUserInfo(ctx, req)
+	if err != nil {
+		return err
+	}
+	return c.out.message(res.Msg, fmt.Sprintf("%s\t%s", res.Msg.PhoneNumber, res.Msg.Username))
+}
@@ -0,0 +1,110 @@
+// Command gomessenger-cli talks to a gomessenger server from the terminal,
+// e.g. to script conversations or to debug streams without a browser.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/vl0000/gomessenger/gen/messaging/v1/messagingv1connect"
+)
+
+const USAGE string = `Usage:
+	gomessenger-cli [flags] register <phone_number> <username>   Creates an account and logs in
+	gomessenger-cli [flags] login <phone_number>                 Logs in and caches the token
+	gomessenger-cli [flags] send [-ttl 1h] <phone_number> <text>  Sends a message
+	gomessenger-cli [flags] history [-since 24h] <phone_number>  Prints a conversation
+	gomessenger-cli [flags] follow [-since 0s] <phone_number>    Prints a conversation as it happens
+	gomessenger-cli [flags] whois <phone_number>                 Shows a user's name
+
+Passwords are read from GOMESSENGER_PASSWORD, or from the first line of stdin.
+
+Flags:
+`
+
+// Everything a command needs
+type cli struct {
+	client  messagingv1connect.MessagingServiceClient
+	session *session
+	// Where the session is saved
+	session_path string
+	stdin        io.Reader
+	out          *printer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New("Invalid arguments")
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("gomessenger-cli", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), USAGE)
+		flags.PrintDefaults()
+	}
+	session_path := flags.String("session", defaultSessionPath(), "File the server address and token are cached in")
+	server_url := flags.String("server", os.Getenv("GOMESSENGER_SERVER"), "Server URL, defaults to the cached one or http://localhost:3000")
+	json_output := flags.Bool("json", false, "Print one JSON object per line")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	s, err := loadSession(*session_path)
+	if err != nil {
+		return err
+	}
+	if *server_url != "" {
+		s.Server = *server_url
+	}
+
+	c := &cli{
+		client:       messagingv1connect.NewMessagingServiceClient(http.DefaultClient, s.Server),
+		session:      s,
+		session_path: *session_path,
+		stdin:        stdin,
+		out:          &printer{w: stdout, json: *json_output},
+	}
+
+	command, command_args := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "register":
+		return c.register(ctx, command_args)
+	case "login":
+		return c.login(ctx, command_args)
+	case "send":
+		return c.send(ctx, command_args)
+	case "history":
+		return c.history(ctx, command_args, false)
+	case "follow":
+		return c.history(ctx, command_args, true)
+	case "whois":
+		return c.whois(ctx, command_args)
+	}
+	flags.Usage()
+	return errUsage
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/vl0000/gomessenger/data"
+	"github.com/vl0000/gomessenger/server"
+)
+
+// Starts a server on a random port and returns its URL
+func startTestServer(t *testing.T) string {
+	opts := data.DefaultOptions()
+	opts.SchemaPath = "./../../data/database.sql"
+	db, err := data.SetupDatabase(filepath.Join(t.TempDir(), "cli.db"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server.MessagingServer{
+		Router:          chi.NewRouter(),
+		Db:              db,
+		TokenAuth:       jwtauth.New("HS256", []byte(os.Getenv("SECRET_KEY")), nil),
+		ShutdownTimeout: time.Second,
+	}
+	s.LoadRoutes()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(listener)
+	t.Cleanup(s.Shutdown)
+	return "http://" + listener.Addr().String()
+}
+
+func TestCLI(t *testing.T) {
+	// SETUP
+	url := startTestServer(t)
+	dir := t.TempDir()
+	// Runs the CLI as alice or bob, each with their own session file
+	cli := func(ctx context.Context, user string, stdin string, stdout io.Writer, args ...string) error {
+		args = append([]string{"-server", url, "-session", filepath.Join(dir, user+".json")}, args...)
+		return run(ctx, args, strings.NewReader(stdin), stdout)
+	}
+	var out strings.Builder
+	if err := cli(context.TODO(), "alice", "secret-password\n", &out, "register", "111-111", "Alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli(context.TODO(), "bob", "other-password\n", &out, "register", "222-222", "Bob"); err != nil {
+		t.Fatal(err)
+	}
+	// END SETUP
+
+	t.Run("Login caches the token", func(t *testing.T) {
+		if err := cli(context.TODO(), "bob", "other-password\n", io.Discard, "login", "222-222"); err != nil {
+			t.Fatal(err)
+		}
+		s, err := loadSession(filepath.Join(dir, "bob.json"))
+		if err != nil {
+			t.Fatal(err)
+		} else if s.PhoneNumber != "222-222" || s.Token == "" || s.Server != url {
+			t.Fatalf("Session was not saved: %+v", s)
+		}
+	})
+
+	t.Run("Sent messages show up in the history as JSON", func(t *testing.T) {
+		if err := cli(context.TODO(), "alice", "", io.Discard, "send", "222-222", "Hello", "Bob"); err != nil {
+			t.Fatal(err)
+		}
+
+		var out strings.Builder
+		if err := cli(context.TODO(), "bob", "", &out, "-json", "history", "111-111"); err != nil {
+			t.Fatal(err)
+		}
+		var message struct {
+			Sender  string `json:"sender"`
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(out.String())), &message); err != nil {
+			t.Fatalf("Output is not a JSON line: %s", out.String())
+		}
+		if message.Sender != "111-111" || message.Content != "Hello Bob" {
+			t.Fatalf("Unexpected message %+v", message)
+		}
+	})
+
+	t.Run("Follow prints new messages", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		reader, writer := io.Pipe()
+		done := make(chan error)
+		go func() {
+			done <- cli(ctx, "bob", "", writer, "follow", "111-111")
+			writer.Close()
+		}()
+
+		received := make(chan string)
+		go func() {
+			lines := bufio.NewScanner(reader)
+			for lines.Scan() {
+				received <- lines.Text()
+			}
+			close(received)
+		}()
+
+		// Messages sent before the stream is registered may be missed, so keep sending
+		deadline := time.After(5 * time.Second)
+	wait:
+		for {
+			if err := cli(context.TODO(), "alice", "", io.Discard, "send", "222-222", "Are you there?"); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case line := <-received:
+				if strings.Contains(line, "Are you there?") {
+					break wait
+				}
+			case <-time.After(200 * time.Millisecond):
+			case <-deadline:
+				t.Fatal("Message never arrived")
+			}
+		}
+
+		cancel()
+		go func() {
+			for range received {
+			}
+		}()
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Commands need a session", func(t *testing.T) {
+		err := cli(context.TODO(), "nobody", "", io.Discard, "whois", "111-111")
+		if !errors.Is(err, errNoSession) {
+			t.Fatalf("Expected errNoSession, got %v", err)
+		}
+
+		var out strings.Builder
+		if err = cli(context.TODO(), "alice", "", &out, "whois", "222-222"); err != nil {
+			t.Fatal(err)
+		} else if !strings.Contains(out.String(), "Bob") {
+			t.Fatalf("Unexpected output %s", out.String())
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+)
+
+// Writes results as text for people or as JSON lines for scripts
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+// JSON output uses the protobuf JSON mapping, so it matches the Connect API
+func (p *printer) message(m proto.Message, text string) error {
+	if p.json {
+		line, err := protojson.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", line)
+		return err
+	}
+	_, err := fmt.Fprintln(p.w, text)
+	return err
+}
+
+func (p *printer) chatMessage(m *messagingv1.Message) error {
+	text := fmt.Sprintf("[%s] %s: %s", m.GetTimestamp(), m.Sender, m.Content)
+	if m.ExpiresAt != nil {
+		text += fmt.Sprintf(" (expires %s)", m.GetExpiresAt())
+	}
+	return p.message(m, text)
+}
+
+// Messages that expired or were deleted, which chats should remove
+func (p *printer) removed(res *messagingv1.GetDMsResponse) error {
+	if len(res.ExpiredIds) == 0 {
+		return nil
+	}
+	return p.message(&messagingv1.GetDMsResponse{ExpiredIds: res.ExpiredIds},
+		fmt.Sprintf("(removed messages %v)", res.ExpiredIds))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const DEFAULT_SERVER string = "http://localhost:3000"
+
+// Cached between runs so that only login needs the password
+type session struct {
+	Server      string `json:"server"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	Token       string `json:"token,omitempty"`
+}
+
+func defaultSessionPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".gomessenger-cli.json"
+	}
+	return filepath.Join(dir, "gomessenger", "cli.json")
+}
+
+// Returns an empty session when path does not exist yet
+func loadSession(path string) (*session, error) {
+	s := &session{Server: DEFAULT_SERVER}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Session -> %s", err)
+	}
+	if err = json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("Session -> %s: %s", path, err)
+	}
+	return s, nil
+}
+
+// The file holds a token, so only the user may read it
+func (s *session) save(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("Session -> %s", err)
+	}
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("Session -> %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const MIN_SECRET_KEY_LEN int = 64
+
+// Every setting of the server. Values are applied in this order, each one
+// overriding the previous: defaults, the YAML file, environment variables, flags.
+//
+// Each field's yaml tag is also its flag name, prefixed with its section,
+// e.g. -database.path. Fields tagged secret are redacted by Print().
+type Config struct {
+	Host      string `yaml:"host" env:"HOST" help:"Address the server listens on"`
+	SecretKey string `yaml:"secret_key" env:"SECRET_KEY" secret:"true" help:"Key used to sign JWTs, at least 64 characters"`
+	// Base64 encoded 32 byte key. Message contents are stored in plaintext without it
+	MessageKey         string `yaml:"message_key" env:"MESSAGE_KEY" secret:"true" help:"Key that encrypts message contents"`
+	MessageKeyPrevious string `yaml:"message_key_previous" env:"MESSAGE_KEY_PREVIOUS" secret:"true" help:"Comma separated keys replaced by message_key"`
+
+	Log      LogConfig      `yaml:"log"`
+	HTTP     HTTPConfig     `yaml:"http"`
+	TLS      TLSConfig      `yaml:"tls"`
+	Database DatabaseConfig `yaml:"database"`
+	Backup   BackupConfig   `yaml:"backup"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+	Push     PushConfig     `yaml:"push"`
+	Email    EmailConfig    `yaml:"email"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" help:"debug, info, warn or error"`
+	Format string `yaml:"format" env:"LOG_FORMAT" help:"text or json"`
+}
+
+type HTTPConfig struct {
+	RequestTimeout    time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT" help:"Maximum duration of a request"`
+	RateLimit         int           `yaml:"rate_limit" env:"RATE_LIMIT" help:"Requests allowed per IP in every rate_limit_window"`
+	BotRateLimit      int           `yaml:"bot_rate_limit" env:"BOT_RATE_LIMIT" help:"Requests allowed per bot in every rate_limit_window"`
+	RateLimitWindow   time.Duration `yaml:"rate_limit_window" env:"RATE_LIMIT_WINDOW" help:"Window of the per IP and per bot rate limits"`
+	DrainDelay        time.Duration `yaml:"drain_delay" env:"DRAIN_DELAY" help:"How long /readyz fails before shutdown closes the database"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" help:"How long shutdown waits for running requests"`
+	PublicDir         string        `yaml:"public_dir" env:"PUBLIC_DIR" help:"Serve the frontend from this directory instead of the embedded copy"`
+	Reflection        bool          `yaml:"reflection" env:"GRPC_REFLECTION" help:"Let grpcurl and similar tools discover the services"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" env:"HEARTBEAT_INTERVAL" help:"Time between keep-alive comments on /events, 0 disables them"`
+	HealthAddr        string        `yaml:"health_addr" env:"HEALTH_ADDR" help:"Address of a plain HTTP listener that only serves /healthz and /readyz"`
+}
+
+// TLS is enabled by setting cert_file and key_file
+type TLSConfig struct {
+	CertFile       string        `yaml:"cert_file" env:"TLS_CERT_FILE" help:"PEM certificate chain, enables TLS"`
+	KeyFile        string        `yaml:"key_file" env:"TLS_KEY_FILE" help:"PEM private key of the certificate"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"TLS_RELOAD_INTERVAL" help:"How often the certificate files are checked for changes"`
+	ClientCAFile   string        `yaml:"client_ca_file" env:"TLS_CLIENT_CA_FILE" help:"PEM CAs that sign client certificates"`
+	ClientAuth     string        `yaml:"client_auth" env:"TLS_CLIENT_AUTH" help:"none, optional or require"`
+	RedirectAddr   string        `yaml:"redirect_addr" env:"TLS_REDIRECT_ADDR" help:"Address of a plain HTTP listener that redirects to HTTPS"`
+}
+
+type DatabaseConfig struct {
+	Path            string        `yaml:"path" env:"DB_PATH" help:"SQLite database file"`
+	SchemaPath      string        `yaml:"schema_path" env:"DB_SCHEMA_PATH" help:"SQL file that creates the tables"`
+	JournalMode     string        `yaml:"journal_mode" env:"DB_JOURNAL_MODE" help:"SQLite journal mode"`
+	BusyTimeout     time.Duration `yaml:"busy_timeout" env:"DB_BUSY_TIMEOUT" help:"How long to wait for a locked database"`
+	ForeignKeys     bool          `yaml:"foreign_keys" env:"DB_FOREIGN_KEYS" help:"Enforce foreign keys"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" help:"Maximum open connections, 0 is unlimited"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" help:"Maximum idle connections"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" help:"Maximum connection age, 0 is unlimited"`
+}
+
+type BackupConfig struct {
+	Dir      string        `yaml:"dir" env:"BACKUP_DIR" help:"Directory snapshots are written to"`
+	Interval time.Duration `yaml:"interval" env:"BACKUP_INTERVAL" help:"Time between scheduled snapshots, 0 disables them"`
+	Keep     int           `yaml:"keep" env:"BACKUP_KEEP" help:"Snapshots to keep, 0 keeps all of them"`
+}
+
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled" env:"METRICS_ENABLED" help:"Serve Prometheus metrics on /metrics"`
+}
+
+type WebhooksConfig struct {
+	Enabled               bool          `yaml:"enabled" env:"WEBHOOKS_ENABLED" help:"Deliver outgoing webhooks from this instance"`
+	MaxAttempts           int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" help:"Attempts before a delivery is marked failed"`
+	RetryDelay            time.Duration `yaml:"retry_delay" env:"WEBHOOKS_RETRY_DELAY" help:"Wait before the first retry, doubled after every attempt"`
+	MaxRetryDelay         time.Duration `yaml:"max_retry_delay" env:"WEBHOOKS_MAX_RETRY_DELAY" help:"Longest wait between attempts"`
+	Timeout               time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" help:"Timeout of a single attempt"`
+	AllowPrivateAddresses bool          `yaml:"allow_private_addresses" env:"WEBHOOKS_ALLOW_PRIVATE_ADDRESSES" help:"Allow webhook URLs on loopback and private networks"`
+}
+
+type PushConfig struct {
+	VAPIDPrivateKey       string        `yaml:"vapid_private_key" env:"VAPID_PRIVATE_KEY" secret:"true" help:"Web Push is disabled without it. Generate one with gomessenger vapid-keys"`
+	Subject               string        `yaml:"subject" env:"PUSH_SUBJECT" help:"mailto: or https: URL push services can contact the operator at"`
+	TTL                   time.Duration `yaml:"ttl" env:"PUSH_TTL" help:"How long push services keep notifications for offline browsers"`
+	Timeout               time.Duration `yaml:"timeout" env:"PUSH_TIMEOUT" help:"Timeout of a request to a push service"`
+	AllowPrivateAddresses bool          `yaml:"allow_private_addresses" env:"PUSH_ALLOW_PRIVATE_ADDRESSES" help:"Allow push endpoints on loopback and private networks"`
+}
+
+// Email is enabled by setting smtp_addr
+type EmailConfig struct {
+	SMTPAddr       string        `yaml:"smtp_addr" env:"SMTP_ADDR" help:"host:port of the SMTP server, enables email"`
+	Username       string        `yaml:"username" env:"SMTP_USERNAME" help:"SMTP user. Authentication requires TLS unless the server is on localhost"`
+	Password       string        `yaml:"password" env:"SMTP_PASSWORD" secret:"true" help:"Password of the SMTP user"`
+	ImplicitTLS    bool          `yaml:"implicit_tls" env:"SMTP_IMPLICIT_TLS" help:"Connect with TLS, usually on port 465, instead of STARTTLS"`
+	From           string        `yaml:"from" env:"EMAIL_FROM" help:"Address emails are sent from"`
+	Timeout        time.Duration `yaml:"timeout" env:"SMTP_TIMEOUT" help:"Timeout of sending one email"`
+	DigestInterval time.Duration `yaml:"digest_interval" env:"DIGEST_INTERVAL" help:"How often users are checked for due digests"`
+	Previews       bool          `yaml:"previews" env:"DIGEST_PREVIEWS" help:"Include the latest message of every chat in digests"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" help:"none, stdout or otlp"`
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT" help:"host:port of the OTLP/HTTP collector"`
+	Insecure    bool    `yaml:"insecure" env:"TRACING_INSECURE" help:"Send traces to the collector without TLS"`
+	ServiceName string  `yaml:"service_name" env:"TRACING_SERVICE_NAME" help:"service.name of every span"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" help:"Fraction of new traces that are recorded"`
+}
+
+func Default() *Config {
+	return &Config{
+		Host: "localhost:3000",
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		HTTP: HTTPConfig{
+			RequestTimeout:    5 * time.Second,
+			RateLimit:         100,
+			BotRateLimit:      60,
+			RateLimitWindow:   time.Minute,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+			HeartbeatInterval: 15 * time.Second,
+		},
+		TLS: TLSConfig{
+			ReloadInterval: time.Minute,
+			ClientAuth:     "none",
+		},
+		Database: DatabaseConfig{
+			SchemaPath:   "./data/database.sql",
+			JournalMode:  "WAL",
+			BusyTimeout:  5 * time.Second,
+			ForeignKeys:  true,
+			MaxIdleConns: 2,
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:       true,
+			MaxAttempts:   8,
+			RetryDelay:    10 * time.Second,
+			MaxRetryDelay: time.Hour,
+			Timeout:       10 * time.Second,
+		},
+		Push: PushConfig{
+			TTL:     24 * time.Hour,
+			Timeout: 10 * time.Second,
+		},
+		Email: EmailConfig{
+			Timeout:        10 * time.Second,
+			DigestInterval: 5 * time.Minute,
+			Previews:       true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "gomessenger",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Builds the configuration from the YAML file given by -config or CONFIG_FILE,
+// the environment and args. Returns the arguments left after the flags.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet("gomessenger", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file")
+	set_flags := registerFlags(flags, reflect.ValueOf(cfg).Elem(), "")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, nil, errors.New(Usage())
+		}
+		return nil, nil, fmt.Errorf("config -> %s", err)
+	}
+
+	if *file != "" {
+		if err := cfg.loadFile(*file); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, nil, err
+	}
+
+	// Flags were parsed into a separate map so that they can be applied last
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if field, ok := set_flags[f.Name]; ok && err == nil {
+			if parse_err := setValue(field, f.Value.String()); parse_err != nil {
+				err = fmt.Errorf("config -> flag -%s: %s", f.Name, parse_err)
+			}
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, flags.Args(), nil
+}
+
+func (cfg *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config -> %s", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config -> %s: %s", path, err)
+	}
+	return nil
+}
+
+// Checks every setting and reports all the problems at once
+func (cfg *Config) Validate() error {
+	var problems []string
+
+	if len(cfg.SecretKey) < MIN_SECRET_KEY_LEN {
+		problems = append(problems, fmt.Sprintf(
+			"secret_key (SECRET_KEY) must be at least %d characters long, got %d",
+			MIN_SECRET_KEY_LEN, len(cfg.SecretKey)))
+	}
+	if cfg.Host == "" {
+		problems = append(problems, "host (HOST) must not be empty")
+	}
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("log.level (LOG_LEVEL) must be debug, info, warn or error, got %q", cfg.Log.Level))
+	}
+	switch strings.ToLower(cfg.Log.Format) {
+	case "text", "json":
+	default:
+		problems = append(problems, fmt.Sprintf("log.format (LOG_FORMAT) must be text or json, got %q", cfg.Log.Format))
+	}
+	if cfg.HTTP.RequestTimeout <= 0 {
+		problems = append(problems, "http.request_timeout (REQUEST_TIMEOUT) must be positive")
+	}
+	if cfg.HTTP.RateLimit <= 0 || cfg.HTTP.BotRateLimit <= 0 || cfg.HTTP.RateLimitWindow <= 0 {
+		problems = append(problems, "http.rate_limit, http.bot_rate_limit and http.rate_limit_window must be positive")
+	}
+	if cfg.HTTP.DrainDelay < 0 {
+		problems = append(problems, "http.drain_delay (DRAIN_DELAY) must not be negative")
+	}
+	if cfg.HTTP.PublicDir != "" {
+		if info, err := os.Stat(cfg.HTTP.PublicDir); err != nil {
+			problems = append(problems, fmt.Sprintf("http.public_dir (PUBLIC_DIR): %s", err))
+		} else if !info.IsDir() {
+			problems = append(problems, "http.public_dir (PUBLIC_DIR) must be a directory")
+		}
+	}
+	if cfg.HTTP.ShutdownTimeout <= 0 {
+		problems = append(problems, "http.shutdown_timeout (SHUTDOWN_TIMEOUT) must be positive")
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		problems = append(problems, "tls.cert_file (TLS_CERT_FILE) and tls.key_file (TLS_KEY_FILE) must be set together")
+	}
+	if cfg.TLS.CertFile != "" && cfg.TLS.ReloadInterval <= 0 {
+		problems = append(problems, "tls.reload_interval (TLS_RELOAD_INTERVAL) must be positive")
+	}
+	switch strings.ToLower(cfg.TLS.ClientAuth) {
+	case "none":
+	case "optional", "require":
+		if cfg.TLS.ClientCAFile == "" {
+			problems = append(problems, "tls.client_auth (TLS_CLIENT_AUTH) requires tls.client_ca_file (TLS_CLIENT_CA_FILE)")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("tls.client_auth (TLS_CLIENT_AUTH) must be none, optional or require, got %q", cfg.TLS.ClientAuth))
+	}
+	if cfg.TLS.RedirectAddr != "" && cfg.TLS.CertFile == "" {
+		problems = append(problems, "tls.redirect_addr (TLS_REDIRECT_ADDR) requires tls.cert_file (TLS_CERT_FILE)")
+	}
+	if cfg.Database.SchemaPath == "" {
+		problems = append(problems, "database.schema_path (DB_SCHEMA_PATH) must not be empty")
+	} else if _, err := os.Stat(cfg.Database.SchemaPath); err != nil {
+		problems = append(problems, fmt.Sprintf("database.schema_path (DB_SCHEMA_PATH): %s", err))
+	}
+	if cfg.Database.MaxOpenConns < 0 || cfg.Database.MaxIdleConns < 0 {
+		problems = append(problems, "database connection limits must not be negative")
+	}
+	switch strings.ToLower(cfg.Tracing.Exporter) {
+	case "none", "stdout", "otlp":
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter (TRACING_EXPORTER) must be none, stdout or otlp, got %q", cfg.Tracing.Exporter))
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1")
+	}
+	if cfg.Push.VAPIDPrivateKey != "" &&
+		!strings.HasPrefix(cfg.Push.Subject, "mailto:") && !strings.HasPrefix(cfg.Push.Subject, "https://") {
+		problems = append(problems, "push.subject (PUSH_SUBJECT) must be a mailto: or https: URL when Web Push is enabled")
+	}
+	if cfg.Push.TTL < 0 || cfg.Push.Timeout <= 0 {
+		problems = append(problems, "push.ttl must not be negative and push.timeout must be positive")
+	}
+	if cfg.Email.SMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(cfg.Email.SMTPAddr); err != nil {
+			problems = append(problems, "email.smtp_addr (SMTP_ADDR) must be host:port")
+		}
+		if address, err := mail.ParseAddress(cfg.Email.From); err != nil || address.Address != cfg.Email.From {
+			problems = append(problems, "email.from (EMAIL_FROM) must be a bare address when email is enabled")
+		}
+	}
+	if cfg.Email.Timeout <= 0 || cfg.Email.DigestInterval <= 0 {
+		problems = append(problems, "email.timeout and email.digest_interval must be positive")
+	}
+	if cfg.Backup.Keep < 0 {
+		problems = append(problems, "backup.keep (BACKUP_KEEP) must not be negative")
+	}
+	if cfg.Backup.Interval > 0 && cfg.Backup.Dir == "" {
+		problems = append(problems, "backup.interval (BACKUP_INTERVAL) requires backup.dir (BACKUP_DIR)")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Invalid configuration:\n\t" + strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
+
+// Writes the configuration as YAML with secrets redacted
+func (cfg *Config) Print(w io.Writer) error {
+	redacted := *cfg
+	redact(reflect.ValueOf(&redacted).Elem())
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&redacted); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Lists every flag along with its environment variable
+func Usage() string {
+	var b strings.Builder
+	b.WriteString("Flags:\n\t-config string\n\t\tYAML configuration file (CONFIG_FILE)\n")
+	walk(reflect.ValueOf(Default()).Elem(), "", func(name string, field reflect.StructField, value reflect.Value) {
+		// Like package flag, bool flags are listed without a value
+		if value.Kind() == reflect.Bool {
+			fmt.Fprintf(&b, "\t-%s\n\t\t%s (%s)\n", name, field.Tag.Get("help"), field.Tag.Get("env"))
+			return
+		}
+		fmt.Fprintf(&b, "\t-%s %s\n\t\t%s (%s)\n", name, typeName(value), field.Tag.Get("help"), field.Tag.Get("env"))
+	})
+	return b.String()
+}
+
+// Calls fn for every leaf setting with its dotted name
+func walk(v reflect.Value, prefix string, fn func(string, reflect.StructField, reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := prefix + field.Tag.Get("yaml")
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			walk(v.Field(i), name+".", fn)
+			continue
+		}
+		fn(name, field, v.Field(i))
+	}
+}
+
+func registerFlags(flags *flag.FlagSet, v reflect.Value, prefix string) map[string]reflect.Value {
+	set_flags := make(map[string]reflect.Value)
+	walk(v, prefix, func(name string, field reflect.StructField, value reflect.Value) {
+		// Values are kept as strings and parsed by setValue() once all sources are known.
+		// Booleans are bool flags so that "-name" alone means true
+		if value.Kind() == reflect.Bool {
+			flags.Bool(name, false, field.Tag.Get("help"))
+		} else {
+			flags.String(name, "", field.Tag.Get("help"))
+		}
+		set_flags[name] = value
+	})
+	return set_flags
+}
+
+func applyEnv(v reflect.Value) error {
+	var err error
+	walk(v, "", func(name string, field reflect.StructField, value reflect.Value) {
+		env, ok := os.LookupEnv(field.Tag.Get("env"))
+		if !ok || err != nil {
+			return
+		}
+		if parse_err := setValue(value, env); parse_err != nil {
+			err = fmt.Errorf("config -> %s: %s", field.Tag.Get("env"), parse_err)
+		}
+	})
+	return err
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func redact(v reflect.Value) {
+	walk(v, "", func(name string, field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") == "true" && value.String() != "" {
+			value.SetString("REDACTED")
+		}
+	})
+}
+
+func typeName(v reflect.Value) string {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return "duration"
+	}
+	return v.Kind().String()
+}
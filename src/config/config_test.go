@@ -0,0 +1,95 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vl0000/gomessenger/config"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("Flags override environment which overrides the file", func(t *testing.T) {
+		// SETUP
+		file := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(file, []byte(`
+host: file:3000
+http:
+  request_timeout: 10s
+database:
+  path: file.db
+`), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("CONFIG_FILE", file)
+		t.Setenv("DB_PATH", "env.db")
+		t.Setenv("HOST", "env:3000")
+		// END SETUP
+
+		cfg, args, err := config.Load([]string{"-host", "flag:3000", "config", "print"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Host != "flag:3000" {
+			t.Errorf("Expected host from flag, got %s", cfg.Host)
+		}
+		if cfg.Database.Path != "env.db" {
+			t.Errorf("Expected database path from environment, got %s", cfg.Database.Path)
+		}
+		if cfg.HTTP.RequestTimeout != 10*time.Second {
+			t.Errorf("Expected request timeout from file, got %s", cfg.HTTP.RequestTimeout)
+		}
+		if cfg.HTTP.RateLimit != 100 {
+			t.Errorf("Expected default rate limit, got %d", cfg.HTTP.RateLimit)
+		}
+		if len(args) != 2 || args[0] != "config" {
+			t.Errorf("Expected the command to be left in args, got %v", args)
+		}
+	})
+
+	t.Run("Boolean flags do not need a value", func(t *testing.T) {
+		cfg, args, err := config.Load([]string{"-metrics.enabled", "-webhooks.enabled=false", "serve"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.Metrics.Enabled || cfg.Webhooks.Enabled {
+			t.Errorf("Expected metrics on and webhooks off, got %v and %v", cfg.Metrics.Enabled, cfg.Webhooks.Enabled)
+		}
+		if len(args) != 1 || args[0] != "serve" {
+			t.Errorf("Expected the command to be left in args, got %v", args)
+		}
+	})
+
+	t.Run("Invalid values are reported", func(t *testing.T) {
+		t.Setenv("BACKUP_KEEP", "many")
+		if _, _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "BACKUP_KEEP") {
+			t.Fatalf("Expected an error naming BACKUP_KEEP, got %v", err)
+		}
+	})
+
+	t.Run("Validation lists every problem", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.SecretKey = "short"
+		cfg.Backup.Interval = time.Hour
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "SECRET_KEY") || !strings.Contains(err.Error(), "BACKUP_DIR") {
+			t.Fatalf("Expected secret key and backup dir problems, got %v", err)
+		}
+	})
+
+	t.Run("Secrets are redacted when printed", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.SecretKey = strings.Repeat("s", config.MIN_SECRET_KEY_LEN)
+		var out bytes.Buffer
+		if err := cfg.Print(&out); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out.String(), cfg.SecretKey) || !strings.Contains(out.String(), "REDACTED") {
+			t.Fatalf("Secret key was not redacted:\n%s", out.String())
+		}
+	})
+}
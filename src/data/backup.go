@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const BACKUP_PREFIX string = "database-"
+
+// Writes a consistent snapshot of the database to dest with VACUUM INTO.
+// It is safe to call while the server is running. dest must not exist.
+func Backup(ctx context.Context, db *sql.DB, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("DB backup -> %s already exists", dest)
+	}
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?;`, dest); err != nil {
+		return fmt.Errorf("DB backup -> %s", err)
+	}
+	return nil
+}
+
+// Takes a timestamped snapshot inside dir and deletes the oldest ones so that
+// at most keep snapshots remain. A keep value of 0 disables rotation.
+func BackupToDir(ctx context.Context, db *sql.DB, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("DB backup -> %s", err)
+	}
+
+	dest := filepath.Join(dir, BACKUP_PREFIX+time.Now().UTC().Format("20060102T150405.000000")+".db")
+	if err := Backup(ctx, db, dest); err != nil {
+		return "", err
+	}
+
+	if keep > 0 {
+		if err := rotateBackups(dir, keep); err != nil {
+			return dest, err
+		}
+	}
+	return dest, nil
+}
+
+func rotateBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("DB backup rotation -> %s", err)
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, BACKUP_PREFIX) && strings.HasSuffix(name, ".db") {
+			snapshots = append(snapshots, name)
+		}
+	}
+	// The timestamp format sorts chronologically
+	sort.Strings(snapshots)
+
+	for len(snapshots) > keep {
+		if err := os.Remove(filepath.Join(dir, snapshots[0])); err != nil {
+			return fmt.Errorf("DB backup rotation -> %s", err)
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// Replaces the database at path with the snapshot at src.
+// The server must not be running while this happens.
+func Restore(ctx context.Context, src string, path string) error {
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("DB restore -> %s", err)
+	}
+
+	snapshot, err := sql.Open("sqlite3", "file:"+src+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("DB restore -> %s", err)
+	}
+	defer snapshot.Close()
+
+	var result string
+	if err := snapshot.QueryRowContext(ctx, `PRAGMA integrity_check;`).Scan(&result); err != nil {
+		return fmt.Errorf("DB restore -> %s", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("DB restore -> snapshot failed integrity check: %s", result)
+	}
+
+	// Copy next to the destination first so the final rename is atomic
+	tmp := path + ".restore"
+	os.Remove(tmp)
+	if err := Backup(ctx, snapshot, tmp); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("DB restore -> %s", err)
+	}
+
+	// Stale WAL files would be replayed on top of the restored database. They
+	// are only removed once it is in place, so a failed restore leaves the
+	// current database whole
+	os.Remove(path + "-wal")
+	os.Remove(path + "-shm")
+	return nil
+}
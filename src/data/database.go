@@ -1,31 +1,138 @@
 package data
 
 import (
+	"crypto/cipher"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
 	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func SetupTestDatabase(path string) (*sql.DB, error) {
-	query, err := os.ReadFile(os.Getenv("DB_SCHEMA_PATH"))
+// Connection settings for SQLite. They are passed in the DSN so that every
+// connection in the pool gets them, not just the one that ran a PRAGMA.
+type Options struct {
+	// SQL file that creates the tables
+	SchemaPath string
+	// One of DELETE, TRUNCATE, PERSIST, MEMORY, WAL or OFF
+	JournalMode string
+	// How long a connection waits for a lock before returning SQLITE_BUSY
+	BusyTimeout time.Duration
+	ForeignKeys bool
+	// 0 means no limit
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		SchemaPath:   "./data/database.sql",
+		JournalMode:  "WAL",
+		BusyTimeout:  5 * time.Second,
+		ForeignKeys:  true,
+		MaxIdleConns: 2,
+	}
+}
+
+func (o Options) dsn(path string) string {
+	params := url.Values{}
+	if o.JournalMode != "" {
+		params.Set("_journal_mode", o.JournalMode)
+	}
+	params.Set("_busy_timeout", strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10))
+	params.Set("_foreign_keys", strconv.FormatBool(o.ForeignKeys))
+	return path + "?" + params.Encode()
+}
+
+// A database handle that caches prepared statements by their query text
+type Store struct {
+	*sql.DB
+	// Encrypts message contents when set
+	Keyring *Keyring
+	stmts   map[string]*sql.Stmt
+	stmtsMu sync.Mutex
+	// Unwrapped data keys by conversation
+	dataKeys   map[string]cipher.AEAD
+	dataKeysMu sync.Mutex
+}
+
+// Returns a prepared statement for query, preparing it on first use
+func (s *Store) Stmt(query string) (*sql.Stmt, error) {
+	s.stmtsMu.Lock()
+	defer s.stmtsMu.Unlock()
+
+	if stmt, ok := s.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := s.DB.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	s.stmts[query] = stmt
+	return stmt, nil
+}
+
+func (s *Store) Close() error {
+	s.stmtsMu.Lock()
+	for query, stmt := range s.stmts {
+		stmt.Close()
+		delete(s.stmts, query)
+	}
+	s.stmtsMu.Unlock()
+	return s.DB.Close()
+}
+
+// Opens the database with DefaultOptions() and the schema at DB_SCHEMA_PATH
+func SetupTestDatabase(path string) (*Store, error) {
+	opts := DefaultOptions()
+	opts.SchemaPath = os.Getenv("DB_SCHEMA_PATH")
+	return SetupDatabase(path, opts)
+}
+
+func SetupDatabase(path string, opts Options) (*Store, error) {
+	query, err := os.ReadFile(opts.SchemaPath)
 	if err != nil {
 		return nil, fmt.Errorf("DB setup -> %s", err)
 	}
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open("sqlite3", opts.dsn(path))
 	if err != nil {
-		db.Close()
 		return nil, fmt.Errorf("DB setup -> %s", err)
 	}
 
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+
+	empty, err := isEmpty(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("DB setup -> %s", err)
+	}
 	if _, err = db.Exec(string(query)); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("DB setup -> %s", err)
 	}
+	if empty {
+		err = setVersion(db, len(migrations))
+	} else {
+		err = migrate(db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("DB migration -> %s", err)
+	}
 
-	log.Println("Database setup")
-	return db, nil
+	slog.Debug("Database setup", "path", path, "journal_mode", opts.JournalMode)
+	return &Store{
+		DB:       db,
+		stmts:    make(map[string]*sql.Stmt),
+		dataKeys: make(map[string]cipher.AEAD),
+	}, nil
 
 }
@@ -3,6 +3,28 @@ CREATE TABLE IF NOT EXISTS "users" (
   "username" TEXT NOT NULL,
   "password" TEXT NOT NULL,
   "salt" TEXT NOT NULL,
+  "role" TEXT NOT NULL DEFAULT 'user',
+  "suspended" INTEGER NOT NULL DEFAULT 0,
+  -- Unix time before which issued tokens are rejected, set by ForceLogout
+  "tokens_valid_after" INTEGER NOT NULL DEFAULT 0,
+  -- Set for bots, which authenticate with API keys instead of a password
+  "bot_owner" TEXT,
+  -- Optional address for digests, only used once it is verified
+  "email" TEXT,
+  "email_verified" INTEGER NOT NULL DEFAULT 0,
+  -- SHA-256 of the pending verification token
+  "email_token" BLOB,
+  "email_token_expires_at" TEXT,
+  -- When the last verification email was sent, whatever the address
+  "email_sent_at" TEXT,
+  -- Notification preferences: off, hourly, daily or weekly
+  "email_digest" TEXT NOT NULL DEFAULT 'daily',
+  "push" INTEGER NOT NULL DEFAULT 1,
+  -- Messages received after this were not seen in the app
+  "last_active_at" TEXT,
+  "last_digest_at" TEXT,
+  -- Highest message id already included in a digest
+  "digest_message_id" INTEGER NOT NULL DEFAULT 0,
   PRIMARY KEY("phone_number")
 );
 CREATE TABLE IF NOT EXISTS "messages" (
@@ -10,8 +32,105 @@ CREATE TABLE IF NOT EXISTS "messages" (
   "sender" TEXT NOT NULL,
   "receiver" TEXT NOT NULL,
   "content" TEXT NOT NULL,
+  -- Whether content was encrypted with the data key of the conversation
+  "encrypted" INTEGER NOT NULL DEFAULT 0,
   "timestamp" TEXT NOT NULL,
+  "expires_at" TEXT,
   PRIMARY KEY("id" AUTOINCREMENT),
   FOREIGN KEY("sender") REFERENCES users("phone_number"),
   FOREIGN KEY("receiver") REFERENCES users("phone_number")
 );
+CREATE TABLE IF NOT EXISTS "conversation_keys" (
+  "conversation" TEXT NOT NULL UNIQUE,
+  "key_id" TEXT NOT NULL,
+  "wrapped_key" BLOB NOT NULL,
+  PRIMARY KEY("conversation")
+);
+-- Outgoing webhooks. Those without an owner were created by an admin and
+-- receive the events of every user
+CREATE TABLE IF NOT EXISTS "webhooks" (
+  "id" INTEGER NOT NULL UNIQUE,
+  "owner" TEXT,
+  "url" TEXT NOT NULL,
+  "secret" TEXT NOT NULL,
+  -- Comma separated event names
+  "events" TEXT NOT NULL,
+  "created_at" TEXT NOT NULL,
+  PRIMARY KEY("id" AUTOINCREMENT),
+  FOREIGN KEY("owner") REFERENCES users("phone_number") ON DELETE CASCADE
+);
+-- Outbox of webhook events, written in the same transaction as the event.
+-- Payloads are built when a delivery is attempted, so message contents are
+-- only stored encrypted in "messages"
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+  "id" INTEGER NOT NULL UNIQUE,
+  "webhook_id" INTEGER NOT NULL,
+  "event" TEXT NOT NULL,
+  -- A message id or a phone number, depending on the event
+  "subject" TEXT NOT NULL,
+  -- pending, delivered or failed
+  "status" TEXT NOT NULL DEFAULT 'pending',
+  "attempts" INTEGER NOT NULL DEFAULT 0,
+  "next_attempt_at" TEXT NOT NULL,
+  "response_code" INTEGER NOT NULL DEFAULT 0,
+  "error" TEXT NOT NULL DEFAULT '',
+  "created_at" TEXT NOT NULL,
+  PRIMARY KEY("id" AUTOINCREMENT),
+  FOREIGN KEY("webhook_id") REFERENCES webhooks("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "webhook_deliveries_due" ON "webhook_deliveries" ("status", "next_attempt_at");
+-- API keys of bots. Keys are "gmk_<id>_<secret>" and only a hash of the secret is kept
+CREATE TABLE IF NOT EXISTS "api_keys" (
+  "id" TEXT NOT NULL UNIQUE,
+  "bot" TEXT NOT NULL,
+  -- SHA-256 of the secret part of the key
+  "hash" BLOB NOT NULL,
+  -- Comma separated scopes
+  "scopes" TEXT NOT NULL,
+  "created_at" TEXT NOT NULL,
+  "revoked_at" TEXT,
+  PRIMARY KEY("id"),
+  FOREIGN KEY("bot") REFERENCES users("phone_number") ON DELETE CASCADE
+);
+-- Incoming webhooks post messages from owner to receiver. Their URLs are
+-- "/hooks/<id>/<token>" and only a hash of the token is kept
+CREATE TABLE IF NOT EXISTS "incoming_webhooks" (
+  "id" TEXT NOT NULL UNIQUE,
+  "owner" TEXT NOT NULL,
+  "receiver" TEXT NOT NULL,
+  "name" TEXT NOT NULL,
+  -- SHA-256 of the token
+  "hash" BLOB NOT NULL,
+  "created_at" TEXT NOT NULL,
+  PRIMARY KEY("id"),
+  FOREIGN KEY("owner") REFERENCES users("phone_number") ON DELETE CASCADE,
+  FOREIGN KEY("receiver") REFERENCES users("phone_number") ON DELETE CASCADE
+);
+-- Commands bots react to. Users type them as "/<name> <args>" in the chat with the bot
+CREATE TABLE IF NOT EXISTS "bot_commands" (
+  "bot" TEXT NOT NULL,
+  "name" TEXT NOT NULL,
+  "description" TEXT NOT NULL,
+  PRIMARY KEY("bot", "name"),
+  FOREIGN KEY("bot") REFERENCES users("phone_number") ON DELETE CASCADE
+);
+-- Conversations muted with /mute. "until" is in UTC and NULL until /unmute
+CREATE TABLE IF NOT EXISTS "mutes" (
+  "user" TEXT NOT NULL,
+  "muted" TEXT NOT NULL,
+  "until" TEXT,
+  PRIMARY KEY("user", "muted"),
+  FOREIGN KEY("user") REFERENCES users("phone_number") ON DELETE CASCADE,
+  FOREIGN KEY("muted") REFERENCES users("phone_number") ON DELETE CASCADE
+);
+-- Browsers that get Web Push notifications of the user's messages
+CREATE TABLE IF NOT EXISTS "push_subscriptions" (
+  "endpoint" TEXT NOT NULL UNIQUE,
+  "user" TEXT NOT NULL,
+  "p256dh" TEXT NOT NULL,
+  "auth" TEXT NOT NULL,
+  "created_at" TEXT NOT NULL,
+  PRIMARY KEY("endpoint"),
+  FOREIGN KEY("user") REFERENCES users("phone_number") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "push_subscriptions_user" ON "push_subscriptions" ("user");
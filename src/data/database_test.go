@@ -1,7 +1,13 @@
 package data_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/vl0000/gomessenger/data"
@@ -13,4 +19,245 @@ func TestDB(t *testing.T) {
 		data.SetupTestDatabase("./testdb.db")
 		os.Remove("./testdb.db")
 	})
+	t.Run("Databases of older versions are migrated", func(t *testing.T) {
+		// SETUP
+		os.Setenv("DB_SCHEMA_PATH", "./database.sql")
+		path := filepath.Join(t.TempDir(), "old.db")
+		old, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = old.Exec(`CREATE TABLE "users" ("phone_number" TEXT NOT NULL UNIQUE, "username" TEXT NOT NULL,
+				"password" TEXT NOT NULL, "salt" TEXT NOT NULL, PRIMARY KEY("phone_number"));
+			CREATE TABLE "messages" ("id" INTEGER NOT NULL UNIQUE, "sender" TEXT NOT NULL, "receiver" TEXT NOT NULL,
+				"content" TEXT NOT NULL, "timestamp" TEXT NOT NULL, PRIMARY KEY("id" AUTOINCREMENT));
+			INSERT INTO users (username, phone_number, password, salt) VALUES ('John Doe', '123-456', '', '');`)
+		old.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		// Migrations run once, so a second setup must not add the columns again
+		for range 2 {
+			db, err := data.SetupTestDatabase(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var role, digest string
+			var suspended bool
+			err = db.QueryRow(`SELECT role, suspended, email_digest FROM users WHERE phone_number = '123-456';`).Scan(&role, &suspended, &digest)
+			db.Close()
+			if err != nil {
+				t.Fatal(err)
+			} else if role != "user" || suspended || digest != "daily" {
+				t.Fatalf("Unexpected defaults of migrated columns: %s, %t, %s", role, suspended, digest)
+			}
+		}
+	})
+	t.Run("Backups can be rotated and restored", func(t *testing.T) {
+		// SETUP
+		os.Setenv("DB_SCHEMA_PATH", "./database.sql")
+		dir := t.TempDir()
+		db, err := data.SetupTestDatabase(filepath.Join(dir, "live.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		_, err = db.Exec(`INSERT INTO users (username, phone_number, password, salt) VALUES ('John Doe', '123-456', '', '');`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		backup_dir := filepath.Join(dir, "backups")
+		var snapshot string
+		for range 3 {
+			snapshot, err = data.BackupToDir(context.TODO(), db.DB, backup_dir, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		entries, err := os.ReadDir(backup_dir)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(entries) != 2 {
+			t.Fatalf("Expected 2 snapshots after rotation, found %d", len(entries))
+		}
+
+		restored := filepath.Join(dir, "restored.db")
+		if err = data.Restore(context.TODO(), snapshot, restored); err != nil {
+			t.Fatal(err)
+		}
+		restored_db, err := data.SetupTestDatabase(restored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer restored_db.Close()
+		var count int
+		if err = restored_db.QueryRow(`SELECT COUNT(*) FROM users;`).Scan(&count); err != nil {
+			t.Fatal(err)
+		} else if count != 1 {
+			t.Fatalf("Expected 1 user in the restored database, found %d", count)
+		}
+	})
+	t.Run("Message contents are encrypted and keys can be rotated", func(t *testing.T) {
+		// SETUP
+		os.Setenv("DB_SCHEMA_PATH", "./database.sql")
+		path := filepath.Join(t.TempDir(), "encrypted.db")
+		db, err := data.SetupTestDatabase(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		old_key, new_key := make([]byte, data.KEY_SIZE), make([]byte, data.KEY_SIZE)
+		rand.Read(old_key)
+		rand.Read(new_key)
+		if db.Keyring, err = data.NewKeyring(old_key); err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		encrypted, ok, err := db.EncryptContent(context.TODO(), "123-456", "654-321", "Hello!!!")
+		if err != nil {
+			t.Fatal(err)
+		} else if !ok || strings.Contains(encrypted, "Hello") {
+			t.Fatal("Content was not encrypted")
+		}
+		// Plaintext that looks like ciphertext is returned as it is
+		if content, err := db.DecryptContent(context.TODO(), "123-456", "654-321", data.ENCRYPTED_PREFIX+"x", false); err != nil {
+			t.Fatal(err)
+		} else if content != data.ENCRYPTED_PREFIX+"x" {
+			t.Fatalf("Expected plaintext to be unchanged, got %s", content)
+		}
+
+		// A fresh store only has the wrapped data key in the database to go on
+		reopened, err := data.SetupTestDatabase(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reopened.Close()
+		if reopened.Keyring, err = data.NewKeyring(new_key, old_key); err != nil {
+			t.Fatal(err)
+		}
+		if rotated, err := reopened.RotateDataKeys(context.TODO()); err != nil {
+			t.Fatal(err)
+		} else if rotated != 1 {
+			t.Fatalf("Expected 1 data key to be rotated, got %d", rotated)
+		}
+
+		// The old key is no longer needed after rotation
+		rotated_db, err := data.SetupTestDatabase(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rotated_db.Close()
+		if rotated_db.Keyring, err = data.NewKeyring(new_key); err != nil {
+			t.Fatal(err)
+		}
+		content, err := rotated_db.DecryptContent(context.TODO(), "654-321", "123-456", encrypted, true)
+		if err != nil {
+			t.Fatal(err)
+		} else if content != "Hello!!!" {
+			t.Fatalf("Expected Hello!!!, got %s", content)
+		}
+	})
+	t.Run("Exports can be imported into another database", func(t *testing.T) {
+		// SETUP
+		os.Setenv("DB_SCHEMA_PATH", "./database.sql")
+		// Exporting must not need a second connection while it reads rows
+		opts := data.DefaultOptions()
+		opts.SchemaPath = "./database.sql"
+		opts.MaxOpenConns = 1
+		src_path := filepath.Join(t.TempDir(), "src.db")
+		src, err := data.SetupDatabase(src_path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer src.Close()
+		key := make([]byte, data.KEY_SIZE)
+		rand.Read(key)
+		if src.Keyring, err = data.NewKeyring(key); err != nil {
+			t.Fatal(err)
+		}
+		_, err = src.Exec(`INSERT INTO users (username, phone_number, password, salt) VALUES
+			('John Doe', '123-456', 'hash', 'salt'), ('Jane Doe', '654-321', 'hash', 'salt');
+			UPDATE users SET suspended = 1, tokens_valid_after = 1700000000, email = 'jane@example.com',
+				email_verified = 1, email_digest = 'weekly', push = 0 WHERE phone_number = '654-321';
+			INSERT INTO users (username, phone_number, password, salt, bot_owner) VALUES
+				('Echo', '999-999', '', '', '123-456');
+			INSERT INTO api_keys (id, bot, hash, scopes, created_at, revoked_at) VALUES
+				('k1', '999-999', x'00ff', 'messages:read,messages:send', '2025-01-01 00:00:00', NULL);
+			INSERT INTO bot_commands (bot, name, description) VALUES ('999-999', 'echo', 'Repeats the message');`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Encrypted by another store, so that the export has to look up the data key
+		writer, err := data.SetupTestDatabase(src_path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer writer.Close()
+		writer.Keyring = src.Keyring
+		content, _, err := writer.EncryptContent(context.TODO(), "123-456", "654-321", "Hello!!!")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = src.Exec(`INSERT INTO messages (id, sender, receiver, content, encrypted, timestamp) VALUES
+			(42, '123-456', '654-321', ?, 1, '2025-01-01 00:00:00');`, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst, err := data.SetupTestDatabase(filepath.Join(t.TempDir(), "dst.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dst.Close()
+		// END SETUP
+
+		var export bytes.Buffer
+		if count, err := src.Export(context.TODO(), &export); err != nil {
+			t.Fatal(err)
+		} else if count != 4 {
+			t.Fatalf("Expected 4 exported records, got %d", count)
+		}
+		if strings.Contains(export.String(), content) {
+			t.Fatal("Export contains encrypted content")
+		}
+
+		if count, err := dst.Import(context.TODO(), &export); err != nil {
+			t.Fatal(err)
+		} else if count != 4 {
+			t.Fatalf("Expected 4 imported records, got %d", count)
+		}
+		var timestamp string
+		err = dst.QueryRow(`SELECT content, timestamp FROM messages WHERE id = 42;`).Scan(&content, &timestamp)
+		if err != nil {
+			t.Fatal(err)
+		} else if content != "Hello!!!" || timestamp != "2025-01-01 00:00:00" {
+			t.Fatalf("Message was not imported as exported: %s at %s", content, timestamp)
+		}
+
+		var suspended, verified, push bool
+		var tokens_valid_after int64
+		var email, email_digest string
+		err = dst.QueryRow(`SELECT suspended, tokens_valid_after, email, email_verified, email_digest, push
+			FROM users WHERE phone_number = '654-321';`).Scan(&suspended, &tokens_valid_after, &email, &verified, &email_digest, &push)
+		if err != nil {
+			t.Fatal(err)
+		} else if !suspended || tokens_valid_after != 1700000000 || email != "jane@example.com" ||
+			!verified || email_digest != "weekly" || push {
+			t.Fatal("User state and preferences were not imported as exported")
+		}
+
+		var bot_owner, scopes, command string
+		err = dst.QueryRow(`SELECT u.bot_owner, k.scopes, c.name FROM users u
+			JOIN api_keys k ON k.bot = u.phone_number JOIN bot_commands c ON c.bot = u.phone_number
+			WHERE u.phone_number = '999-999';`).Scan(&bot_owner, &scopes, &command)
+		if err != nil {
+			t.Fatal(err)
+		} else if bot_owner != "123-456" || scopes != "messages:read,messages:send" || command != "echo" {
+			t.Fatalf("Bot was not imported as exported: %s %s %s", bot_owner, scopes, command)
+		}
+	})
 }
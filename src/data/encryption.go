@@ -0,0 +1,294 @@
+package data
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix of encrypted values in messages.content. Plaintext may start with it
+// too, so whether a row is encrypted is kept in messages.encrypted.
+const ENCRYPTED_PREFIX string = "enc1:"
+
+const KEY_SIZE int = 32
+
+// Key-encryption keys. Data keys are wrapped with the current key, the
+// previous ones are only kept to unwrap data keys that were not rotated yet.
+type Keyring struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// The id of a key is derived from the key itself so it is stable across restarts
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KEY_SIZE {
+		return nil, fmt.Errorf("Keys must be %d bytes long", KEY_SIZE)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func NewKeyring(current []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{currentID: keyID(current), keys: make(map[string]cipher.AEAD)}
+	for _, key := range append([][]byte{current}, previous...) {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[keyID(key)] = aead
+	}
+	return k, nil
+}
+
+// Builds a keyring from a base64 key and comma separated base64 previous keys.
+// Returns nil when current is empty, which leaves messages unencrypted.
+func ParseKeyring(current string, previous string) (*Keyring, error) {
+	if current == "" {
+		return nil, nil
+	}
+
+	var keys [][]byte
+	for _, encoded := range append([]string{current}, strings.Split(previous, ",")...) {
+		if encoded == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("MESSAGE_KEY -> %s", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(keys[0], keys[1:]...)
+}
+
+func seal(aead cipher.AEAD, plaintext []byte, additional_data []byte) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	return aead.Seal(nonce, nonce, plaintext, additional_data)
+}
+
+func unseal(aead cipher.AEAD, sealed []byte, additional_data []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("Encrypted value is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additional_data)
+}
+
+// Both directions of a chat share a data key
+func conversationID(user_a string, user_b string) string {
+	if user_a > user_b {
+		user_a, user_b = user_b, user_a
+	}
+	return user_a + ":" + user_b
+}
+
+// Returns the cached data key of a conversation, or reads and unwraps it.
+// Returns sql.ErrNoRows when the conversation has no data key yet.
+func (s *Store) dataKey(ctx context.Context, conversation string) (cipher.AEAD, error) {
+	s.dataKeysMu.Lock()
+	aead, ok := s.dataKeys[conversation]
+	s.dataKeysMu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	var key_id string
+	var wrapped []byte
+	err := s.QueryRowContext(ctx, `SELECT key_id, wrapped_key FROM conversation_keys
+		WHERE conversation = ?;`, conversation).Scan(&key_id, &wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, ok := s.Keyring.keys[key_id]
+	if !ok {
+		return nil, fmt.Errorf("Data key of %s is wrapped with unknown key %s", conversation, key_id)
+	}
+	key, err := unseal(kek, wrapped, []byte(conversation))
+	if err != nil {
+		return nil, err
+	}
+	if aead, err = newAEAD(key); err != nil {
+		return nil, err
+	}
+
+	s.dataKeysMu.Lock()
+	defer s.dataKeysMu.Unlock()
+	// Another request may have cached the same key meanwhile
+	if cached, ok := s.dataKeys[conversation]; ok {
+		return cached, nil
+	}
+	s.dataKeys[conversation] = aead
+	return aead, nil
+}
+
+// Returns the data key of a conversation, creating it on first use
+func (s *Store) createDataKey(ctx context.Context, conversation string) (cipher.AEAD, error) {
+	aead, err := s.dataKey(ctx, conversation)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return aead, err
+	}
+
+	key := make([]byte, KEY_SIZE)
+	rand.Read(key)
+	wrapped := seal(s.Keyring.keys[s.Keyring.currentID], key, []byte(conversation))
+
+	// Another instance may have created the key first, so always read it back
+	_, err = s.ExecContext(ctx, `INSERT OR IGNORE INTO conversation_keys
+		(conversation, key_id, wrapped_key) VALUES (?, ?, ?);`,
+		conversation, s.Keyring.currentID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return s.dataKey(ctx, conversation)
+}
+
+// Encrypts a message body with its conversation's data key, and returns
+// whether it did. The content is returned unchanged when the store has no
+// keyring.
+func (s *Store) EncryptContent(ctx context.Context, sender string, receiver string, content string) (string, bool, error) {
+	if s.Keyring == nil {
+		return content, false, nil
+	}
+
+	conversation := conversationID(sender, receiver)
+	aead, err := s.createDataKey(ctx, conversation)
+	if err != nil {
+		return "", false, fmt.Errorf("Encryption -> %s", err)
+	}
+
+	sealed := seal(aead, []byte(content), []byte(conversation))
+	return ENCRYPTED_PREFIX + base64.StdEncoding.EncodeToString(sealed), true, nil
+}
+
+// Reverses EncryptContent(). Only content stored as encrypted is decrypted,
+// plaintext is returned as it is whatever it starts with.
+func (s *Store) DecryptContent(ctx context.Context, sender string, receiver string, content string, encrypted bool) (string, error) {
+	if !encrypted {
+		return content, nil
+	}
+	if s.Keyring == nil {
+		return "", errors.New("Decryption -> message is encrypted but no MESSAGE_KEY is set")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(content, ENCRYPTED_PREFIX))
+	if err != nil {
+		return "", fmt.Errorf("Decryption -> %s", err)
+	}
+
+	conversation := conversationID(sender, receiver)
+	aead, err := s.dataKey(ctx, conversation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("Decryption -> %s has no data key", conversation)
+	} else if err != nil {
+		return "", fmt.Errorf("Decryption -> %s", err)
+	}
+
+	plaintext, err := unseal(aead, sealed, []byte(conversation))
+	if err != nil {
+		return "", fmt.Errorf("Decryption -> %s", err)
+	}
+	return string(plaintext), nil
+}
+
+// Re-wraps every data key that is not wrapped with the current key.
+// Messages are not touched since their data keys stay the same.
+func (s *Store) RotateDataKeys(ctx context.Context) (int, error) {
+	if s.Keyring == nil {
+		return 0, errors.New("Key rotation -> no MESSAGE_KEY is set")
+	}
+
+	tx, err := s.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT conversation, key_id, wrapped_key FROM conversation_keys
+		WHERE key_id != ?;`, s.Keyring.currentID)
+	if err != nil {
+		return 0, err
+	}
+
+	type rewrapped struct {
+		conversation string
+		wrapped      []byte
+	}
+	var updates []rewrapped
+	for rows.Next() {
+		var conversation, key_id string
+		var wrapped []byte
+		if err := rows.Scan(&conversation, &key_id, &wrapped); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		kek, ok := s.Keyring.keys[key_id]
+		if !ok {
+			rows.Close()
+			return 0, fmt.Errorf("Key rotation -> %s is wrapped with unknown key %s", conversation, key_id)
+		}
+		key, err := unseal(kek, wrapped, []byte(conversation))
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("Key rotation -> %s", err)
+		}
+
+		updates = append(updates, rewrapped{
+			conversation: conversation,
+			wrapped:      seal(s.Keyring.keys[s.Keyring.currentID], key, []byte(conversation)),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, update := range updates {
+		_, err := tx.ExecContext(ctx, `UPDATE conversation_keys SET key_id = ?, wrapped_key = ?
+			WHERE conversation = ?;`, s.Keyring.currentID, update.wrapped, update.conversation)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(updates), tx.Commit()
+}
+
+// Deletes the data keys of every conversation user takes part in. Messages of
+// those conversations can not be decrypted afterwards, so tx should delete them too.
+func (s *Store) DeleteDataKeys(ctx context.Context, tx *sql.Tx, user string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM conversation_keys
+		WHERE conversation LIKE ? || ':%' OR conversation LIKE '%:' || ?;`, user, user)
+	if err != nil {
+		return err
+	}
+
+	s.dataKeysMu.Lock()
+	defer s.dataKeysMu.Unlock()
+	for conversation := range s.dataKeys {
+		user_a, user_b, _ := strings.Cut(conversation, ":")
+		if user_a == user || user_b == user {
+			delete(s.dataKeys, conversation)
+		}
+	}
+	return nil
+}
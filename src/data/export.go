@@ -0,0 +1,305 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// How many records are inserted per transaction during an import
+const IMPORT_BATCH_SIZE int = 500
+
+// How many messages are read at a time during an export
+const EXPORT_PAGE_SIZE int = 500
+
+// Largest line accepted by Import()
+const MAX_RECORD_SIZE int = 1 << 20
+
+// Writes every user and message as newline-delimited messagingv1.ExportRecord JSON.
+// Users come first so that an import never references a missing user, and carry
+// their account state, preferences and, for bots, API keys and commands. Message
+// contents are decrypted, so the export can be imported with a different key.
+func (s *Store) Export(ctx context.Context, w io.Writer) (int, error) {
+	out := bufio.NewWriter(w)
+	count := 0
+
+	write := func(record *messagingv1.ExportRecord) error {
+		line, err := protojson.Marshal(record)
+		if err != nil {
+			return err
+		}
+		out.Write(line)
+		count++
+		return out.WriteByte('\n')
+	}
+
+	// Loaded first so that no other query runs while the users are read
+	api_keys, bot_commands, err := s.exportBots(ctx)
+	if err != nil {
+		return count, fmt.Errorf("Export -> %s", err)
+	}
+
+	users, err := s.QueryContext(ctx, `SELECT phone_number, username, password, salt, role, suspended,
+		tokens_valid_after, bot_owner, email, email_verified, email_digest, push FROM users
+		ORDER BY phone_number;`)
+	if err != nil {
+		return count, fmt.Errorf("Export -> %s", err)
+	}
+	defer users.Close()
+
+	for users.Next() {
+		var push bool
+		var bot_owner, email sql.NullString
+		user := &messagingv1.ExportedUser{Push: &push}
+		err := users.Scan(&user.PhoneNumber, &user.Username, &user.PasswordHash, &user.Salt, &user.Role, &user.Suspended,
+			&user.TokensValidAfter, &bot_owner, &email, &user.EmailVerified, &user.EmailDigest, &push)
+		if err != nil {
+			return count, fmt.Errorf("Export -> %s", err)
+		}
+		if bot_owner.Valid {
+			user.BotOwner = &bot_owner.String
+		}
+		if email.Valid {
+			user.Email = &email.String
+		}
+		user.ApiKeys = api_keys[user.PhoneNumber]
+		user.BotCommands = bot_commands[user.PhoneNumber]
+		if err := write(&messagingv1.ExportRecord{Record: &messagingv1.ExportRecord_User{User: user}}); err != nil {
+			return count, fmt.Errorf("Export -> %s", err)
+		}
+	}
+	if err := users.Err(); err != nil {
+		return count, fmt.Errorf("Export -> %s", err)
+	}
+
+	// Messages are read a page at a time and decrypted with no cursor open,
+	// since looking up a data key needs a connection of its own
+	var after uint64
+	for {
+		page, err := s.exportMessages(ctx, after)
+		if err != nil {
+			return count, fmt.Errorf("Export -> %s", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		after = page[len(page)-1].GetId()
+
+		for _, message := range page {
+			// Unreadable messages are left out so the rest can still be exported
+			message.Content, err = s.DecryptContent(ctx, message.Sender, message.Receiver, message.Content, message.encrypted)
+			if err != nil {
+				slog.Warn("Export skipped a message that can not be decrypted", "id", message.GetId(), "error", err)
+				continue
+			}
+			if err := write(&messagingv1.ExportRecord{Record: &messagingv1.ExportRecord_Message{Message: message.Message}}); err != nil {
+				return count, fmt.Errorf("Export -> %s", err)
+			}
+		}
+	}
+
+	return count, out.Flush()
+}
+
+type exportedMessage struct {
+	*messagingv1.Message
+	encrypted bool
+}
+
+// Returns up to EXPORT_PAGE_SIZE messages with an id after after, as stored
+func (s *Store) exportMessages(ctx context.Context, after uint64) ([]exportedMessage, error) {
+	rows, err := s.QueryContext(ctx, `SELECT id, sender, receiver, content, encrypted, timestamp, expires_at FROM messages
+		WHERE id > ? ORDER BY id LIMIT ?;`, after, EXPORT_PAGE_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var page []exportedMessage
+	for rows.Next() {
+		var id uint64
+		var timestamp string
+		var expires_at sql.NullString
+		message := exportedMessage{Message: &messagingv1.Message{Id: &id, Timestamp: &timestamp}}
+		err := rows.Scan(&id, &message.Sender, &message.Receiver, &message.Content, &message.encrypted, &timestamp, &expires_at)
+		if err != nil {
+			return nil, err
+		}
+		if expires_at.Valid {
+			message.ExpiresAt = &expires_at.String
+		}
+		page = append(page, message)
+	}
+	return page, rows.Err()
+}
+
+// Returns the API keys and commands of every bot by its phone number
+func (s *Store) exportBots(ctx context.Context) (
+	map[string][]*messagingv1.ExportedAPIKey,
+	map[string][]*messagingv1.BotCommand,
+	error,
+) {
+	api_keys := make(map[string][]*messagingv1.ExportedAPIKey)
+	bot_commands := make(map[string][]*messagingv1.BotCommand)
+
+	keys, err := s.QueryContext(ctx, `SELECT bot, id, hash, scopes, created_at, revoked_at FROM api_keys
+		ORDER BY created_at, id;`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer keys.Close()
+	for keys.Next() {
+		var bot, scopes string
+		var revoked_at sql.NullString
+		key := &messagingv1.ExportedAPIKey{}
+		if err := keys.Scan(&bot, &key.Id, &key.Hash, &scopes, &key.CreatedAt, &revoked_at); err != nil {
+			return nil, nil, err
+		}
+		key.Scopes = strings.Split(scopes, ",")
+		if revoked_at.Valid {
+			key.RevokedAt = &revoked_at.String
+		}
+		api_keys[bot] = append(api_keys[bot], key)
+	}
+	if err := keys.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	commands, err := s.QueryContext(ctx, `SELECT bot, name, description FROM bot_commands ORDER BY bot, name;`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer commands.Close()
+	for commands.Next() {
+		var bot string
+		command := &messagingv1.BotCommand{}
+		if err := commands.Scan(&bot, &command.Name, &command.Description); err != nil {
+			return nil, nil, err
+		}
+		bot_commands[bot] = append(bot_commands[bot], command)
+	}
+	return api_keys, bot_commands, commands.Err()
+}
+
+// Loads a file written by Export(). Ids and timestamps are kept, so importing
+// into a database that already has the same users or message ids fails.
+// Records are committed in batches, so a failed import may be partially applied.
+func (s *Store) Import(ctx context.Context, r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MAX_RECORD_SIZE)
+
+	var batch []*messagingv1.ExportRecord
+	count, line := 0, 0
+
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		record := &messagingv1.ExportRecord{}
+		if err := protojson.Unmarshal(scanner.Bytes(), record); err != nil {
+			return count, fmt.Errorf("Import -> line %d: %s", line, err)
+		}
+
+		// Encrypting may create data keys, which must not happen inside the batch transaction
+		if message := record.GetMessage(); message != nil {
+			content, _, err := s.EncryptContent(ctx, message.Sender, message.Receiver, message.Content)
+			if err != nil {
+				return count, fmt.Errorf("Import -> line %d: %s", line, err)
+			}
+			message.Content = content
+		}
+
+		batch = append(batch, record)
+		if len(batch) == IMPORT_BATCH_SIZE {
+			if err := s.importBatch(ctx, batch); err != nil {
+				return count, fmt.Errorf("Import -> before line %d: %s", line, err)
+			}
+			count += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("Import -> line %d: %s", line+1, err)
+	}
+
+	if err := s.importBatch(ctx, batch); err != nil {
+		return count, fmt.Errorf("Import -> %s", err)
+	}
+	return count + len(batch), nil
+}
+
+func (s *Store) importBatch(ctx context.Context, batch []*messagingv1.ExportRecord) error {
+	tx, err := s.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, record := range batch {
+		switch record := record.Record.(type) {
+		case *messagingv1.ExportRecord_User:
+			user := record.User
+			role := user.Role
+			if role == "" {
+				role = "user"
+			}
+			email_digest := user.EmailDigest
+			if email_digest == "" {
+				email_digest = "daily"
+			}
+			// Exports written before push was exported leave it unset
+			push := user.Push == nil || *user.Push
+			// Bots have an empty password, which protojson reads back as nil
+			password := user.PasswordHash
+			if password == nil {
+				password = []byte{}
+			}
+			_, err = tx.ExecContext(ctx, `INSERT INTO users (phone_number, username, password, salt, role, suspended,
+				tokens_valid_after, bot_owner, email, email_verified, email_digest, push)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+				user.PhoneNumber, user.Username, password, string(user.Salt), role, user.Suspended,
+				user.TokensValidAfter, user.BotOwner, user.Email, user.EmailVerified, email_digest, push)
+			if err != nil {
+				return err
+			}
+
+			for _, key := range user.ApiKeys {
+				_, err = tx.ExecContext(ctx, `INSERT INTO api_keys (id, bot, hash, scopes, created_at, revoked_at)
+					VALUES (?, ?, ?, ?, ?, ?);`,
+					key.Id, user.PhoneNumber, key.Hash, strings.Join(key.Scopes, ","), key.CreatedAt, key.RevokedAt)
+				if err != nil {
+					return err
+				}
+			}
+			for _, command := range user.BotCommands {
+				_, err = tx.ExecContext(ctx, `INSERT INTO bot_commands (bot, name, description) VALUES (?, ?, ?);`,
+					user.PhoneNumber, command.Name, command.Description)
+				if err != nil {
+					return err
+				}
+			}
+
+		case *messagingv1.ExportRecord_Message:
+			message := record.Message
+			// Import() encrypted the content whenever there is a keyring
+			_, err = tx.ExecContext(ctx, `INSERT INTO messages (id, sender, receiver, content, encrypted, timestamp, expires_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?);`,
+				message.Id, message.Sender, message.Receiver, message.Content, s.Keyring != nil,
+				message.GetTimestamp(), message.ExpiresAt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
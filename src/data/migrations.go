@@ -0,0 +1,107 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// A column added to a table that already existed in a release
+type column struct {
+	table      string
+	name       string
+	definition string
+	// Optional UPDATE that sets the column of existing rows
+	fill string
+}
+
+// Columns added to existing tables. The schema only creates tables that are
+// missing, so databases created by older versions get their new columns here.
+// PRAGMA user_version holds how many of these were applied, so migrations
+// must only ever be appended.
+var migrations = []column{
+	// Disappearing messages
+	{"messages", "expires_at", `TEXT`, ""},
+	// Roles
+	{"users", "role", `TEXT NOT NULL DEFAULT 'user'`, ""},
+	// Admin procedures
+	{"users", "suspended", `INTEGER NOT NULL DEFAULT 0`, ""},
+	{"users", "tokens_valid_after", `INTEGER NOT NULL DEFAULT 0`, ""},
+	// Bot accounts
+	{"users", "bot_owner", `TEXT`, ""},
+	// Email digests and notification preferences
+	{"users", "email", `TEXT`, ""},
+	{"users", "email_verified", `INTEGER NOT NULL DEFAULT 0`, ""},
+	{"users", "email_token", `BLOB`, ""},
+	{"users", "email_token_expires_at", `TEXT`, ""},
+	{"users", "email_digest", `TEXT NOT NULL DEFAULT 'daily'`, ""},
+	{"users", "push", `INTEGER NOT NULL DEFAULT 1`, ""},
+	{"users", "last_active_at", `TEXT`, ""},
+	{"users", "last_digest_at", `TEXT`, ""},
+	{"users", "digest_message_id", `INTEGER NOT NULL DEFAULT 0`, ""},
+	// Encrypted messages were only told apart by their prefix before
+	{"messages", "encrypted", `INTEGER NOT NULL DEFAULT 0`,
+		`UPDATE "messages" SET "encrypted" = 1 WHERE substr("content", 1, 5) = 'enc1:';`},
+	// Throttling verification emails per user
+	{"users", "email_sent_at", `TEXT`, ""},
+}
+
+// Returns whether the database has no tables yet, in which case the schema
+// creates every column and no migration has to run
+func isEmpty(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users';`).Scan(&count)
+	return count == 0, err
+}
+
+// Adds the columns of the migrations after the database's user_version, in
+// one transaction. Columns that already exist are skipped, since databases
+// created before versioning have some of them without a user_version.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version;`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= len(migrations) {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i, col := range migrations[version:] {
+		var exists bool
+		err = tx.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?;`, col.table, col.name).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("migration %d -> %w", version+i+1, err)
+		} else if exists {
+			continue
+		}
+
+		query := fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s;`, col.table, col.name, col.definition)
+		if _, err = tx.Exec(query); err != nil {
+			return fmt.Errorf("migration %d -> %w", version+i+1, err)
+		}
+		if col.fill == "" {
+			continue
+		}
+		if _, err = tx.Exec(col.fill); err != nil {
+			return fmt.Errorf("migration %d -> %w", version+i+1, err)
+		}
+	}
+	if err = setVersion(tx, len(migrations)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// PRAGMA does not take parameters
+func setVersion(db execer, version int) error {
+	_, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d;`, version))
+	return err
+}
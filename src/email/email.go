@@ -0,0 +1,196 @@
+// Sends plain text email over SMTP
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Longest address SMTP allows in a path, RFC 5321 section 4.5.3.1.3
+const MAX_ADDRESS_LENGTH int = 254
+
+var ErrInvalidAddress = errors.New("Email address is invalid")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Delivers an email. The SMTP sender is used in production, tests can use
+// their own
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Options struct {
+	// host:port of the SMTP server
+	Addr string
+	// Authenticates with PLAIN when set, which net/smtp only does over TLS or
+	// to localhost
+	Username string
+	Password string
+	// Connects with TLS instead of upgrading with STARTTLS, usually on port 465
+	ImplicitTLS bool
+	// Address every email is sent from
+	From string
+	// Timeout of sending one email, including the connection
+	Timeout time.Duration
+}
+
+// Sends every email over a new connection to an SMTP server. STARTTLS is used
+// whenever the server offers it
+type SMTP struct {
+	opts Options
+	host string
+}
+
+func NewSMTP(opts Options) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP address -> %w", err)
+	}
+	if opts.From, err = ValidateAddress(opts.From); err != nil {
+		return nil, fmt.Errorf("Sender address -> %w", err)
+	}
+	return &SMTP{opts: opts, host: host}, nil
+}
+
+// Returns the bare address of a single address without a display name, like
+// "user@example.com", or ErrInvalidAddress
+func ValidateAddress(address string) (string, error) {
+	if len(address) > MAX_ADDRESS_LENGTH || strings.ContainsAny(address, "\r\n<>") {
+		return "", ErrInvalidAddress
+	}
+	parsed, err := netmail.ParseAddress(address)
+	if err != nil || parsed.Name != "" || parsed.Address != address {
+		return "", ErrInvalidAddress
+	}
+	return parsed.Address, nil
+}
+
+func (m *SMTP) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: m.host, MinVersion: tls.VersionTLS12}
+}
+
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	to, err := ValidateAddress(msg.To)
+	if err != nil {
+		return err
+	}
+	body, err := Compose(m.opts.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if m.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.opts.Timeout)
+		defer cancel()
+	}
+	var conn net.Conn
+	if m.opts.ImplicitTLS {
+		dialer := &tls.Dialer{Config: m.tlsConfig()}
+		conn, err = dialer.DialContext(ctx, "tcp", m.opts.Addr)
+	} else {
+		dialer := &net.Dialer{}
+		conn, err = dialer.DialContext(ctx, "tcp", m.opts.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	// net/smtp has no contexts, so the deadline of ctx ends the whole exchange
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !m.opts.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(m.tlsConfig()); err != nil {
+				return fmt.Errorf("STARTTLS -> %w", err)
+			}
+		}
+	}
+	if m.opts.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.host))
+		if err != nil {
+			return fmt.Errorf("SMTP authentication -> %w", err)
+		}
+	}
+
+	if err = client.Mail(m.opts.From); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(body); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Returns msg from the address from as an RFC 5322 message with a quoted
+// printable UTF-8 body
+func Compose(from string, msg Message, date time.Time) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("Subject must be a single line")
+	}
+	_, domain, _ := strings.Cut(from, "@")
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	buffer := &bytes.Buffer{}
+	headers := [][2]string{
+		{"From", from},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", "<" + hex.EncodeToString(id) + "@" + domain + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+		// Digests should not be answered with vacation notices
+		{"Auto-Submitted", "auto-generated"},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(buffer, "%s: %s\r\n", header[0], header[1])
+	}
+	buffer.WriteString("\r\n")
+
+	// Text mode writes line breaks as CRLF
+	writer := quotedprintable.NewWriter(buffer)
+	if _, err := writer.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
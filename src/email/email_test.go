@@ -0,0 +1,199 @@
+package email_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vl0000/gomessenger/email"
+)
+
+// An SMTP server that accepts every recipient but those starting with
+// "nobody@", and passes the messages it receives to the test
+type smtpSink struct {
+	listener net.Listener
+	received chan string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &smtpSink{listener: listener, received: make(chan string, 8)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 sink ESMTP")
+
+	var envelope []string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 sink")
+		case "MAIL":
+			envelope = []string{args}
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			if strings.Contains(args, "<nobody@") {
+				text.PrintfLine("550 No such user")
+				continue
+			}
+			envelope = append(envelope, args)
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			body, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.received <- strings.Join(envelope, " ") + "\n" + string(body)
+			text.PrintfLine("250 Queued")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	// SETUP
+	sink := newSMTPSink(t)
+	defer sink.listener.Close()
+	sender, err := email.NewSMTP(email.Options{
+		Addr:    sink.listener.Addr().String(),
+		From:    "gomessenger@example.com",
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// END SETUP
+
+	t.Run("Sink receives the composed message", func(t *testing.T) {
+		err := sender.Send(context.TODO(), email.Message{
+			To:      "user@example.com",
+			Subject: "3 unread messages – gomessenger",
+			Body:    "Hello ünïcode\n.a line that starts with a dot\n",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var received string
+		select {
+		case received = <-sink.received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Sink received nothing")
+		}
+		envelope, raw, _ := strings.Cut(received, "\n")
+		if envelope != "FROM:<gomessenger@example.com> TO:<user@example.com>" {
+			t.Fatalf("Unexpected envelope %q", envelope)
+		}
+
+		message, err := netmail.ReadMessage(strings.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+		if err != nil || subject != "3 unread messages – gomessenger" {
+			t.Fatalf("Unexpected subject %q, %v", subject, err)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(message.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The sink reads lines without their CRLF
+		if string(body) != "Hello ünïcode\n.a line that starts with a dot\n" {
+			t.Fatalf("Unexpected body %q", body)
+		}
+	})
+
+	t.Run("Headers can not be injected", func(t *testing.T) {
+		err := sender.Send(context.TODO(), email.Message{To: "user@example.com\r\nBcc: other@example.com", Subject: "Hi"})
+		if !errors.Is(err, email.ErrInvalidAddress) {
+			t.Fatalf("Expected ErrInvalidAddress, got %v", err)
+		}
+		err = sender.Send(context.TODO(), email.Message{To: "user@example.com", Subject: "Hi\r\nBcc: other@example.com"})
+		if err == nil {
+			t.Fatal("Expected a multi-line subject to be refused")
+		}
+		for _, address := range []string{"User <user@example.com>", "user", "a@b, c@d"} {
+			if _, err = email.ValidateAddress(address); !errors.Is(err, email.ErrInvalidAddress) {
+				t.Fatalf("Expected %q to be invalid, got %v", address, err)
+			}
+		}
+	})
+
+	t.Run("Refused recipients are errors", func(t *testing.T) {
+		err := sender.Send(context.TODO(), email.Message{To: "nobody@example.com", Subject: "Hi"})
+		if err == nil || !strings.Contains(err.Error(), "550") {
+			t.Fatalf("Expected the refusal, got %v", err)
+		}
+	})
+
+	t.Run("Unreachable servers time out", func(t *testing.T) {
+		// SETUP
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		// Accepts connections but never greets
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				bufio.NewReader(conn).ReadByte()
+				conn.Close()
+			}
+		}()
+		silent, err := email.NewSMTP(email.Options{
+			Addr:    listener.Addr().String(),
+			From:    "gomessenger@example.com",
+			Timeout: 200 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		start := time.Now()
+		if err = silent.Send(context.TODO(), email.Message{To: "user@example.com", Subject: "Hi"}); err == nil {
+			t.Fatal("Expected a timeout")
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("Send took %s", time.Since(start))
+		}
+	})
+}
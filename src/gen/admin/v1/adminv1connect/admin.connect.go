@@ -0,0 +1,337 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: admin/v1/admin.proto
+
+package adminv1connect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	v1 "github.com/vl0000/gomessenger/gen/admin/v1"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// AdminServiceName is the fully-qualified name of the AdminService service.
+	AdminServiceName = "admin.v1.AdminService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// AdminServiceBackupDatabaseProcedure is the fully-qualified name of the AdminService's
+	// BackupDatabase RPC.
+	AdminServiceBackupDatabaseProcedure = "/admin.v1.AdminService/BackupDatabase"
+	// AdminServiceSetLogLevelProcedure is the fully-qualified name of the AdminService's SetLogLevel
+	// RPC.
+	AdminServiceSetLogLevelProcedure = "/admin.v1.AdminService/SetLogLevel"
+	// AdminServiceListUsersProcedure is the fully-qualified name of the AdminService's ListUsers RPC.
+	AdminServiceListUsersProcedure = "/admin.v1.AdminService/ListUsers"
+	// AdminServiceGetUserProcedure is the fully-qualified name of the AdminService's GetUser RPC.
+	AdminServiceGetUserProcedure = "/admin.v1.AdminService/GetUser"
+	// AdminServiceSuspendUserProcedure is the fully-qualified name of the AdminService's SuspendUser
+	// RPC.
+	AdminServiceSuspendUserProcedure = "/admin.v1.AdminService/SuspendUser"
+	// AdminServiceDeleteUserProcedure is the fully-qualified name of the AdminService's DeleteUser RPC.
+	AdminServiceDeleteUserProcedure = "/admin.v1.AdminService/DeleteUser"
+	// AdminServiceDeleteMessageProcedure is the fully-qualified name of the AdminService's
+	// DeleteMessage RPC.
+	AdminServiceDeleteMessageProcedure = "/admin.v1.AdminService/DeleteMessage"
+	// AdminServiceForceLogoutProcedure is the fully-qualified name of the AdminService's ForceLogout
+	// RPC.
+	AdminServiceForceLogoutProcedure = "/admin.v1.AdminService/ForceLogout"
+	// AdminServiceGetStatsProcedure is the fully-qualified name of the AdminService's GetStats RPC.
+	AdminServiceGetStatsProcedure = "/admin.v1.AdminService/GetStats"
+)
+
+// AdminServiceClient is a client for the admin.v1.AdminService service.
+type AdminServiceClient interface {
+	BackupDatabase(context.Context, *connect.Request[v1.BackupDatabaseRequest]) (*connect.Response[v1.BackupDatabaseResponse], error)
+	SetLogLevel(context.Context, *connect.Request[v1.SetLogLevelRequest]) (*connect.Response[v1.SetLogLevelResponse], error)
+	ListUsers(context.Context, *connect.Request[v1.ListUsersRequest]) (*connect.Response[v1.ListUsersResponse], error)
+	GetUser(context.Context, *connect.Request[v1.GetUserRequest]) (*connect.Response[v1.GetUserResponse], error)
+	SuspendUser(context.Context, *connect.Request[v1.SuspendUserRequest]) (*connect.Response[v1.SuspendUserResponse], error)
+	DeleteUser(context.Context, *connect.Request[v1.DeleteUserRequest]) (*connect.Response[v1.DeleteUserResponse], error)
+	DeleteMessage(context.Context, *connect.Request[v1.DeleteMessageRequest]) (*connect.Response[v1.DeleteMessageResponse], error)
+	ForceLogout(context.Context, *connect.Request[v1.ForceLogoutRequest]) (*connect.Response[v1.ForceLogoutResponse], error)
+	GetStats(context.Context, *connect.Request[v1.GetStatsRequest]) (*connect.Response[v1.GetStatsResponse], error)
+}
+
+// NewAdminServiceClient constructs a client for the admin.v1.AdminService service. By default, it
+// uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewAdminServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) AdminServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	adminServiceMethods := v1.File_admin_v1_admin_proto.Services().ByName("AdminService").Methods()
+	return &adminServiceClient{
+		backupDatabase: connect.NewClient[v1.BackupDatabaseRequest, v1.BackupDatabaseResponse](
+			httpClient,
+			baseURL+AdminServiceBackupDatabaseProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("BackupDatabase")),
+			connect.WithClientOptions(opts...),
+		),
+		setLogLevel: connect.NewClient[v1.SetLogLevelRequest, v1.SetLogLevelResponse](
+			httpClient,
+			baseURL+AdminServiceSetLogLevelProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("SetLogLevel")),
+			connect.WithClientOptions(opts...),
+		),
+		listUsers: connect.NewClient[v1.ListUsersRequest, v1.ListUsersResponse](
+			httpClient,
+			baseURL+AdminServiceListUsersProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ListUsers")),
+			connect.WithClientOptions(opts...),
+		),
+		getUser: connect.NewClient[v1.GetUserRequest, v1.GetUserResponse](
+			httpClient,
+			baseURL+AdminServiceGetUserProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("GetUser")),
+			connect.WithClientOptions(opts...),
+		),
+		suspendUser: connect.NewClient[v1.SuspendUserRequest, v1.SuspendUserResponse](
+			httpClient,
+			baseURL+AdminServiceSuspendUserProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("SuspendUser")),
+			connect.WithClientOptions(opts...),
+		),
+		deleteUser: connect.NewClient[v1.DeleteUserRequest, v1.DeleteUserResponse](
+			httpClient,
+			baseURL+AdminServiceDeleteUserProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("DeleteUser")),
+			connect.WithClientOptions(opts...),
+		),
+		deleteMessage: connect.NewClient[v1.DeleteMessageRequest, v1.DeleteMessageResponse](
+			httpClient,
+			baseURL+AdminServiceDeleteMessageProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("DeleteMessage")),
+			connect.WithClientOptions(opts...),
+		),
+		forceLogout: connect.NewClient[v1.ForceLogoutRequest, v1.ForceLogoutResponse](
+			httpClient,
+			baseURL+AdminServiceForceLogoutProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ForceLogout")),
+			connect.WithClientOptions(opts...),
+		),
+		getStats: connect.NewClient[v1.GetStatsRequest, v1.GetStatsResponse](
+			httpClient,
+			baseURL+AdminServiceGetStatsProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("GetStats")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// adminServiceClient implements AdminServiceClient.
+type adminServiceClient struct {
+	backupDatabase *connect.Client[v1.BackupDatabaseRequest, v1.BackupDatabaseResponse]
+	setLogLevel    *connect.Client[v1.SetLogLevelRequest, v1.SetLogLevelResponse]
+	listUsers      *connect.Client[v1.ListUsersRequest, v1.ListUsersResponse]
+	getUser        *connect.Client[v1.GetUserRequest, v1.GetUserResponse]
+	suspendUser    *connect.Client[v1.SuspendUserRequest, v1.SuspendUserResponse]
+	deleteUser     *connect.Client[v1.DeleteUserRequest, v1.DeleteUserResponse]
+	deleteMessage  *connect.Client[v1.DeleteMessageRequest, v1.DeleteMessageResponse]
+	forceLogout    *connect.Client[v1.ForceLogoutRequest, v1.ForceLogoutResponse]
+	getStats       *connect.Client[v1.GetStatsRequest, v1.GetStatsResponse]
+}
+
+// BackupDatabase calls admin.v1.AdminService.BackupDatabase.
+func (c *adminServiceClient) BackupDatabase(ctx context.Context, req *connect.Request[v1.BackupDatabaseRequest]) (*connect.Response[v1.BackupDatabaseResponse], error) {
+	return c.backupDatabase.CallUnary(ctx, req)
+}
+
+// SetLogLevel calls admin.v1.AdminService.SetLogLevel.
+func (c *adminServiceClient) SetLogLevel(ctx context.Context, req *connect.Request[v1.SetLogLevelRequest]) (*connect.Response[v1.SetLogLevelResponse], error) {
+	return c.setLogLevel.CallUnary(ctx, req)
+}
+
+// ListUsers calls admin.v1.AdminService.ListUsers.
+func (c *adminServiceClient) ListUsers(ctx context.Context, req *connect.Request[v1.ListUsersRequest]) (*connect.Response[v1.ListUsersResponse], error) {
+	return c.listUsers.CallUnary(ctx, req)
+}
+
+// GetUser calls admin.v1.AdminService.GetUser.
+func (c *adminServiceClient) GetUser(ctx context.Context, req *connect.Request[v1.GetUserRequest]) (*connect.Response[v1.GetUserResponse], error) {
+	return c.getUser.CallUnary(ctx, req)
+}
+
+// SuspendUser calls admin.v1.AdminService.SuspendUser.
+func (c *adminServiceClient) SuspendUser(ctx context.Context, req *connect.Request[v1.SuspendUserRequest]) (*connect.Response[v1.SuspendUserResponse], error) {
+	return c.suspendUser.CallUnary(ctx, req)
+}
+
+// DeleteUser calls admin.v1.AdminService.DeleteUser.
+func (c *adminServiceClient) DeleteUser(ctx context.Context, req *connect.Request[v1.DeleteUserRequest]) (*connect.Response[v1.DeleteUserResponse], error) {
+	return c.deleteUser.CallUnary(ctx, req)
+}
+
+// DeleteMessage calls admin.v1.AdminService.DeleteMessage.
+func (c *adminServiceClient) DeleteMessage(ctx context.Context, req *connect.Request[v1.DeleteMessageRequest]) (*connect.Response[v1.DeleteMessageResponse], error) {
+	return c.deleteMessage.CallUnary(ctx, req)
+}
+
+// ForceLogout calls admin.v1.AdminService.ForceLogout.
+func (c *adminServiceClient) ForceLogout(ctx context.Context, req *connect.Request[v1.ForceLogoutRequest]) (*connect.Response[v1.ForceLogoutResponse], error) {
+	return c.forceLogout.CallUnary(ctx, req)
+}
+
+// GetStats calls admin.v1.AdminService.GetStats.
+func (c *adminServiceClient) GetStats(ctx context.Context, req *connect.Request[v1.GetStatsRequest]) (*connect.Response[v1.GetStatsResponse], error) {
+	return c.getStats.CallUnary(ctx, req)
+}
+
+// AdminServiceHandler is an implementation of the admin.v1.AdminService service.
+type AdminServiceHandler interface {
+	BackupDatabase(context.Context, *connect.Request[v1.BackupDatabaseRequest]) (*connect.Response[v1.BackupDatabaseResponse], error)
+	SetLogLevel(context.Context, *connect.Request[v1.SetLogLevelRequest]) (*connect.Response[v1.SetLogLevelResponse], error)
+	ListUsers(context.Context, *connect.Request[v1.ListUsersRequest]) (*connect.Response[v1.ListUsersResponse], error)
+	GetUser(context.Context, *connect.Request[v1.GetUserRequest]) (*connect.Response[v1.GetUserResponse], error)
+	SuspendUser(context.Context, *connect.Request[v1.SuspendUserRequest]) (*connect.Response[v1.SuspendUserResponse], error)
+	DeleteUser(context.Context, *connect.Request[v1.DeleteUserRequest]) (*connect.Response[v1.DeleteUserResponse], error)
+	DeleteMessage(context.Context, *connect.Request[v1.DeleteMessageRequest]) (*connect.Response[v1.DeleteMessageResponse], error)
+	ForceLogout(context.Context, *connect.Request[v1.ForceLogoutRequest]) (*connect.Response[v1.ForceLogoutResponse], error)
+	GetStats(context.Context, *connect.Request[v1.GetStatsRequest]) (*connect.Response[v1.GetStatsResponse], error)
+}
+
+// NewAdminServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewAdminServiceHandler(svc AdminServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	adminServiceMethods := v1.File_admin_v1_admin_proto.Services().ByName("AdminService").Methods()
+	adminServiceBackupDatabaseHandler := connect.NewUnaryHandler(
+		AdminServiceBackupDatabaseProcedure,
+		svc.BackupDatabase,
+		connect.WithSchema(adminServiceMethods.ByName("BackupDatabase")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceSetLogLevelHandler := connect.NewUnaryHandler(
+		AdminServiceSetLogLevelProcedure,
+		svc.SetLogLevel,
+		connect.WithSchema(adminServiceMethods.ByName("SetLogLevel")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceListUsersHandler := connect.NewUnaryHandler(
+		AdminServiceListUsersProcedure,
+		svc.ListUsers,
+		connect.WithSchema(adminServiceMethods.ByName("ListUsers")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceGetUserHandler := connect.NewUnaryHandler(
+		AdminServiceGetUserProcedure,
+		svc.GetUser,
+		connect.WithSchema(adminServiceMethods.ByName("GetUser")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceSuspendUserHandler := connect.NewUnaryHandler(
+		AdminServiceSuspendUserProcedure,
+		svc.SuspendUser,
+		connect.WithSchema(adminServiceMethods.ByName("SuspendUser")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceDeleteUserHandler := connect.NewUnaryHandler(
+		AdminServiceDeleteUserProcedure,
+		svc.DeleteUser,
+		connect.WithSchema(adminServiceMethods.ByName("DeleteUser")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceDeleteMessageHandler := connect.NewUnaryHandler(
+		AdminServiceDeleteMessageProcedure,
+		svc.DeleteMessage,
+		connect.WithSchema(adminServiceMethods.ByName("DeleteMessage")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceForceLogoutHandler := connect.NewUnaryHandler(
+		AdminServiceForceLogoutProcedure,
+		svc.ForceLogout,
+		connect.WithSchema(adminServiceMethods.ByName("ForceLogout")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceGetStatsHandler := connect.NewUnaryHandler(
+		AdminServiceGetStatsProcedure,
+		svc.GetStats,
+		connect.WithSchema(adminServiceMethods.ByName("GetStats")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/admin.v1.AdminService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case AdminServiceBackupDatabaseProcedure:
+			adminServiceBackupDatabaseHandler.ServeHTTP(w, r)
+		case AdminServiceSetLogLevelProcedure:
+			adminServiceSetLogLevelHandler.ServeHTTP(w, r)
+		case AdminServiceListUsersProcedure:
+			adminServiceListUsersHandler.ServeHTTP(w, r)
+		case AdminServiceGetUserProcedure:
+			adminServiceGetUserHandler.ServeHTTP(w, r)
+		case AdminServiceSuspendUserProcedure:
+			adminServiceSuspendUserHandler.ServeHTTP(w, r)
+		case AdminServiceDeleteUserProcedure:
+			adminServiceDeleteUserHandler.ServeHTTP(w, r)
+		case AdminServiceDeleteMessageProcedure:
+			adminServiceDeleteMessageHandler.ServeHTTP(w, r)
+		case AdminServiceForceLogoutProcedure:
+			adminServiceForceLogoutHandler.ServeHTTP(w, r)
+		case AdminServiceGetStatsProcedure:
+			adminServiceGetStatsHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedAdminServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedAdminServiceHandler struct{}
+
+func (UnimplementedAdminServiceHandler) BackupDatabase(context.Context, *connect.Request[v1.BackupDatabaseRequest]) (*connect.Response[v1.BackupDatabaseResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.BackupDatabase is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) SetLogLevel(context.Context, *connect.Request[v1.SetLogLevelRequest]) (*connect.Response[v1.SetLogLevelResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.SetLogLevel is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ListUsers(context.Context, *connect.Request[v1.ListUsersRequest]) (*connect.Response[v1.ListUsersResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.ListUsers is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) GetUser(context.Context, *connect.Request[v1.GetUserRequest]) (*connect.Response[v1.GetUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.GetUser is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) SuspendUser(context.Context, *connect.Request[v1.SuspendUserRequest]) (*connect.Response[v1.SuspendUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.SuspendUser is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) DeleteUser(context.Context, *connect.Request[v1.DeleteUserRequest]) (*connect.Response[v1.DeleteUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.DeleteUser is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) DeleteMessage(context.Context, *connect.Request[v1.DeleteMessageRequest]) (*connect.Response[v1.DeleteMessageResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.DeleteMessage is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ForceLogout(context.Context, *connect.Request[v1.ForceLogoutRequest]) (*connect.Response[v1.ForceLogoutResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.ForceLogout is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) GetStats(context.Context, *connect.Request[v1.GetStatsRequest]) (*connect.Response[v1.GetStatsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.v1.AdminService.GetStats is not implemented"))
+}
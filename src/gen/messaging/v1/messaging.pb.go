@@ -7,6 +7,7 @@
 package messagingv1
 
 import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -22,12 +23,14 @@ const (
 )
 
 type Message struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            *uint64                `protobuf:"varint,1,opt,name=id,proto3,oneof" json:"id,omitempty"`
-	Sender        string                 `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
-	Receiver      string                 `protobuf:"bytes,3,opt,name=receiver,proto3" json:"receiver,omitempty"`
-	Content       string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
-	Timestamp     *string                `protobuf:"bytes,5,opt,name=timestamp,proto3,oneof" json:"timestamp,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        *uint64                `protobuf:"varint,1,opt,name=id,proto3,oneof" json:"id,omitempty"`
+	Sender    string                 `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
+	Receiver  string                 `protobuf:"bytes,3,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	Content   string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	Timestamp *string                `protobuf:"bytes,5,opt,name=timestamp,proto3,oneof" json:"timestamp,omitempty"`
+	// Set for disappearing messages. Same format as timestamp.
+	ExpiresAt     *string `protobuf:"bytes,6,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -97,6 +100,13 @@ func (x *Message) GetTimestamp() string {
 	return ""
 }
 
+func (x *Message) GetExpiresAt() string {
+	if x != nil && x.ExpiresAt != nil {
+		return *x.ExpiresAt
+	}
+	return ""
+}
+
 type RegisterUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
@@ -298,8 +308,10 @@ func (x *LoginResponse) GetJwtToken() string {
 }
 
 type SendDirectMessageRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Message *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// Seconds until the message is deleted. Messages without it never expire.
+	TtlSeconds    *uint32 `protobuf:"varint,2,opt,name=ttl_seconds,json=ttlSeconds,proto3,oneof" json:"ttl_seconds,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -341,6 +353,13 @@ func (x *SendDirectMessageRequest) GetMessage() *Message {
 	return nil
 }
 
+func (x *SendDirectMessageRequest) GetTtlSeconds() uint32 {
+	if x != nil && x.TtlSeconds != nil {
+		return *x.TtlSeconds
+	}
+	return 0
+}
+
 type GetDMsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserA         string                 `protobuf:"bytes,1,opt,name=user_a,json=userA,proto3" json:"user_a,omitempty"`
@@ -402,8 +421,14 @@ func (x *GetDMsRequest) GetFromDate() string {
 }
 
 type GetDMsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Messages      []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Messages []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	// Ids of messages that expired and must be removed from the chat.
+	ExpiredIds []uint64 `protobuf:"varint,2,rep,packed,name=expired_ids,json=expiredIds,proto3" json:"expired_ids,omitempty"`
+	// Set when the other participant is typing.
+	Typing bool `protobuf:"varint,3,opt,name=typing,proto3" json:"typing,omitempty"`
+	// Ids of messages the other participant acknowledged receiving.
+	AckedIds      []uint64 `protobuf:"varint,4,rep,packed,name=acked_ids,json=ackedIds,proto3" json:"acked_ids,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -445,9 +470,33 @@ func (x *GetDMsResponse) GetMessages() []*Message {
 	return nil
 }
 
+func (x *GetDMsResponse) GetExpiredIds() []uint64 {
+	if x != nil {
+		return x.ExpiredIds
+	}
+	return nil
+}
+
+func (x *GetDMsResponse) GetTyping() bool {
+	if x != nil {
+		return x.Typing
+	}
+	return false
+}
+
+func (x *GetDMsResponse) GetAckedIds() []uint64 {
+	if x != nil {
+		return x.AckedIds
+	}
+	return nil
+}
+
 type SendDirectMessageResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Empty when a command like /mute was run instead of sending a message
+	Message *Message `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// Result of a command, shown only to the sender
+	Notice        string `protobuf:"bytes,2,opt,name=notice,proto3" json:"notice,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -489,6 +538,13 @@ func (x *SendDirectMessageResponse) GetMessage() *Message {
 	return nil
 }
 
+func (x *SendDirectMessageResponse) GetNotice() string {
+	if x != nil {
+		return x.Notice
+	}
+	return ""
+}
+
 type GetUserInfoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PhoneNumber   string                 `protobuf:"bytes,1,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
@@ -585,113 +641,3105 @@ func (x *GetUserInfoResponse) GetUsername() string {
 	return ""
 }
 
-var File_messaging_v1_messaging_proto protoreflect.FileDescriptor
+// A user as written by the export command. The password is only present hashed.
+type ExportedUser struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	PhoneNumber  string                 `protobuf:"bytes,1,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
+	Username     string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	PasswordHash []byte                 `protobuf:"bytes,3,opt,name=password_hash,json=passwordHash,proto3" json:"password_hash,omitempty"`
+	Salt         []byte                 `protobuf:"bytes,4,opt,name=salt,proto3" json:"salt,omitempty"`
+	Role         string                 `protobuf:"bytes,5,opt,name=role,proto3" json:"role,omitempty"`
+	Suspended    bool                   `protobuf:"varint,6,opt,name=suspended,proto3" json:"suspended,omitempty"`
+	// Unix time before which issued tokens are rejected
+	TokensValidAfter int64 `protobuf:"varint,7,opt,name=tokens_valid_after,json=tokensValidAfter,proto3" json:"tokens_valid_after,omitempty"`
+	// Set for bots
+	BotOwner      *string           `protobuf:"bytes,8,opt,name=bot_owner,json=botOwner,proto3,oneof" json:"bot_owner,omitempty"`
+	ApiKeys       []*ExportedAPIKey `protobuf:"bytes,9,rep,name=api_keys,json=apiKeys,proto3" json:"api_keys,omitempty"`
+	BotCommands   []*BotCommand     `protobuf:"bytes,10,rep,name=bot_commands,json=botCommands,proto3" json:"bot_commands,omitempty"`
+	Email         *string           `protobuf:"bytes,11,opt,name=email,proto3,oneof" json:"email,omitempty"`
+	EmailVerified bool              `protobuf:"varint,12,opt,name=email_verified,json=emailVerified,proto3" json:"email_verified,omitempty"`
+	EmailDigest   string            `protobuf:"bytes,13,opt,name=email_digest,json=emailDigest,proto3" json:"email_digest,omitempty"`
+	// Unset in exports written before it was added, which means enabled
+	Push          *bool `protobuf:"varint,14,opt,name=push,proto3,oneof" json:"push,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_messaging_v1_messaging_proto_rawDesc = "" +
-	"\n" +
-	"\x1cmessaging/v1/messaging.proto\x12\fmessaging.v1\"\xa4\x01\n" +
-	"\aMessage\x12\x13\n" +
-	"\x02id\x18\x01 \x01(\x04H\x00R\x02id\x88\x01\x01\x12\x16\n" +
-	"\x06sender\x18\x02 \x01(\tR\x06sender\x12\x1a\n" +
-	"\breceiver\x18\x03 \x01(\tR\breceiver\x12\x18\n" +
-	"\acontent\x18\x04 \x01(\tR\acontent\x12!\n" +
-	"\ttimestamp\x18\x05 \x01(\tH\x01R\ttimestamp\x88\x01\x01B\x05\n" +
-	"\x03_idB\f\n" +
-	"\n" +
-	"_timestamp\"p\n" +
-	"\x13RegisterUserRequest\x12\x1a\n" +
-	"\busername\x18\x01 \x01(\tR\busername\x12!\n" +
-	"\fphone_number\x18\x02 \x01(\tR\vphoneNumber\x12\x1a\n" +
-	"\bpassword\x18\x03 \x01(\tR\bpassword\"3\n" +
-	"\x14RegisterUserResponse\x12\x1b\n" +
-	"\tjwt_token\x18\x01 \x01(\tR\bjwtToken\"M\n" +
-	"\fLoginRequest\x12!\n" +
-	"\fphone_number\x18\x01 \x01(\tR\vphoneNumber\x12\x1a\n" +
-	"\bpassword\x18\x02 \x01(\tR\bpassword\",\n" +
-	"\rLoginResponse\x12\x1b\n" +
-	"\tjwt_token\x18\x01 \x01(\tR\bjwtToken\"K\n" +
-	"\x18SendDirectMessageRequest\x12/\n" +
-	"\amessage\x18\x01 \x01(\v2\x15.messaging.v1.MessageR\amessage\"Z\n" +
-	"\rGetDMsRequest\x12\x15\n" +
-	"\x06user_a\x18\x01 \x01(\tR\x05userA\x12\x15\n" +
-	"\x06user_b\x18\x02 \x01(\tR\x05userB\x12\x1b\n" +
-	"\tfrom_date\x18\x03 \x01(\tR\bfromDate\"C\n" +
-	"\x0eGetDMsResponse\x121\n" +
-	"\bmessages\x18\x01 \x03(\v2\x15.messaging.v1.MessageR\bmessages\"L\n" +
-	"\x19SendDirectMessageResponse\x12/\n" +
-	"\amessage\x18\x01 \x01(\v2\x15.messaging.v1.MessageR\amessage\"7\n" +
-	"\x12GetUserInfoRequest\x12!\n" +
-	"\fphone_number\x18\x01 \x01(\tR\vphoneNumber\"T\n" +
-	"\x13GetUserInfoResponse\x12!\n" +
-	"\fphone_number\x18\x01 \x01(\tR\vphoneNumber\x12\x1a\n" +
-	"\busername\x18\x02 \x01(\tR\busername2\xb6\x03\n" +
-	"\x10MessagingService\x12f\n" +
-	"\x11SendDirectMessage\x12&.messaging.v1.SendDirectMessageRequest\x1a'.messaging.v1.SendDirectMessageResponse\"\x00\x12G\n" +
-	"\x06GetDMs\x12\x1b.messaging.v1.GetDMsRequest\x1a\x1c.messaging.v1.GetDMsResponse\"\x000\x01\x12W\n" +
-	"\fRegisterUser\x12!.messaging.v1.RegisterUserRequest\x1a\".messaging.v1.RegisterUserResponse\"\x00\x12B\n" +
-	"\x05Login\x12\x1a.messaging.v1.LoginRequest\x1a\x1b.messaging.v1.LoginResponse\"\x00\x12T\n" +
-	"\vGetUserInfo\x12 .messaging.v1.GetUserInfoRequest\x1a!.messaging.v1.GetUserInfoResponse\"\x00B<Z:github.com/vl0000/gomessenger/gen/messaging/v1;messagingv1b\x06proto3"
+func (x *ExportedUser) Reset() {
+	*x = ExportedUser{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-var (
-	file_messaging_v1_messaging_proto_rawDescOnce sync.Once
-	file_messaging_v1_messaging_proto_rawDescData []byte
-)
+func (x *ExportedUser) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
-func file_messaging_v1_messaging_proto_rawDescGZIP() []byte {
-	file_messaging_v1_messaging_proto_rawDescOnce.Do(func() {
-		file_messaging_v1_messaging_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_messaging_v1_messaging_proto_rawDesc), len(file_messaging_v1_messaging_proto_rawDesc)))
-	})
-	return file_messaging_v1_messaging_proto_rawDescData
+func (*ExportedUser) ProtoMessage() {}
+
+func (x *ExportedUser) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-var file_messaging_v1_messaging_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
-var file_messaging_v1_messaging_proto_goTypes = []any{
-	(*Message)(nil),                   // 0: messaging.v1.Message
-	(*RegisterUserRequest)(nil),       // 1: messaging.v1.RegisterUserRequest
-	(*RegisterUserResponse)(nil),      // 2: messaging.v1.RegisterUserResponse
-	(*LoginRequest)(nil),              // 3: messaging.v1.LoginRequest
-	(*LoginResponse)(nil),             // 4: messaging.v1.LoginResponse
-	(*SendDirectMessageRequest)(nil),  // 5: messaging.v1.SendDirectMessageRequest
-	(*GetDMsRequest)(nil),             // 6: messaging.v1.GetDMsRequest
-	(*GetDMsResponse)(nil),            // 7: messaging.v1.GetDMsResponse
-	(*SendDirectMessageResponse)(nil), // 8: messaging.v1.SendDirectMessageResponse
-	(*GetUserInfoRequest)(nil),        // 9: messaging.v1.GetUserInfoRequest
-	(*GetUserInfoResponse)(nil),       // 10: messaging.v1.GetUserInfoResponse
+// Deprecated: Use ExportedUser.ProtoReflect.Descriptor instead.
+func (*ExportedUser) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{11}
 }
-var file_messaging_v1_messaging_proto_depIdxs = []int32{
-	0,  // 0: messaging.v1.SendDirectMessageRequest.message:type_name -> messaging.v1.Message
-	0,  // 1: messaging.v1.GetDMsResponse.messages:type_name -> messaging.v1.Message
-	0,  // 2: messaging.v1.SendDirectMessageResponse.message:type_name -> messaging.v1.Message
-	5,  // 3: messaging.v1.MessagingService.SendDirectMessage:input_type -> messaging.v1.SendDirectMessageRequest
-	6,  // 4: messaging.v1.MessagingService.GetDMs:input_type -> messaging.v1.GetDMsRequest
-	1,  // 5: messaging.v1.MessagingService.RegisterUser:input_type -> messaging.v1.RegisterUserRequest
-	3,  // 6: messaging.v1.MessagingService.Login:input_type -> messaging.v1.LoginRequest
-	9,  // 7: messaging.v1.MessagingService.GetUserInfo:input_type -> messaging.v1.GetUserInfoRequest
-	8,  // 8: messaging.v1.MessagingService.SendDirectMessage:output_type -> messaging.v1.SendDirectMessageResponse
-	7,  // 9: messaging.v1.MessagingService.GetDMs:output_type -> messaging.v1.GetDMsResponse
-	2,  // 10: messaging.v1.MessagingService.RegisterUser:output_type -> messaging.v1.RegisterUserResponse
-	4,  // 11: messaging.v1.MessagingService.Login:output_type -> messaging.v1.LoginResponse
-	10, // 12: messaging.v1.MessagingService.GetUserInfo:output_type -> messaging.v1.GetUserInfoResponse
-	8,  // [8:13] is the sub-list for method output_type
-	3,  // [3:8] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+
+func (x *ExportedUser) GetPhoneNumber() string {
+	if x != nil {
+		return x.PhoneNumber
+	}
+	return ""
 }
 
-func init() { file_messaging_v1_messaging_proto_init() }
-func file_messaging_v1_messaging_proto_init() {
-	if File_messaging_v1_messaging_proto != nil {
-		return
+func (x *ExportedUser) GetUsername() string {
+	if x != nil {
+		return x.Username
 	}
-	file_messaging_v1_messaging_proto_msgTypes[0].OneofWrappers = []any{}
+	return ""
+}
+
+func (x *ExportedUser) GetPasswordHash() []byte {
+	if x != nil {
+		return x.PasswordHash
+	}
+	return nil
+}
+
+func (x *ExportedUser) GetSalt() []byte {
+	if x != nil {
+		return x.Salt
+	}
+	return nil
+}
+
+func (x *ExportedUser) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *ExportedUser) GetSuspended() bool {
+	if x != nil {
+		return x.Suspended
+	}
+	return false
+}
+
+func (x *ExportedUser) GetTokensValidAfter() int64 {
+	if x != nil {
+		return x.TokensValidAfter
+	}
+	return 0
+}
+
+func (x *ExportedUser) GetBotOwner() string {
+	if x != nil && x.BotOwner != nil {
+		return *x.BotOwner
+	}
+	return ""
+}
+
+func (x *ExportedUser) GetApiKeys() []*ExportedAPIKey {
+	if x != nil {
+		return x.ApiKeys
+	}
+	return nil
+}
+
+func (x *ExportedUser) GetBotCommands() []*BotCommand {
+	if x != nil {
+		return x.BotCommands
+	}
+	return nil
+}
+
+func (x *ExportedUser) GetEmail() string {
+	if x != nil && x.Email != nil {
+		return *x.Email
+	}
+	return ""
+}
+
+func (x *ExportedUser) GetEmailVerified() bool {
+	if x != nil {
+		return x.EmailVerified
+	}
+	return false
+}
+
+func (x *ExportedUser) GetEmailDigest() string {
+	if x != nil {
+		return x.EmailDigest
+	}
+	return ""
+}
+
+func (x *ExportedUser) GetPush() bool {
+	if x != nil && x.Push != nil {
+		return *x.Push
+	}
+	return false
+}
+
+// An API key of a bot. Only the hash of its secret is present.
+type ExportedAPIKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Hash          []byte                 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Scopes        []string               `protobuf:"bytes,3,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	CreatedAt     string                 `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	RevokedAt     *string                `protobuf:"bytes,5,opt,name=revoked_at,json=revokedAt,proto3,oneof" json:"revoked_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportedAPIKey) Reset() {
+	*x = ExportedAPIKey{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportedAPIKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportedAPIKey) ProtoMessage() {}
+
+func (x *ExportedAPIKey) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportedAPIKey.ProtoReflect.Descriptor instead.
+func (*ExportedAPIKey) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ExportedAPIKey) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ExportedAPIKey) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *ExportedAPIKey) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *ExportedAPIKey) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *ExportedAPIKey) GetRevokedAt() string {
+	if x != nil && x.RevokedAt != nil {
+		return *x.RevokedAt
+	}
+	return ""
+}
+
+// One line of an export file
+type ExportRecord struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Record:
+	//
+	//	*ExportRecord_User
+	//	*ExportRecord_Message
+	Record        isExportRecord_Record `protobuf_oneof:"record"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRecord) Reset() {
+	*x = ExportRecord{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRecord) ProtoMessage() {}
+
+func (x *ExportRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRecord.ProtoReflect.Descriptor instead.
+func (*ExportRecord) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ExportRecord) GetRecord() isExportRecord_Record {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *ExportRecord) GetUser() *ExportedUser {
+	if x != nil {
+		if x, ok := x.Record.(*ExportRecord_User); ok {
+			return x.User
+		}
+	}
+	return nil
+}
+
+func (x *ExportRecord) GetMessage() *Message {
+	if x != nil {
+		if x, ok := x.Record.(*ExportRecord_Message); ok {
+			return x.Message
+		}
+	}
+	return nil
+}
+
+type isExportRecord_Record interface {
+	isExportRecord_Record()
+}
+
+type ExportRecord_User struct {
+	User *ExportedUser `protobuf:"bytes,1,opt,name=user,proto3,oneof"`
+}
+
+type ExportRecord_Message struct {
+	Message *Message `protobuf:"bytes,2,opt,name=message,proto3,oneof"`
+}
+
+func (*ExportRecord_User) isExportRecord_Record() {}
+
+func (*ExportRecord_Message) isExportRecord_Record() {}
+
+type Webhook struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url   string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	// message.received, message.sent or user.registered
+	Events []string `protobuf:"bytes,3,rep,name=events,proto3" json:"events,omitempty"`
+	// Global webhooks are created by admins and receive the events of every user.
+	// Only they can subscribe to user.registered.
+	Global        bool   `protobuf:"varint,4,opt,name=global,proto3" json:"global,omitempty"`
+	CreatedAt     string `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Webhook) Reset() {
+	*x = Webhook{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Webhook) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Webhook) ProtoMessage() {}
+
+func (x *Webhook) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Webhook.ProtoReflect.Descriptor instead.
+func (*Webhook) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Webhook) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Webhook) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Webhook) GetEvents() []string {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *Webhook) GetGlobal() bool {
+	if x != nil {
+		return x.Global
+	}
+	return false
+}
+
+func (x *Webhook) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+type CreateWebhookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Events        []string               `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+	Global        bool                   `protobuf:"varint,3,opt,name=global,proto3" json:"global,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateWebhookRequest) Reset() {
+	*x = CreateWebhookRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateWebhookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateWebhookRequest) ProtoMessage() {}
+
+func (x *CreateWebhookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateWebhookRequest.ProtoReflect.Descriptor instead.
+func (*CreateWebhookRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CreateWebhookRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CreateWebhookRequest) GetEvents() []string {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *CreateWebhookRequest) GetGlobal() bool {
+	if x != nil {
+		return x.Global
+	}
+	return false
+}
+
+type CreateWebhookResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Webhook *Webhook               `protobuf:"bytes,1,opt,name=webhook,proto3" json:"webhook,omitempty"`
+	// Key of the X-Gomessenger-Signature HMAC. It is only returned here.
+	Secret        string `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateWebhookResponse) Reset() {
+	*x = CreateWebhookResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateWebhookResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateWebhookResponse) ProtoMessage() {}
+
+func (x *CreateWebhookResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateWebhookResponse.ProtoReflect.Descriptor instead.
+func (*CreateWebhookResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CreateWebhookResponse) GetWebhook() *Webhook {
+	if x != nil {
+		return x.Webhook
+	}
+	return nil
+}
+
+func (x *CreateWebhookResponse) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+// Lists the caller's webhooks, and the global ones for admins
+type ListWebhooksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWebhooksRequest) Reset() {
+	*x = ListWebhooksRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWebhooksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWebhooksRequest) ProtoMessage() {}
+
+func (x *ListWebhooksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWebhooksRequest.ProtoReflect.Descriptor instead.
+func (*ListWebhooksRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{17}
+}
+
+type ListWebhooksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Webhooks      []*Webhook             `protobuf:"bytes,1,rep,name=webhooks,proto3" json:"webhooks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWebhooksResponse) Reset() {
+	*x = ListWebhooksResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWebhooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWebhooksResponse) ProtoMessage() {}
+
+func (x *ListWebhooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWebhooksResponse.ProtoReflect.Descriptor instead.
+func (*ListWebhooksResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListWebhooksResponse) GetWebhooks() []*Webhook {
+	if x != nil {
+		return x.Webhooks
+	}
+	return nil
+}
+
+type DeleteWebhookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteWebhookRequest) Reset() {
+	*x = DeleteWebhookRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteWebhookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteWebhookRequest) ProtoMessage() {}
+
+func (x *DeleteWebhookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteWebhookRequest.ProtoReflect.Descriptor instead.
+func (*DeleteWebhookRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DeleteWebhookRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteWebhookResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteWebhookResponse) Reset() {
+	*x = DeleteWebhookResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteWebhookResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteWebhookResponse) ProtoMessage() {}
+
+func (x *DeleteWebhookResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteWebhookResponse.ProtoReflect.Descriptor instead.
+func (*DeleteWebhookResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{20}
+}
+
+type WebhookDelivery struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Event string                 `protobuf:"bytes,2,opt,name=event,proto3" json:"event,omitempty"`
+	// pending, delivered or failed
+	Status   string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Attempts uint32 `protobuf:"varint,4,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	// HTTP status of the last attempt, 0 when no response was received
+	ResponseCode int32 `protobuf:"varint,5,opt,name=response_code,json=responseCode,proto3" json:"response_code,omitempty"`
+	// Why the last attempt failed
+	Error     string `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt string `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// When a pending delivery is attempted again
+	NextAttemptAt string `protobuf:"bytes,8,opt,name=next_attempt_at,json=nextAttemptAt,proto3" json:"next_attempt_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WebhookDelivery) Reset() {
+	*x = WebhookDelivery{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WebhookDelivery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebhookDelivery) ProtoMessage() {}
+
+func (x *WebhookDelivery) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebhookDelivery.ProtoReflect.Descriptor instead.
+func (*WebhookDelivery) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *WebhookDelivery) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WebhookDelivery) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *WebhookDelivery) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *WebhookDelivery) GetAttempts() uint32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *WebhookDelivery) GetResponseCode() int32 {
+	if x != nil {
+		return x.ResponseCode
+	}
+	return 0
+}
+
+func (x *WebhookDelivery) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *WebhookDelivery) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *WebhookDelivery) GetNextAttemptAt() string {
+	if x != nil {
+		return x.NextAttemptAt
+	}
+	return ""
+}
+
+// Newest deliveries first
+type ListWebhookDeliveriesRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	WebhookId uint64                 `protobuf:"varint,1,opt,name=webhook_id,json=webhookId,proto3" json:"webhook_id,omitempty"`
+	// Defaults to 50, at most 500
+	PageSize      int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWebhookDeliveriesRequest) Reset() {
+	*x = ListWebhookDeliveriesRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWebhookDeliveriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWebhookDeliveriesRequest) ProtoMessage() {}
+
+func (x *ListWebhookDeliveriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWebhookDeliveriesRequest.ProtoReflect.Descriptor instead.
+func (*ListWebhookDeliveriesRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListWebhookDeliveriesRequest) GetWebhookId() uint64 {
+	if x != nil {
+		return x.WebhookId
+	}
+	return 0
+}
+
+func (x *ListWebhookDeliveriesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type ListWebhookDeliveriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deliveries    []*WebhookDelivery     `protobuf:"bytes,1,rep,name=deliveries,proto3" json:"deliveries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWebhookDeliveriesResponse) Reset() {
+	*x = ListWebhookDeliveriesResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWebhookDeliveriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWebhookDeliveriesResponse) ProtoMessage() {}
+
+func (x *ListWebhookDeliveriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWebhookDeliveriesResponse.ProtoReflect.Descriptor instead.
+func (*ListWebhookDeliveriesResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ListWebhookDeliveriesResponse) GetDeliveries() []*WebhookDelivery {
+	if x != nil {
+		return x.Deliveries
+	}
+	return nil
+}
+
+// Lets services without a client, like CI pipelines, post into a conversation
+// of its owner. Messages posted to it are sent by the owner to receiver.
+type IncomingWebhook struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Receiver string                 `protobuf:"bytes,2,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	// Shown to the owner to tell their webhooks apart
+	Name          string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	CreatedAt     string `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncomingWebhook) Reset() {
+	*x = IncomingWebhook{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncomingWebhook) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncomingWebhook) ProtoMessage() {}
+
+func (x *IncomingWebhook) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncomingWebhook.ProtoReflect.Descriptor instead.
+func (*IncomingWebhook) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *IncomingWebhook) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *IncomingWebhook) GetReceiver() string {
+	if x != nil {
+		return x.Receiver
+	}
+	return ""
+}
+
+func (x *IncomingWebhook) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *IncomingWebhook) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+type CreateIncomingWebhookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Receiver      string                 `protobuf:"bytes,1,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateIncomingWebhookRequest) Reset() {
+	*x = CreateIncomingWebhookRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateIncomingWebhookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateIncomingWebhookRequest) ProtoMessage() {}
+
+func (x *CreateIncomingWebhookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateIncomingWebhookRequest.ProtoReflect.Descriptor instead.
+func (*CreateIncomingWebhookRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *CreateIncomingWebhookRequest) GetReceiver() string {
+	if x != nil {
+		return x.Receiver
+	}
+	return ""
+}
+
+func (x *CreateIncomingWebhookRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type CreateIncomingWebhookResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	IncomingWebhook *IncomingWebhook       `protobuf:"bytes,1,opt,name=incoming_webhook,json=incomingWebhook,proto3" json:"incoming_webhook,omitempty"`
+	// Path on this server that accepts POST {"text": ...}. It contains the
+	// webhook's secret token and is only returned here.
+	Path          string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateIncomingWebhookResponse) Reset() {
+	*x = CreateIncomingWebhookResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateIncomingWebhookResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateIncomingWebhookResponse) ProtoMessage() {}
+
+func (x *CreateIncomingWebhookResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateIncomingWebhookResponse.ProtoReflect.Descriptor instead.
+func (*CreateIncomingWebhookResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *CreateIncomingWebhookResponse) GetIncomingWebhook() *IncomingWebhook {
+	if x != nil {
+		return x.IncomingWebhook
+	}
+	return nil
+}
+
+func (x *CreateIncomingWebhookResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type ListIncomingWebhooksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListIncomingWebhooksRequest) Reset() {
+	*x = ListIncomingWebhooksRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListIncomingWebhooksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListIncomingWebhooksRequest) ProtoMessage() {}
+
+func (x *ListIncomingWebhooksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListIncomingWebhooksRequest.ProtoReflect.Descriptor instead.
+func (*ListIncomingWebhooksRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{27}
+}
+
+type ListIncomingWebhooksResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	IncomingWebhooks []*IncomingWebhook     `protobuf:"bytes,1,rep,name=incoming_webhooks,json=incomingWebhooks,proto3" json:"incoming_webhooks,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ListIncomingWebhooksResponse) Reset() {
+	*x = ListIncomingWebhooksResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListIncomingWebhooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListIncomingWebhooksResponse) ProtoMessage() {}
+
+func (x *ListIncomingWebhooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListIncomingWebhooksResponse.ProtoReflect.Descriptor instead.
+func (*ListIncomingWebhooksResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ListIncomingWebhooksResponse) GetIncomingWebhooks() []*IncomingWebhook {
+	if x != nil {
+		return x.IncomingWebhooks
+	}
+	return nil
+}
+
+type DeleteIncomingWebhookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteIncomingWebhookRequest) Reset() {
+	*x = DeleteIncomingWebhookRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteIncomingWebhookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteIncomingWebhookRequest) ProtoMessage() {}
+
+func (x *DeleteIncomingWebhookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteIncomingWebhookRequest.ProtoReflect.Descriptor instead.
+func (*DeleteIncomingWebhookRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *DeleteIncomingWebhookRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteIncomingWebhookResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteIncomingWebhookResponse) Reset() {
+	*x = DeleteIncomingWebhookResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteIncomingWebhookResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteIncomingWebhookResponse) ProtoMessage() {}
+
+func (x *DeleteIncomingWebhookResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteIncomingWebhookResponse.ProtoReflect.Descriptor instead.
+func (*DeleteIncomingWebhookResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{30}
+}
+
+// Keys of a browser's push subscription, as PushSubscription.toJSON() returns them
+type PushSubscription struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Endpoint string                 `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	// Base64url keys from the "keys" object
+	P256Dh        string `protobuf:"bytes,2,opt,name=p256dh,proto3" json:"p256dh,omitempty"`
+	Auth          string `protobuf:"bytes,3,opt,name=auth,proto3" json:"auth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushSubscription) Reset() {
+	*x = PushSubscription{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushSubscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushSubscription) ProtoMessage() {}
+
+func (x *PushSubscription) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushSubscription.ProtoReflect.Descriptor instead.
+func (*PushSubscription) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *PushSubscription) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *PushSubscription) GetP256Dh() string {
+	if x != nil {
+		return x.P256Dh
+	}
+	return ""
+}
+
+func (x *PushSubscription) GetAuth() string {
+	if x != nil {
+		return x.Auth
+	}
+	return ""
+}
+
+type GetPushPublicKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPushPublicKeyRequest) Reset() {
+	*x = GetPushPublicKeyRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPushPublicKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPushPublicKeyRequest) ProtoMessage() {}
+
+func (x *GetPushPublicKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPushPublicKeyRequest.ProtoReflect.Descriptor instead.
+func (*GetPushPublicKeyRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{32}
+}
+
+type GetPushPublicKeyResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The applicationServerKey to pass to pushManager.subscribe()
+	VapidPublicKey string `protobuf:"bytes,1,opt,name=vapid_public_key,json=vapidPublicKey,proto3" json:"vapid_public_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetPushPublicKeyResponse) Reset() {
+	*x = GetPushPublicKeyResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPushPublicKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPushPublicKeyResponse) ProtoMessage() {}
+
+func (x *GetPushPublicKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPushPublicKeyResponse.ProtoReflect.Descriptor instead.
+func (*GetPushPublicKeyResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *GetPushPublicKeyResponse) GetVapidPublicKey() string {
+	if x != nil {
+		return x.VapidPublicKey
+	}
+	return ""
+}
+
+// Sends messages to the browser while the caller has no open stream for their chat
+type RegisterPushSubscriptionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subscription  *PushSubscription      `protobuf:"bytes,1,opt,name=subscription,proto3" json:"subscription,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterPushSubscriptionRequest) Reset() {
+	*x = RegisterPushSubscriptionRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterPushSubscriptionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterPushSubscriptionRequest) ProtoMessage() {}
+
+func (x *RegisterPushSubscriptionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterPushSubscriptionRequest.ProtoReflect.Descriptor instead.
+func (*RegisterPushSubscriptionRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *RegisterPushSubscriptionRequest) GetSubscription() *PushSubscription {
+	if x != nil {
+		return x.Subscription
+	}
+	return nil
+}
+
+type RegisterPushSubscriptionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterPushSubscriptionResponse) Reset() {
+	*x = RegisterPushSubscriptionResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterPushSubscriptionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterPushSubscriptionResponse) ProtoMessage() {}
+
+func (x *RegisterPushSubscriptionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterPushSubscriptionResponse.ProtoReflect.Descriptor instead.
+func (*RegisterPushSubscriptionResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{35}
+}
+
+type UnregisterPushSubscriptionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Endpoint      string                 `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnregisterPushSubscriptionRequest) Reset() {
+	*x = UnregisterPushSubscriptionRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnregisterPushSubscriptionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnregisterPushSubscriptionRequest) ProtoMessage() {}
+
+func (x *UnregisterPushSubscriptionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnregisterPushSubscriptionRequest.ProtoReflect.Descriptor instead.
+func (*UnregisterPushSubscriptionRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *UnregisterPushSubscriptionRequest) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+type UnregisterPushSubscriptionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnregisterPushSubscriptionResponse) Reset() {
+	*x = UnregisterPushSubscriptionResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnregisterPushSubscriptionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnregisterPushSubscriptionResponse) ProtoMessage() {}
+
+func (x *UnregisterPushSubscriptionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnregisterPushSubscriptionResponse.ProtoReflect.Descriptor instead.
+func (*UnregisterPushSubscriptionResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{37}
+}
+
+type NotificationPreferences struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Empty when the caller has not set an address
+	Email         string `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	EmailVerified bool   `protobuf:"varint,2,opt,name=email_verified,json=emailVerified,proto3" json:"email_verified,omitempty"`
+	// How often unread messages are emailed: off, hourly, daily or weekly
+	EmailDigest string `protobuf:"bytes,3,opt,name=email_digest,json=emailDigest,proto3" json:"email_digest,omitempty"`
+	// Web Push notifications of new messages
+	Push          bool `protobuf:"varint,4,opt,name=push,proto3" json:"push,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NotificationPreferences) Reset() {
+	*x = NotificationPreferences{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NotificationPreferences) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NotificationPreferences) ProtoMessage() {}
+
+func (x *NotificationPreferences) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NotificationPreferences.ProtoReflect.Descriptor instead.
+func (*NotificationPreferences) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *NotificationPreferences) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *NotificationPreferences) GetEmailVerified() bool {
+	if x != nil {
+		return x.EmailVerified
+	}
+	return false
+}
+
+func (x *NotificationPreferences) GetEmailDigest() string {
+	if x != nil {
+		return x.EmailDigest
+	}
+	return ""
+}
+
+func (x *NotificationPreferences) GetPush() bool {
+	if x != nil {
+		return x.Push
+	}
+	return false
+}
+
+// Sets the caller's email address and emails them a token to verify it with.
+// An empty address removes it
+type SetEmailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetEmailRequest) Reset() {
+	*x = SetEmailRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetEmailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetEmailRequest) ProtoMessage() {}
+
+func (x *SetEmailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetEmailRequest.ProtoReflect.Descriptor instead.
+func (*SetEmailRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *SetEmailRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+type SetEmailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetEmailResponse) Reset() {
+	*x = SetEmailResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetEmailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetEmailResponse) ProtoMessage() {}
+
+func (x *SetEmailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetEmailResponse.ProtoReflect.Descriptor instead.
+func (*SetEmailResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{40}
+}
+
+type VerifyEmailRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Token from the verification email
+	Token         string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyEmailRequest) Reset() {
+	*x = VerifyEmailRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyEmailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyEmailRequest) ProtoMessage() {}
+
+func (x *VerifyEmailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyEmailRequest.ProtoReflect.Descriptor instead.
+func (*VerifyEmailRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *VerifyEmailRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type VerifyEmailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyEmailResponse) Reset() {
+	*x = VerifyEmailResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyEmailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyEmailResponse) ProtoMessage() {}
+
+func (x *VerifyEmailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyEmailResponse.ProtoReflect.Descriptor instead.
+func (*VerifyEmailResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{42}
+}
+
+type GetNotificationPreferencesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNotificationPreferencesRequest) Reset() {
+	*x = GetNotificationPreferencesRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNotificationPreferencesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNotificationPreferencesRequest) ProtoMessage() {}
+
+func (x *GetNotificationPreferencesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNotificationPreferencesRequest.ProtoReflect.Descriptor instead.
+func (*GetNotificationPreferencesRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{43}
+}
+
+type GetNotificationPreferencesResponse struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Preferences   *NotificationPreferences `protobuf:"bytes,1,opt,name=preferences,proto3" json:"preferences,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNotificationPreferencesResponse) Reset() {
+	*x = GetNotificationPreferencesResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNotificationPreferencesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNotificationPreferencesResponse) ProtoMessage() {}
+
+func (x *GetNotificationPreferencesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNotificationPreferencesResponse.ProtoReflect.Descriptor instead.
+func (*GetNotificationPreferencesResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *GetNotificationPreferencesResponse) GetPreferences() *NotificationPreferences {
+	if x != nil {
+		return x.Preferences
+	}
+	return nil
+}
+
+// Fields that are not set are left unchanged
+type SetNotificationPreferencesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailDigest   *string                `protobuf:"bytes,1,opt,name=email_digest,json=emailDigest,proto3,oneof" json:"email_digest,omitempty"`
+	Push          *bool                  `protobuf:"varint,2,opt,name=push,proto3,oneof" json:"push,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetNotificationPreferencesRequest) Reset() {
+	*x = SetNotificationPreferencesRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetNotificationPreferencesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetNotificationPreferencesRequest) ProtoMessage() {}
+
+func (x *SetNotificationPreferencesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetNotificationPreferencesRequest.ProtoReflect.Descriptor instead.
+func (*SetNotificationPreferencesRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *SetNotificationPreferencesRequest) GetEmailDigest() string {
+	if x != nil && x.EmailDigest != nil {
+		return *x.EmailDigest
+	}
+	return ""
+}
+
+func (x *SetNotificationPreferencesRequest) GetPush() bool {
+	if x != nil && x.Push != nil {
+		return *x.Push
+	}
+	return false
+}
+
+type SetNotificationPreferencesResponse struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Preferences   *NotificationPreferences `protobuf:"bytes,1,opt,name=preferences,proto3" json:"preferences,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetNotificationPreferencesResponse) Reset() {
+	*x = SetNotificationPreferencesResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetNotificationPreferencesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetNotificationPreferencesResponse) ProtoMessage() {}
+
+func (x *SetNotificationPreferencesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetNotificationPreferencesResponse.ProtoReflect.Descriptor instead.
+func (*SetNotificationPreferencesResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *SetNotificationPreferencesResponse) GetPreferences() *NotificationPreferences {
+	if x != nil {
+		return x.Preferences
+	}
+	return nil
+}
+
+type Bot struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Used like a phone number, e.g. as the sender of the bot's messages
+	Id       string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	// Scopes of the active API key
+	Scopes []string `protobuf:"bytes,3,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	// Id of the active API key, which is also part of the key
+	KeyId         string `protobuf:"bytes,4,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Bot) Reset() {
+	*x = Bot{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bot) ProtoMessage() {}
+
+func (x *Bot) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bot.ProtoReflect.Descriptor instead.
+func (*Bot) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *Bot) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Bot) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *Bot) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *Bot) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+// Creates a bot owned by the caller together with its first API key
+type CreateBotRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Lowercase letters, digits and dashes. The bot's id is "bot-<name>".
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	// messages:send, messages:read and users:read
+	Scopes        []string `protobuf:"bytes,3,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateBotRequest) Reset() {
+	*x = CreateBotRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateBotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateBotRequest) ProtoMessage() {}
+
+func (x *CreateBotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateBotRequest.ProtoReflect.Descriptor instead.
+func (*CreateBotRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *CreateBotRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateBotRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *CreateBotRequest) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+type CreateBotResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Bot   *Bot                   `protobuf:"bytes,1,opt,name=bot,proto3" json:"bot,omitempty"`
+	// Sent in the Authorization header instead of a JWT. It is only returned here.
+	ApiKey        string `protobuf:"bytes,2,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateBotResponse) Reset() {
+	*x = CreateBotResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateBotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateBotResponse) ProtoMessage() {}
+
+func (x *CreateBotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateBotResponse.ProtoReflect.Descriptor instead.
+func (*CreateBotResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *CreateBotResponse) GetBot() *Bot {
+	if x != nil {
+		return x.Bot
+	}
+	return nil
+}
+
+func (x *CreateBotResponse) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+// Replaces the bot's API key. The previous key stops working immediately.
+type RotateBotKeyRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	BotId string                 `protobuf:"bytes,1,opt,name=bot_id,json=botId,proto3" json:"bot_id,omitempty"`
+	// The previous key's scopes are kept when empty
+	Scopes        []string `protobuf:"bytes,2,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateBotKeyRequest) Reset() {
+	*x = RotateBotKeyRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateBotKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateBotKeyRequest) ProtoMessage() {}
+
+func (x *RotateBotKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateBotKeyRequest.ProtoReflect.Descriptor instead.
+func (*RotateBotKeyRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *RotateBotKeyRequest) GetBotId() string {
+	if x != nil {
+		return x.BotId
+	}
+	return ""
+}
+
+func (x *RotateBotKeyRequest) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+type RotateBotKeyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bot           *Bot                   `protobuf:"bytes,1,opt,name=bot,proto3" json:"bot,omitempty"`
+	ApiKey        string                 `protobuf:"bytes,2,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateBotKeyResponse) Reset() {
+	*x = RotateBotKeyResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateBotKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateBotKeyResponse) ProtoMessage() {}
+
+func (x *RotateBotKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateBotKeyResponse.ProtoReflect.Descriptor instead.
+func (*RotateBotKeyResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *RotateBotKeyResponse) GetBot() *Bot {
+	if x != nil {
+		return x.Bot
+	}
+	return nil
+}
+
+func (x *RotateBotKeyResponse) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+type ListBotsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBotsRequest) Reset() {
+	*x = ListBotsRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBotsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBotsRequest) ProtoMessage() {}
+
+func (x *ListBotsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBotsRequest.ProtoReflect.Descriptor instead.
+func (*ListBotsRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{52}
+}
+
+type ListBotsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bots          []*Bot                 `protobuf:"bytes,1,rep,name=bots,proto3" json:"bots,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBotsResponse) Reset() {
+	*x = ListBotsResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBotsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBotsResponse) ProtoMessage() {}
+
+func (x *ListBotsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBotsResponse.ProtoReflect.Descriptor instead.
+func (*ListBotsResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *ListBotsResponse) GetBots() []*Bot {
+	if x != nil {
+		return x.Bots
+	}
+	return nil
+}
+
+// Deletes the bot together with its messages and API keys
+type DeleteBotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BotId         string                 `protobuf:"bytes,1,opt,name=bot_id,json=botId,proto3" json:"bot_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBotRequest) Reset() {
+	*x = DeleteBotRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBotRequest) ProtoMessage() {}
+
+func (x *DeleteBotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBotRequest.ProtoReflect.Descriptor instead.
+func (*DeleteBotRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *DeleteBotRequest) GetBotId() string {
+	if x != nil {
+		return x.BotId
+	}
+	return ""
+}
+
+type DeleteBotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBotResponse) Reset() {
+	*x = DeleteBotResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBotResponse) ProtoMessage() {}
+
+func (x *DeleteBotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBotResponse.ProtoReflect.Descriptor instead.
+func (*DeleteBotResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{55}
+}
+
+type BotCommand struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Typed as /<name>
+	Name          string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BotCommand) Reset() {
+	*x = BotCommand{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BotCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BotCommand) ProtoMessage() {}
+
+func (x *BotCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BotCommand.ProtoReflect.Descriptor instead.
+func (*BotCommand) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *BotCommand) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *BotCommand) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+// Replaces the commands of a bot of the caller
+type SetBotCommandsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BotId         string                 `protobuf:"bytes,1,opt,name=bot_id,json=botId,proto3" json:"bot_id,omitempty"`
+	Commands      []*BotCommand          `protobuf:"bytes,2,rep,name=commands,proto3" json:"commands,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetBotCommandsRequest) Reset() {
+	*x = SetBotCommandsRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBotCommandsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBotCommandsRequest) ProtoMessage() {}
+
+func (x *SetBotCommandsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBotCommandsRequest.ProtoReflect.Descriptor instead.
+func (*SetBotCommandsRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *SetBotCommandsRequest) GetBotId() string {
+	if x != nil {
+		return x.BotId
+	}
+	return ""
+}
+
+func (x *SetBotCommandsRequest) GetCommands() []*BotCommand {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+type SetBotCommandsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetBotCommandsResponse) Reset() {
+	*x = SetBotCommandsResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBotCommandsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBotCommandsResponse) ProtoMessage() {}
+
+func (x *SetBotCommandsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBotCommandsResponse.ProtoReflect.Descriptor instead.
+func (*SetBotCommandsResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{58}
+}
+
+// Lists the commands that can be typed in the chat with user_b
+type ListCommandsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserB         string                 `protobuf:"bytes,1,opt,name=user_b,json=userB,proto3" json:"user_b,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCommandsRequest) Reset() {
+	*x = ListCommandsRequest{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCommandsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCommandsRequest) ProtoMessage() {}
+
+func (x *ListCommandsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCommandsRequest.ProtoReflect.Descriptor instead.
+func (*ListCommandsRequest) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *ListCommandsRequest) GetUserB() string {
+	if x != nil {
+		return x.UserB
+	}
+	return ""
+}
+
+type ListCommandsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Built-in commands first, then those of user_b if it is a bot
+	Commands      []*BotCommand `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCommandsResponse) Reset() {
+	*x = ListCommandsResponse{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCommandsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCommandsResponse) ProtoMessage() {}
+
+func (x *ListCommandsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCommandsResponse.ProtoReflect.Descriptor instead.
+func (*ListCommandsResponse) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *ListCommandsResponse) GetCommands() []*BotCommand {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+// Data of command.invoked webhook deliveries
+type CommandInvocation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Bot   string                 `protobuf:"bytes,1,opt,name=bot,proto3" json:"bot,omitempty"`
+	Name  string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// Text after the command's name
+	Args string `protobuf:"bytes,3,opt,name=args,proto3" json:"args,omitempty"`
+	// The message that invoked the command, which the bot receives as well
+	Message       *Message `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandInvocation) Reset() {
+	*x = CommandInvocation{}
+	mi := &file_messaging_v1_messaging_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandInvocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandInvocation) ProtoMessage() {}
+
+func (x *CommandInvocation) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_messaging_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandInvocation.ProtoReflect.Descriptor instead.
+func (*CommandInvocation) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_messaging_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *CommandInvocation) GetBot() string {
+	if x != nil {
+		return x.Bot
+	}
+	return ""
+}
+
+func (x *CommandInvocation) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CommandInvocation) GetArgs() string {
+	if x != nil {
+		return x.Args
+	}
+	return ""
+}
+
+func (x *CommandInvocation) GetMessage() *Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+var File_messaging_v1_messaging_proto protoreflect.FileDescriptor
+
+const file_messaging_v1_messaging_proto_rawDesc = "" +
+	"\n" +
+	"\x1cmessaging/v1/messaging.proto\x12\fmessaging.v1\x1a\x1cgoogle/api/annotations.proto\"\xd7\x01\n" +
+	"\aMessage\x12\x13\n" +
+	"\x02id\x18\x01 \x01(\x04H\x00R\x02id\x88\x01\x01\x12\x16\n" +
+	"\x06sender\x18\x02 \x01(\tR\x06sender\x12\x1a\n" +
+	"\breceiver\x18\x03 \x01(\tR\breceiver\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\x12!\n" +
+	"\ttimestamp\x18\x05 \x01(\tH\x01R\ttimestamp\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\tH\x02R\texpiresAt\x88\x01\x01B\x05\n" +
+	"\x03_idB\f\n" +
+	"\n" +
+	"_timestampB\r\n" +
+	"\v_expires_at\"p\n" +
+	"\x13RegisterUserRequest\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12!\n" +
+	"\fphone_number\x18\x02 \x01(\tR\vphoneNumber\x12\x1a\n" +
+	"\bpassword\x18\x03 \x01(\tR\bpassword\"3\n" +
+	"\x14RegisterUserResponse\x12\x1b\n" +
+	"\tjwt_token\x18\x01 \x01(\tR\bjwtToken\"M\n" +
+	"\fLoginRequest\x12!\n" +
+	"\fphone_number\x18\x01 \x01(\tR\vphoneNumber\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\",\n" +
+	"\rLoginResponse\x12\x1b\n" +
+	"\tjwt_token\x18\x01 \x01(\tR\bjwtToken\"\x81\x01\n" +
+	"\x18SendDirectMessageRequest\x12/\n" +
+	"\amessage\x18\x01 \x01(\v2\x15.messaging.v1.MessageR\amessage\x12$\n" +
+	"\vttl_seconds\x18\x02 \x01(\rH\x00R\n" +
+	"ttlSeconds\x88\x01\x01B\x0e\n" +
+	"\f_ttl_seconds\"Z\n" +
+	"\rGetDMsRequest\x12\x15\n" +
+	"\x06user_a\x18\x01 \x01(\tR\x05userA\x12\x15\n" +
+	"\x06user_b\x18\x02 \x01(\tR\x05userB\x12\x1b\n" +
+	"\tfrom_date\x18\x03 \x01(\tR\bfromDate\"\x99\x01\n" +
+	"\x0eGetDMsResponse\x121\n" +
+	"\bmessages\x18\x01 \x03(\v2\x15.messaging.v1.MessageR\bmessages\x12\x1f\n" +
+	"\vexpired_ids\x18\x02 \x03(\x04R\n" +
+	"expiredIds\x12\x16\n" +
+	"\x06typing\x18\x03 \x01(\bR\x06typing\x12\x1b\n" +
+	"\tacked_ids\x18\x04 \x03(\x04R\backedIds\"d\n" +
+	"\x19SendDirectMessageResponse\x12/\n" +
+	"\amessage\x18\x01 \x01(\v2\x15.messaging.v1.MessageR\amessage\x12\x16\n" +
+	"\x06notice\x18\x02 \x01(\tR\x06notice\"7\n" +
+	"\x12GetUserInfoRequest\x12!\n" +
+	"\fphone_number\x18\x01 \x01(\tR\vphoneNumber\"T\n" +
+	"\x13GetUserInfoResponse\x12!\n" +
+	"\fphone_number\x18\x01 \x01(\tR\vphoneNumber\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\"\x9d\x04\n" +
+	"\fExportedUser\x12!\n" +
+	"\fphone_number\x18\x01 \x01(\tR\vphoneNumber\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12#\n" +
+	"\rpassword_hash\x18\x03 \x01(\fR\fpasswordHash\x12\x12\n" +
+	"\x04salt\x18\x04 \x01(\fR\x04salt\x12\x12\n" +
+	"\x04role\x18\x05 \x01(\tR\x04role\x12\x1c\n" +
+	"\tsuspended\x18\x06 \x01(\bR\tsuspended\x12,\n" +
+	"\x12tokens_valid_after\x18\a \x01(\x03R\x10tokensValidAfter\x12 \n" +
+	"\tbot_owner\x18\b \x01(\tH\x00R\bbotOwner\x88\x01\x01\x127\n" +
+	"\bapi_keys\x18\t \x03(\v2\x1c.messaging.v1.ExportedAPIKeyR\aapiKeys\x12;\n" +
+	"\fbot_commands\x18\n" +
+	" \x03(\v2\x18.messaging.v1.BotCommandR\vbotCommands\x12\x19\n" +
+	"\x05email\x18\v \x01(\tH\x01R\x05email\x88\x01\x01\x12%\n" +
+	"\x0eemail_verified\x18\f \x01(\bR\remailVerified\x12!\n" +
+	"\femail_digest\x18\r \x01(\tR\vemailDigest\x12\x17\n" +
+	"\x04push\x18\x0e \x01(\bH\x02R\x04push\x88\x01\x01B\f\n" +
+	"\n" +
+	"_bot_ownerB\b\n" +
+	"\x06_emailB\a\n" +
+	"\x05_push\"\x9e\x01\n" +
+	"\x0eExportedAPIKey\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\fR\x04hash\x12\x16\n" +
+	"\x06scopes\x18\x03 \x03(\tR\x06scopes\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\tR\tcreatedAt\x12\"\n" +
+	"\n" +
+	"revoked_at\x18\x05 \x01(\tH\x00R\trevokedAt\x88\x01\x01B\r\n" +
+	"\v_revoked_at\"}\n" +
+	"\fExportRecord\x120\n" +
+	"\x04user\x18\x01 \x01(\v2\x1a.messaging.v1.ExportedUserH\x00R\x04user\x121\n" +
+	"\amessage\x18\x02 \x01(\v2\x15.messaging.v1.MessageH\x00R\amessageB\b\n" +
+	"\x06record\"z\n" +
+	"\aWebhook\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x16\n" +
+	"\x06events\x18\x03 \x03(\tR\x06events\x12\x16\n" +
+	"\x06global\x18\x04 \x01(\bR\x06global\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\tR\tcreatedAt\"X\n" +
+	"\x14CreateWebhookRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x16\n" +
+	"\x06events\x18\x02 \x03(\tR\x06events\x12\x16\n" +
+	"\x06global\x18\x03 \x01(\bR\x06global\"`\n" +
+	"\x15CreateWebhookResponse\x12/\n" +
+	"\awebhook\x18\x01 \x01(\v2\x15.messaging.v1.WebhookR\awebhook\x12\x16\n" +
+	"\x06secret\x18\x02 \x01(\tR\x06secret\"\x15\n" +
+	"\x13ListWebhooksRequest\"I\n" +
+	"\x14ListWebhooksResponse\x121\n" +
+	"\bwebhooks\x18\x01 \x03(\v2\x15.messaging.v1.WebhookR\bwebhooks\"&\n" +
+	"\x14DeleteWebhookRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"\x17\n" +
+	"\x15DeleteWebhookResponse\"\xed\x01\n" +
+	"\x0fWebhookDelivery\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x14\n" +
+	"\x05event\x18\x02 \x01(\tR\x05event\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1a\n" +
+	"\battempts\x18\x04 \x01(\rR\battempts\x12#\n" +
+	"\rresponse_code\x18\x05 \x01(\x05R\fresponseCode\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\a \x01(\tR\tcreatedAt\x12&\n" +
+	"\x0fnext_attempt_at\x18\b \x01(\tR\rnextAttemptAt\"Z\n" +
+	"\x1cListWebhookDeliveriesRequest\x12\x1d\n" +
+	"\n" +
+	"webhook_id\x18\x01 \x01(\x04R\twebhookId\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\"^\n" +
+	"\x1dListWebhookDeliveriesResponse\x12=\n" +
+	"\n" +
+	"deliveries\x18\x01 \x03(\v2\x1d.messaging.v1.WebhookDeliveryR\n" +
+	"deliveries\"p\n" +
+	"\x0fIncomingWebhook\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\breceiver\x18\x02 \x01(\tR\breceiver\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\tR\tcreatedAt\"N\n" +
+	"\x1cCreateIncomingWebhookRequest\x12\x1a\n" +
+	"\breceiver\x18\x01 \x01(\tR\breceiver\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"}\n" +
+	"\x1dCreateIncomingWebhookResponse\x12H\n" +
+	"\x10incoming_webhook\x18\x01 \x01(\v2\x1d.messaging.v1.IncomingWebhookR\x0fincomingWebhook\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\"\x1d\n" +
+	"\x1bListIncomingWebhooksRequest\"j\n" +
+	"\x1cListIncomingWebhooksResponse\x12J\n" +
+	"\x11incoming_webhooks\x18\x01 \x03(\v2\x1d.messaging.v1.IncomingWebhookR\x10incomingWebhooks\".\n" +
+	"\x1cDeleteIncomingWebhookRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x1f\n" +
+	"\x1dDeleteIncomingWebhookResponse\"Z\n" +
+	"\x10PushSubscription\x12\x1a\n" +
+	"\bendpoint\x18\x01 \x01(\tR\bendpoint\x12\x16\n" +
+	"\x06p256dh\x18\x02 \x01(\tR\x06p256dh\x12\x12\n" +
+	"\x04auth\x18\x03 \x01(\tR\x04auth\"\x19\n" +
+	"\x17GetPushPublicKeyRequest\"D\n" +
+	"\x18GetPushPublicKeyResponse\x12(\n" +
+	"\x10vapid_public_key\x18\x01 \x01(\tR\x0evapidPublicKey\"e\n" +
+	"\x1fRegisterPushSubscriptionRequest\x12B\n" +
+	"\fsubscription\x18\x01 \x01(\v2\x1e.messaging.v1.PushSubscriptionR\fsubscription\"\"\n" +
+	" RegisterPushSubscriptionResponse\"?\n" +
+	"!UnregisterPushSubscriptionRequest\x12\x1a\n" +
+	"\bendpoint\x18\x01 \x01(\tR\bendpoint\"$\n" +
+	"\"UnregisterPushSubscriptionResponse\"\x8d\x01\n" +
+	"\x17NotificationPreferences\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x12%\n" +
+	"\x0eemail_verified\x18\x02 \x01(\bR\remailVerified\x12!\n" +
+	"\femail_digest\x18\x03 \x01(\tR\vemailDigest\x12\x12\n" +
+	"\x04push\x18\x04 \x01(\bR\x04push\"'\n" +
+	"\x0fSetEmailRequest\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\"\x12\n" +
+	"\x10SetEmailResponse\"*\n" +
+	"\x12VerifyEmailRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"\x15\n" +
+	"\x13VerifyEmailResponse\"#\n" +
+	"!GetNotificationPreferencesRequest\"m\n" +
+	"\"GetNotificationPreferencesResponse\x12G\n" +
+	"\vpreferences\x18\x01 \x01(\v2%.messaging.v1.NotificationPreferencesR\vpreferences\"~\n" +
+	"!SetNotificationPreferencesRequest\x12&\n" +
+	"\femail_digest\x18\x01 \x01(\tH\x00R\vemailDigest\x88\x01\x01\x12\x17\n" +
+	"\x04push\x18\x02 \x01(\bH\x01R\x04push\x88\x01\x01B\x0f\n" +
+	"\r_email_digestB\a\n" +
+	"\x05_push\"m\n" +
+	"\"SetNotificationPreferencesResponse\x12G\n" +
+	"\vpreferences\x18\x01 \x01(\v2%.messaging.v1.NotificationPreferencesR\vpreferences\"`\n" +
+	"\x03Bot\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x16\n" +
+	"\x06scopes\x18\x03 \x03(\tR\x06scopes\x12\x15\n" +
+	"\x06key_id\x18\x04 \x01(\tR\x05keyId\"Z\n" +
+	"\x10CreateBotRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x16\n" +
+	"\x06scopes\x18\x03 \x03(\tR\x06scopes\"Q\n" +
+	"\x11CreateBotResponse\x12#\n" +
+	"\x03bot\x18\x01 \x01(\v2\x11.messaging.v1.BotR\x03bot\x12\x17\n" +
+	"\aapi_key\x18\x02 \x01(\tR\x06apiKey\"D\n" +
+	"\x13RotateBotKeyRequest\x12\x15\n" +
+	"\x06bot_id\x18\x01 \x01(\tR\x05botId\x12\x16\n" +
+	"\x06scopes\x18\x02 \x03(\tR\x06scopes\"T\n" +
+	"\x14RotateBotKeyResponse\x12#\n" +
+	"\x03bot\x18\x01 \x01(\v2\x11.messaging.v1.BotR\x03bot\x12\x17\n" +
+	"\aapi_key\x18\x02 \x01(\tR\x06apiKey\"\x11\n" +
+	"\x0fListBotsRequest\"9\n" +
+	"\x10ListBotsResponse\x12%\n" +
+	"\x04bots\x18\x01 \x03(\v2\x11.messaging.v1.BotR\x04bots\")\n" +
+	"\x10DeleteBotRequest\x12\x15\n" +
+	"\x06bot_id\x18\x01 \x01(\tR\x05botId\"\x13\n" +
+	"\x11DeleteBotResponse\"B\n" +
+	"\n" +
+	"BotCommand\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\"d\n" +
+	"\x15SetBotCommandsRequest\x12\x15\n" +
+	"\x06bot_id\x18\x01 \x01(\tR\x05botId\x124\n" +
+	"\bcommands\x18\x02 \x03(\v2\x18.messaging.v1.BotCommandR\bcommands\"\x18\n" +
+	"\x16SetBotCommandsResponse\",\n" +
+	"\x13ListCommandsRequest\x12\x15\n" +
+	"\x06user_b\x18\x01 \x01(\tR\x05userB\"L\n" +
+	"\x14ListCommandsResponse\x124\n" +
+	"\bcommands\x18\x01 \x03(\v2\x18.messaging.v1.BotCommandR\bcommands\"~\n" +
+	"\x11CommandInvocation\x12\x10\n" +
+	"\x03bot\x18\x01 \x01(\tR\x03bot\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04args\x18\x03 \x01(\tR\x04args\x12/\n" +
+	"\amessage\x18\x04 \x01(\v2\x15.messaging.v1.MessageR\amessage2\x99\x19\n" +
+	"\x10MessagingService\x12}\n" +
+	"\x11SendDirectMessage\x12&.messaging.v1.SendDirectMessageRequest\x1a'.messaging.v1.SendDirectMessageResponse\"\x17\x82\xd3\xe4\x93\x02\x11:\x01*\"\f/v1/messages\x12G\n" +
+	"\x06GetDMs\x12\x1b.messaging.v1.GetDMsRequest\x1a\x1c.messaging.v1.GetDMsResponse\"\x000\x01\x12q\n" +
+	"\aListDMs\x12\x1b.messaging.v1.GetDMsRequest\x1a\x1c.messaging.v1.GetDMsResponse\"+\x82\xd3\xe4\x93\x02%\x12#/v1/conversations/{user_b}/messages\x12k\n" +
+	"\fRegisterUser\x12!.messaging.v1.RegisterUserRequest\x1a\".messaging.v1.RegisterUserResponse\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/users\x12Y\n" +
+	"\x05Login\x12\x1a.messaging.v1.LoginRequest\x1a\x1b.messaging.v1.LoginResponse\"\x17\x82\xd3\xe4\x93\x02\x11:\x01*\"\f/v1/sessions\x12t\n" +
+	"\vGetUserInfo\x12 .messaging.v1.GetUserInfoRequest\x1a!.messaging.v1.GetUserInfoResponse\" \x82\xd3\xe4\x93\x02\x1a\x12\x18/v1/users/{phone_number}\x12\x92\x01\n" +
+	"\x15CreateIncomingWebhook\x12*.messaging.v1.CreateIncomingWebhookRequest\x1a+.messaging.v1.CreateIncomingWebhookResponse\" \x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/v1/incoming-webhooks\x12\x8c\x01\n" +
+	"\x14ListIncomingWebhooks\x12).messaging.v1.ListIncomingWebhooksRequest\x1a*.messaging.v1.ListIncomingWebhooksResponse\"\x1d\x82\xd3\xe4\x93\x02\x17\x12\x15/v1/incoming-webhooks\x12\x94\x01\n" +
+	"\x15DeleteIncomingWebhook\x12*.messaging.v1.DeleteIncomingWebhookRequest\x1a+.messaging.v1.DeleteIncomingWebhookResponse\"\"\x82\xd3\xe4\x93\x02\x1c*\x1a/v1/incoming-webhooks/{id}\x12w\n" +
+	"\x10GetPushPublicKey\x12%.messaging.v1.GetPushPublicKeyRequest\x1a&.messaging.v1.GetPushPublicKeyResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\f/v1/push/key\x12\x9c\x01\n" +
+	"\x18RegisterPushSubscription\x12-.messaging.v1.RegisterPushSubscriptionRequest\x1a..messaging.v1.RegisterPushSubscriptionResponse\"!\x82\xd3\xe4\x93\x02\x1b:\x01*\"\x16/v1/push/subscriptions\x12\x9f\x01\n" +
+	"\x1aUnregisterPushSubscription\x12/.messaging.v1.UnregisterPushSubscriptionRequest\x1a0.messaging.v1.UnregisterPushSubscriptionResponse\"\x1e\x82\xd3\xe4\x93\x02\x18*\x16/v1/push/subscriptions\x12_\n" +
+	"\bSetEmail\x12\x1d.messaging.v1.SetEmailRequest\x1a\x1e.messaging.v1.SetEmailResponse\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\x1a\t/v1/email\x12o\n" +
+	"\vVerifyEmail\x12 .messaging.v1.VerifyEmailRequest\x1a!.messaging.v1.VerifyEmailResponse\"\x1b\x82\xd3\xe4\x93\x02\x15:\x01*\"\x10/v1/email/verify\x12\x9a\x01\n" +
+	"\x1aGetNotificationPreferences\x12/.messaging.v1.GetNotificationPreferencesRequest\x1a0.messaging.v1.GetNotificationPreferencesResponse\"\x19\x82\xd3\xe4\x93\x02\x13\x12\x11/v1/notifications\x12\x9d\x01\n" +
+	"\x1aSetNotificationPreferences\x12/.messaging.v1.SetNotificationPreferencesRequest\x1a0.messaging.v1.SetNotificationPreferencesResponse\"\x1c\x82\xd3\xe4\x93\x02\x16:\x01*2\x11/v1/notifications\x12a\n" +
+	"\tCreateBot\x12\x1e.messaging.v1.CreateBotRequest\x1a\x1f.messaging.v1.CreateBotResponse\"\x13\x82\xd3\xe4\x93\x02\r:\x01*\"\b/v1/bots\x12w\n" +
+	"\fRotateBotKey\x12!.messaging.v1.RotateBotKeyRequest\x1a\".messaging.v1.RotateBotKeyResponse\" \x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/v1/bots/{bot_id}/key\x12[\n" +
+	"\bListBots\x12\x1d.messaging.v1.ListBotsRequest\x1a\x1e.messaging.v1.ListBotsResponse\"\x10\x82\xd3\xe4\x93\x02\n" +
+	"\x12\b/v1/bots\x12g\n" +
+	"\tDeleteBot\x12\x1e.messaging.v1.DeleteBotRequest\x1a\x1f.messaging.v1.DeleteBotResponse\"\x19\x82\xd3\xe4\x93\x02\x13*\x11/v1/bots/{bot_id}\x12\x82\x01\n" +
+	"\x0eSetBotCommands\x12#.messaging.v1.SetBotCommandsRequest\x1a$.messaging.v1.SetBotCommandsResponse\"%\x82\xd3\xe4\x93\x02\x1f:\x01*\x1a\x1a/v1/bots/{bot_id}/commands\x12k\n" +
+	"\fListCommands\x12!.messaging.v1.ListCommandsRequest\x1a\".messaging.v1.ListCommandsResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\f/v1/commands\x12q\n" +
+	"\rCreateWebhook\x12\".messaging.v1.CreateWebhookRequest\x1a#.messaging.v1.CreateWebhookResponse\"\x17\x82\xd3\xe4\x93\x02\x11:\x01*\"\f/v1/webhooks\x12k\n" +
+	"\fListWebhooks\x12!.messaging.v1.ListWebhooksRequest\x1a\".messaging.v1.ListWebhooksResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\f/v1/webhooks\x12s\n" +
+	"\rDeleteWebhook\x12\".messaging.v1.DeleteWebhookRequest\x1a#.messaging.v1.DeleteWebhookResponse\"\x19\x82\xd3\xe4\x93\x02\x13*\x11/v1/webhooks/{id}\x12\x9e\x01\n" +
+	"\x15ListWebhookDeliveries\x12*.messaging.v1.ListWebhookDeliveriesRequest\x1a+.messaging.v1.ListWebhookDeliveriesResponse\",\x82\xd3\xe4\x93\x02&\x12$/v1/webhooks/{webhook_id}/deliveriesB<Z:github.com/vl0000/gomessenger/gen/messaging/v1;messagingv1b\x06proto3"
+
+var (
+	file_messaging_v1_messaging_proto_rawDescOnce sync.Once
+	file_messaging_v1_messaging_proto_rawDescData []byte
+)
+
+func file_messaging_v1_messaging_proto_rawDescGZIP() []byte {
+	file_messaging_v1_messaging_proto_rawDescOnce.Do(func() {
+		file_messaging_v1_messaging_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_messaging_v1_messaging_proto_rawDesc), len(file_messaging_v1_messaging_proto_rawDesc)))
+	})
+	return file_messaging_v1_messaging_proto_rawDescData
+}
+
+var file_messaging_v1_messaging_proto_msgTypes = make([]protoimpl.MessageInfo, 62)
+var file_messaging_v1_messaging_proto_goTypes = []any{
+	(*Message)(nil),                            // 0: messaging.v1.Message
+	(*RegisterUserRequest)(nil),                // 1: messaging.v1.RegisterUserRequest
+	(*RegisterUserResponse)(nil),               // 2: messaging.v1.RegisterUserResponse
+	(*LoginRequest)(nil),                       // 3: messaging.v1.LoginRequest
+	(*LoginResponse)(nil),                      // 4: messaging.v1.LoginResponse
+	(*SendDirectMessageRequest)(nil),           // 5: messaging.v1.SendDirectMessageRequest
+	(*GetDMsRequest)(nil),                      // 6: messaging.v1.GetDMsRequest
+	(*GetDMsResponse)(nil),                     // 7: messaging.v1.GetDMsResponse
+	(*SendDirectMessageResponse)(nil),          // 8: messaging.v1.SendDirectMessageResponse
+	(*GetUserInfoRequest)(nil),                 // 9: messaging.v1.GetUserInfoRequest
+	(*GetUserInfoResponse)(nil),                // 10: messaging.v1.GetUserInfoResponse
+	(*ExportedUser)(nil),                       // 11: messaging.v1.ExportedUser
+	(*ExportedAPIKey)(nil),                     // 12: messaging.v1.ExportedAPIKey
+	(*ExportRecord)(nil),                       // 13: messaging.v1.ExportRecord
+	(*Webhook)(nil),                            // 14: messaging.v1.Webhook
+	(*CreateWebhookRequest)(nil),               // 15: messaging.v1.CreateWebhookRequest
+	(*CreateWebhookResponse)(nil),              // 16: messaging.v1.CreateWebhookResponse
+	(*ListWebhooksRequest)(nil),                // 17: messaging.v1.ListWebhooksRequest
+	(*ListWebhooksResponse)(nil),               // 18: messaging.v1.ListWebhooksResponse
+	(*DeleteWebhookRequest)(nil),               // 19: messaging.v1.DeleteWebhookRequest
+	(*DeleteWebhookResponse)(nil),              // 20: messaging.v1.DeleteWebhookResponse
+	(*WebhookDelivery)(nil),                    // 21: messaging.v1.WebhookDelivery
+	(*ListWebhookDeliveriesRequest)(nil),       // 22: messaging.v1.ListWebhookDeliveriesRequest
+	(*ListWebhookDeliveriesResponse)(nil),      // 23: messaging.v1.ListWebhookDeliveriesResponse
+	(*IncomingWebhook)(nil),                    // 24: messaging.v1.IncomingWebhook
+	(*CreateIncomingWebhookRequest)(nil),       // 25: messaging.v1.CreateIncomingWebhookRequest
+	(*CreateIncomingWebhookResponse)(nil),      // 26: messaging.v1.CreateIncomingWebhookResponse
+	(*ListIncomingWebhooksRequest)(nil),        // 27: messaging.v1.ListIncomingWebhooksRequest
+	(*ListIncomingWebhooksResponse)(nil),       // 28: messaging.v1.ListIncomingWebhooksResponse
+	(*DeleteIncomingWebhookRequest)(nil),       // 29: messaging.v1.DeleteIncomingWebhookRequest
+	(*DeleteIncomingWebhookResponse)(nil),      // 30: messaging.v1.DeleteIncomingWebhookResponse
+	(*PushSubscription)(nil),                   // 31: messaging.v1.PushSubscription
+	(*GetPushPublicKeyRequest)(nil),            // 32: messaging.v1.GetPushPublicKeyRequest
+	(*GetPushPublicKeyResponse)(nil),           // 33: messaging.v1.GetPushPublicKeyResponse
+	(*RegisterPushSubscriptionRequest)(nil),    // 34: messaging.v1.RegisterPushSubscriptionRequest
+	(*RegisterPushSubscriptionResponse)(nil),   // 35: messaging.v1.RegisterPushSubscriptionResponse
+	(*UnregisterPushSubscriptionRequest)(nil),  // 36: messaging.v1.UnregisterPushSubscriptionRequest
+	(*UnregisterPushSubscriptionResponse)(nil), // 37: messaging.v1.UnregisterPushSubscriptionResponse
+	(*NotificationPreferences)(nil),            // 38: messaging.v1.NotificationPreferences
+	(*SetEmailRequest)(nil),                    // 39: messaging.v1.SetEmailRequest
+	(*SetEmailResponse)(nil),                   // 40: messaging.v1.SetEmailResponse
+	(*VerifyEmailRequest)(nil),                 // 41: messaging.v1.VerifyEmailRequest
+	(*VerifyEmailResponse)(nil),                // 42: messaging.v1.VerifyEmailResponse
+	(*GetNotificationPreferencesRequest)(nil),  // 43: messaging.v1.GetNotificationPreferencesRequest
+	(*GetNotificationPreferencesResponse)(nil), // 44: messaging.v1.GetNotificationPreferencesResponse
+	(*SetNotificationPreferencesRequest)(nil),  // 45: messaging.v1.SetNotificationPreferencesRequest
+	(*SetNotificationPreferencesResponse)(nil), // 46: messaging.v1.SetNotificationPreferencesResponse
+	(*Bot)(nil),                    // 47: messaging.v1.Bot
+	(*CreateBotRequest)(nil),       // 48: messaging.v1.CreateBotRequest
+	(*CreateBotResponse)(nil),      // 49: messaging.v1.CreateBotResponse
+	(*RotateBotKeyRequest)(nil),    // 50: messaging.v1.RotateBotKeyRequest
+	(*RotateBotKeyResponse)(nil),   // 51: messaging.v1.RotateBotKeyResponse
+	(*ListBotsRequest)(nil),        // 52: messaging.v1.ListBotsRequest
+	(*ListBotsResponse)(nil),       // 53: messaging.v1.ListBotsResponse
+	(*DeleteBotRequest)(nil),       // 54: messaging.v1.DeleteBotRequest
+	(*DeleteBotResponse)(nil),      // 55: messaging.v1.DeleteBotResponse
+	(*BotCommand)(nil),             // 56: messaging.v1.BotCommand
+	(*SetBotCommandsRequest)(nil),  // 57: messaging.v1.SetBotCommandsRequest
+	(*SetBotCommandsResponse)(nil), // 58: messaging.v1.SetBotCommandsResponse
+	(*ListCommandsRequest)(nil),    // 59: messaging.v1.ListCommandsRequest
+	(*ListCommandsResponse)(nil),   // 60: messaging.v1.ListCommandsResponse
+	(*CommandInvocation)(nil),      // 61: messaging.v1.CommandInvocation
+}
+var file_messaging_v1_messaging_proto_depIdxs = []int32{
+	0,  // 0: messaging.v1.SendDirectMessageRequest.message:type_name -> messaging.v1.Message
+	0,  // 1: messaging.v1.GetDMsResponse.messages:type_name -> messaging.v1.Message
+	0,  // 2: messaging.v1.SendDirectMessageResponse.message:type_name -> messaging.v1.Message
+	12, // 3: messaging.v1.ExportedUser.api_keys:type_name -> messaging.v1.ExportedAPIKey
+	56, // 4: messaging.v1.ExportedUser.bot_commands:type_name -> messaging.v1.BotCommand
+	11, // 5: messaging.v1.ExportRecord.user:type_name -> messaging.v1.ExportedUser
+	0,  // 6: messaging.v1.ExportRecord.message:type_name -> messaging.v1.Message
+	14, // 7: messaging.v1.CreateWebhookResponse.webhook:type_name -> messaging.v1.Webhook
+	14, // 8: messaging.v1.ListWebhooksResponse.webhooks:type_name -> messaging.v1.Webhook
+	21, // 9: messaging.v1.ListWebhookDeliveriesResponse.deliveries:type_name -> messaging.v1.WebhookDelivery
+	24, // 10: messaging.v1.CreateIncomingWebhookResponse.incoming_webhook:type_name -> messaging.v1.IncomingWebhook
+	24, // 11: messaging.v1.ListIncomingWebhooksResponse.incoming_webhooks:type_name -> messaging.v1.IncomingWebhook
+	31, // 12: messaging.v1.RegisterPushSubscriptionRequest.subscription:type_name -> messaging.v1.PushSubscription
+	38, // 13: messaging.v1.GetNotificationPreferencesResponse.preferences:type_name -> messaging.v1.NotificationPreferences
+	38, // 14: messaging.v1.SetNotificationPreferencesResponse.preferences:type_name -> messaging.v1.NotificationPreferences
+	47, // 15: messaging.v1.CreateBotResponse.bot:type_name -> messaging.v1.Bot
+	47, // 16: messaging.v1.RotateBotKeyResponse.bot:type_name -> messaging.v1.Bot
+	47, // 17: messaging.v1.ListBotsResponse.bots:type_name -> messaging.v1.Bot
+	56, // 18: messaging.v1.SetBotCommandsRequest.commands:type_name -> messaging.v1.BotCommand
+	56, // 19: messaging.v1.ListCommandsResponse.commands:type_name -> messaging.v1.BotCommand
+	0,  // 20: messaging.v1.CommandInvocation.message:type_name -> messaging.v1.Message
+	5,  // 21: messaging.v1.MessagingService.SendDirectMessage:input_type -> messaging.v1.SendDirectMessageRequest
+	6,  // 22: messaging.v1.MessagingService.GetDMs:input_type -> messaging.v1.GetDMsRequest
+	6,  // 23: messaging.v1.MessagingService.ListDMs:input_type -> messaging.v1.GetDMsRequest
+	1,  // 24: messaging.v1.MessagingService.RegisterUser:input_type -> messaging.v1.RegisterUserRequest
+	3,  // 25: messaging.v1.MessagingService.Login:input_type -> messaging.v1.LoginRequest
+	9,  // 26: messaging.v1.MessagingService.GetUserInfo:input_type -> messaging.v1.GetUserInfoRequest
+	25, // 27: messaging.v1.MessagingService.CreateIncomingWebhook:input_type -> messaging.v1.CreateIncomingWebhookRequest
+	27, // 28: messaging.v1.MessagingService.ListIncomingWebhooks:input_type -> messaging.v1.ListIncomingWebhooksRequest
+	29, // 29: messaging.v1.MessagingService.DeleteIncomingWebhook:input_type -> messaging.v1.DeleteIncomingWebhookRequest
+	32, // 30: messaging.v1.MessagingService.GetPushPublicKey:input_type -> messaging.v1.GetPushPublicKeyRequest
+	34, // 31: messaging.v1.MessagingService.RegisterPushSubscription:input_type -> messaging.v1.RegisterPushSubscriptionRequest
+	36, // 32: messaging.v1.MessagingService.UnregisterPushSubscription:input_type -> messaging.v1.UnregisterPushSubscriptionRequest
+	39, // 33: messaging.v1.MessagingService.SetEmail:input_type -> messaging.v1.SetEmailRequest
+	41, // 34: messaging.v1.MessagingService.VerifyEmail:input_type -> messaging.v1.VerifyEmailRequest
+	43, // 35: messaging.v1.MessagingService.GetNotificationPreferences:input_type -> messaging.v1.GetNotificationPreferencesRequest
+	45, // 36: messaging.v1.MessagingService.SetNotificationPreferences:input_type -> messaging.v1.SetNotificationPreferencesRequest
+	48, // 37: messaging.v1.MessagingService.CreateBot:input_type -> messaging.v1.CreateBotRequest
+	50, // 38: messaging.v1.MessagingService.RotateBotKey:input_type -> messaging.v1.RotateBotKeyRequest
+	52, // 39: messaging.v1.MessagingService.ListBots:input_type -> messaging.v1.ListBotsRequest
+	54, // 40: messaging.v1.MessagingService.DeleteBot:input_type -> messaging.v1.DeleteBotRequest
+	57, // 41: messaging.v1.MessagingService.SetBotCommands:input_type -> messaging.v1.SetBotCommandsRequest
+	59, // 42: messaging.v1.MessagingService.ListCommands:input_type -> messaging.v1.ListCommandsRequest
+	15, // 43: messaging.v1.MessagingService.CreateWebhook:input_type -> messaging.v1.CreateWebhookRequest
+	17, // 44: messaging.v1.MessagingService.ListWebhooks:input_type -> messaging.v1.ListWebhooksRequest
+	19, // 45: messaging.v1.MessagingService.DeleteWebhook:input_type -> messaging.v1.DeleteWebhookRequest
+	22, // 46: messaging.v1.MessagingService.ListWebhookDeliveries:input_type -> messaging.v1.ListWebhookDeliveriesRequest
+	8,  // 47: messaging.v1.MessagingService.SendDirectMessage:output_type -> messaging.v1.SendDirectMessageResponse
+	7,  // 48: messaging.v1.MessagingService.GetDMs:output_type -> messaging.v1.GetDMsResponse
+	7,  // 49: messaging.v1.MessagingService.ListDMs:output_type -> messaging.v1.GetDMsResponse
+	2,  // 50: messaging.v1.MessagingService.RegisterUser:output_type -> messaging.v1.RegisterUserResponse
+	4,  // 51: messaging.v1.MessagingService.Login:output_type -> messaging.v1.LoginResponse
+	10, // 52: messaging.v1.MessagingService.GetUserInfo:output_type -> messaging.v1.GetUserInfoResponse
+	26, // 53: messaging.v1.MessagingService.CreateIncomingWebhook:output_type -> messaging.v1.CreateIncomingWebhookResponse
+	28, // 54: messaging.v1.MessagingService.ListIncomingWebhooks:output_type -> messaging.v1.ListIncomingWebhooksResponse
+	30, // 55: messaging.v1.MessagingService.DeleteIncomingWebhook:output_type -> messaging.v1.DeleteIncomingWebhookResponse
+	33, // 56: messaging.v1.MessagingService.GetPushPublicKey:output_type -> messaging.v1.GetPushPublicKeyResponse
+	35, // 57: messaging.v1.MessagingService.RegisterPushSubscription:output_type -> messaging.v1.RegisterPushSubscriptionResponse
+	37, // 58: messaging.v1.MessagingService.UnregisterPushSubscription:output_type -> messaging.v1.UnregisterPushSubscriptionResponse
+	40, // 59: messaging.v1.MessagingService.SetEmail:output_type -> messaging.v1.SetEmailResponse
+	42, // 60: messaging.v1.MessagingService.VerifyEmail:output_type -> messaging.v1.VerifyEmailResponse
+	44, // 61: messaging.v1.MessagingService.GetNotificationPreferences:output_type -> messaging.v1.GetNotificationPreferencesResponse
+	46, // 62: messaging.v1.MessagingService.SetNotificationPreferences:output_type -> messaging.v1.SetNotificationPreferencesResponse
+	49, // 63: messaging.v1.MessagingService.CreateBot:output_type -> messaging.v1.CreateBotResponse
+	51, // 64: messaging.v1.MessagingService.RotateBotKey:output_type -> messaging.v1.RotateBotKeyResponse
+	53, // 65: messaging.v1.MessagingService.ListBots:output_type -> messaging.v1.ListBotsResponse
+	55, // 66: messaging.v1.MessagingService.DeleteBot:output_type -> messaging.v1.DeleteBotResponse
+	58, // 67: messaging.v1.MessagingService.SetBotCommands:output_type -> messaging.v1.SetBotCommandsResponse
+	60, // 68: messaging.v1.MessagingService.ListCommands:output_type -> messaging.v1.ListCommandsResponse
+	16, // 69: messaging.v1.MessagingService.CreateWebhook:output_type -> messaging.v1.CreateWebhookResponse
+	18, // 70: messaging.v1.MessagingService.ListWebhooks:output_type -> messaging.v1.ListWebhooksResponse
+	20, // 71: messaging.v1.MessagingService.DeleteWebhook:output_type -> messaging.v1.DeleteWebhookResponse
+	23, // 72: messaging.v1.MessagingService.ListWebhookDeliveries:output_type -> messaging.v1.ListWebhookDeliveriesResponse
+	47, // [47:73] is the sub-list for method output_type
+	21, // [21:47] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
+}
+
+func init() { file_messaging_v1_messaging_proto_init() }
+func file_messaging_v1_messaging_proto_init() {
+	if File_messaging_v1_messaging_proto != nil {
+		return
+	}
+	file_messaging_v1_messaging_proto_msgTypes[0].OneofWrappers = []any{}
+	file_messaging_v1_messaging_proto_msgTypes[5].OneofWrappers = []any{}
+	file_messaging_v1_messaging_proto_msgTypes[11].OneofWrappers = []any{}
+	file_messaging_v1_messaging_proto_msgTypes[12].OneofWrappers = []any{}
+	file_messaging_v1_messaging_proto_msgTypes[13].OneofWrappers = []any{
+		(*ExportRecord_User)(nil),
+		(*ExportRecord_Message)(nil),
+	}
+	file_messaging_v1_messaging_proto_msgTypes[45].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_messaging_v1_messaging_proto_rawDesc), len(file_messaging_v1_messaging_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   11,
+			NumMessages:   62,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -38,6 +38,9 @@ const (
 	MessagingServiceSendDirectMessageProcedure = "/messaging.v1.MessagingService/SendDirectMessage"
 	// MessagingServiceGetDMsProcedure is the fully-qualified name of the MessagingService's GetDMs RPC.
 	MessagingServiceGetDMsProcedure = "/messaging.v1.MessagingService/GetDMs"
+	// MessagingServiceListDMsProcedure is the fully-qualified name of the MessagingService's ListDMs
+	// RPC.
+	MessagingServiceListDMsProcedure = "/messaging.v1.MessagingService/ListDMs"
 	// MessagingServiceRegisterUserProcedure is the fully-qualified name of the MessagingService's
 	// RegisterUser RPC.
 	MessagingServiceRegisterUserProcedure = "/messaging.v1.MessagingService/RegisterUser"
@@ -46,15 +49,99 @@ const (
 	// MessagingServiceGetUserInfoProcedure is the fully-qualified name of the MessagingService's
 	// GetUserInfo RPC.
 	MessagingServiceGetUserInfoProcedure = "/messaging.v1.MessagingService/GetUserInfo"
+	// MessagingServiceCreateIncomingWebhookProcedure is the fully-qualified name of the
+	// MessagingService's CreateIncomingWebhook RPC.
+	MessagingServiceCreateIncomingWebhookProcedure = "/messaging.v1.MessagingService/CreateIncomingWebhook"
+	// MessagingServiceListIncomingWebhooksProcedure is the fully-qualified name of the
+	// MessagingService's ListIncomingWebhooks RPC.
+	MessagingServiceListIncomingWebhooksProcedure = "/messaging.v1.MessagingService/ListIncomingWebhooks"
+	// MessagingServiceDeleteIncomingWebhookProcedure is the fully-qualified name of the
+	// MessagingService's DeleteIncomingWebhook RPC.
+	MessagingServiceDeleteIncomingWebhookProcedure = "/messaging.v1.MessagingService/DeleteIncomingWebhook"
+	// MessagingServiceGetPushPublicKeyProcedure is the fully-qualified name of the MessagingService's
+	// GetPushPublicKey RPC.
+	MessagingServiceGetPushPublicKeyProcedure = "/messaging.v1.MessagingService/GetPushPublicKey"
+	// MessagingServiceRegisterPushSubscriptionProcedure is the fully-qualified name of the
+	// MessagingService's RegisterPushSubscription RPC.
+	MessagingServiceRegisterPushSubscriptionProcedure = "/messaging.v1.MessagingService/RegisterPushSubscription"
+	// MessagingServiceUnregisterPushSubscriptionProcedure is the fully-qualified name of the
+	// MessagingService's UnregisterPushSubscription RPC.
+	MessagingServiceUnregisterPushSubscriptionProcedure = "/messaging.v1.MessagingService/UnregisterPushSubscription"
+	// MessagingServiceSetEmailProcedure is the fully-qualified name of the MessagingService's SetEmail
+	// RPC.
+	MessagingServiceSetEmailProcedure = "/messaging.v1.MessagingService/SetEmail"
+	// MessagingServiceVerifyEmailProcedure is the fully-qualified name of the MessagingService's
+	// VerifyEmail RPC.
+	MessagingServiceVerifyEmailProcedure = "/messaging.v1.MessagingService/VerifyEmail"
+	// MessagingServiceGetNotificationPreferencesProcedure is the fully-qualified name of the
+	// MessagingService's GetNotificationPreferences RPC.
+	MessagingServiceGetNotificationPreferencesProcedure = "/messaging.v1.MessagingService/GetNotificationPreferences"
+	// MessagingServiceSetNotificationPreferencesProcedure is the fully-qualified name of the
+	// MessagingService's SetNotificationPreferences RPC.
+	MessagingServiceSetNotificationPreferencesProcedure = "/messaging.v1.MessagingService/SetNotificationPreferences"
+	// MessagingServiceCreateBotProcedure is the fully-qualified name of the MessagingService's
+	// CreateBot RPC.
+	MessagingServiceCreateBotProcedure = "/messaging.v1.MessagingService/CreateBot"
+	// MessagingServiceRotateBotKeyProcedure is the fully-qualified name of the MessagingService's
+	// RotateBotKey RPC.
+	MessagingServiceRotateBotKeyProcedure = "/messaging.v1.MessagingService/RotateBotKey"
+	// MessagingServiceListBotsProcedure is the fully-qualified name of the MessagingService's ListBots
+	// RPC.
+	MessagingServiceListBotsProcedure = "/messaging.v1.MessagingService/ListBots"
+	// MessagingServiceDeleteBotProcedure is the fully-qualified name of the MessagingService's
+	// DeleteBot RPC.
+	MessagingServiceDeleteBotProcedure = "/messaging.v1.MessagingService/DeleteBot"
+	// MessagingServiceSetBotCommandsProcedure is the fully-qualified name of the MessagingService's
+	// SetBotCommands RPC.
+	MessagingServiceSetBotCommandsProcedure = "/messaging.v1.MessagingService/SetBotCommands"
+	// MessagingServiceListCommandsProcedure is the fully-qualified name of the MessagingService's
+	// ListCommands RPC.
+	MessagingServiceListCommandsProcedure = "/messaging.v1.MessagingService/ListCommands"
+	// MessagingServiceCreateWebhookProcedure is the fully-qualified name of the MessagingService's
+	// CreateWebhook RPC.
+	MessagingServiceCreateWebhookProcedure = "/messaging.v1.MessagingService/CreateWebhook"
+	// MessagingServiceListWebhooksProcedure is the fully-qualified name of the MessagingService's
+	// ListWebhooks RPC.
+	MessagingServiceListWebhooksProcedure = "/messaging.v1.MessagingService/ListWebhooks"
+	// MessagingServiceDeleteWebhookProcedure is the fully-qualified name of the MessagingService's
+	// DeleteWebhook RPC.
+	MessagingServiceDeleteWebhookProcedure = "/messaging.v1.MessagingService/DeleteWebhook"
+	// MessagingServiceListWebhookDeliveriesProcedure is the fully-qualified name of the
+	// MessagingService's ListWebhookDeliveries RPC.
+	MessagingServiceListWebhookDeliveriesProcedure = "/messaging.v1.MessagingService/ListWebhookDeliveries"
 )
 
 // MessagingServiceClient is a client for the messaging.v1.MessagingService service.
 type MessagingServiceClient interface {
 	SendDirectMessage(context.Context, *connect.Request[v1.SendDirectMessageRequest]) (*connect.Response[v1.SendDirectMessageResponse], error)
+	// Sends the history since from_date, then every new message. Not available
+	// over REST, where ListDMs returns the history.
 	GetDMs(context.Context, *connect.Request[v1.GetDMsRequest]) (*connect.ServerStreamForClient[v1.GetDMsResponse], error)
+	// The history part of GetDMs
+	ListDMs(context.Context, *connect.Request[v1.GetDMsRequest]) (*connect.Response[v1.GetDMsResponse], error)
 	RegisterUser(context.Context, *connect.Request[v1.RegisterUserRequest]) (*connect.Response[v1.RegisterUserResponse], error)
 	Login(context.Context, *connect.Request[v1.LoginRequest]) (*connect.Response[v1.LoginResponse], error)
 	GetUserInfo(context.Context, *connect.Request[v1.GetUserInfoRequest]) (*connect.Response[v1.GetUserInfoResponse], error)
+	CreateIncomingWebhook(context.Context, *connect.Request[v1.CreateIncomingWebhookRequest]) (*connect.Response[v1.CreateIncomingWebhookResponse], error)
+	ListIncomingWebhooks(context.Context, *connect.Request[v1.ListIncomingWebhooksRequest]) (*connect.Response[v1.ListIncomingWebhooksResponse], error)
+	DeleteIncomingWebhook(context.Context, *connect.Request[v1.DeleteIncomingWebhookRequest]) (*connect.Response[v1.DeleteIncomingWebhookResponse], error)
+	GetPushPublicKey(context.Context, *connect.Request[v1.GetPushPublicKeyRequest]) (*connect.Response[v1.GetPushPublicKeyResponse], error)
+	RegisterPushSubscription(context.Context, *connect.Request[v1.RegisterPushSubscriptionRequest]) (*connect.Response[v1.RegisterPushSubscriptionResponse], error)
+	UnregisterPushSubscription(context.Context, *connect.Request[v1.UnregisterPushSubscriptionRequest]) (*connect.Response[v1.UnregisterPushSubscriptionResponse], error)
+	SetEmail(context.Context, *connect.Request[v1.SetEmailRequest]) (*connect.Response[v1.SetEmailResponse], error)
+	VerifyEmail(context.Context, *connect.Request[v1.VerifyEmailRequest]) (*connect.Response[v1.VerifyEmailResponse], error)
+	GetNotificationPreferences(context.Context, *connect.Request[v1.GetNotificationPreferencesRequest]) (*connect.Response[v1.GetNotificationPreferencesResponse], error)
+	SetNotificationPreferences(context.Context, *connect.Request[v1.SetNotificationPreferencesRequest]) (*connect.Response[v1.SetNotificationPreferencesResponse], error)
+	CreateBot(context.Context, *connect.Request[v1.CreateBotRequest]) (*connect.Response[v1.CreateBotResponse], error)
+	RotateBotKey(context.Context, *connect.Request[v1.RotateBotKeyRequest]) (*connect.Response[v1.RotateBotKeyResponse], error)
+	ListBots(context.Context, *connect.Request[v1.ListBotsRequest]) (*connect.Response[v1.ListBotsResponse], error)
+	DeleteBot(context.Context, *connect.Request[v1.DeleteBotRequest]) (*connect.Response[v1.DeleteBotResponse], error)
+	SetBotCommands(context.Context, *connect.Request[v1.SetBotCommandsRequest]) (*connect.Response[v1.SetBotCommandsResponse], error)
+	ListCommands(context.Context, *connect.Request[v1.ListCommandsRequest]) (*connect.Response[v1.ListCommandsResponse], error)
+	CreateWebhook(context.Context, *connect.Request[v1.CreateWebhookRequest]) (*connect.Response[v1.CreateWebhookResponse], error)
+	ListWebhooks(context.Context, *connect.Request[v1.ListWebhooksRequest]) (*connect.Response[v1.ListWebhooksResponse], error)
+	DeleteWebhook(context.Context, *connect.Request[v1.DeleteWebhookRequest]) (*connect.Response[v1.DeleteWebhookResponse], error)
+	ListWebhookDeliveries(context.Context, *connect.Request[v1.ListWebhookDeliveriesRequest]) (*connect.Response[v1.ListWebhookDeliveriesResponse], error)
 }
 
 // NewMessagingServiceClient constructs a client for the messaging.v1.MessagingService service. By
@@ -80,6 +167,12 @@ func NewMessagingServiceClient(httpClient connect.HTTPClient, baseURL string, op
 			connect.WithSchema(messagingServiceMethods.ByName("GetDMs")),
 			connect.WithClientOptions(opts...),
 		),
+		listDMs: connect.NewClient[v1.GetDMsRequest, v1.GetDMsResponse](
+			httpClient,
+			baseURL+MessagingServiceListDMsProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("ListDMs")),
+			connect.WithClientOptions(opts...),
+		),
 		registerUser: connect.NewClient[v1.RegisterUserRequest, v1.RegisterUserResponse](
 			httpClient,
 			baseURL+MessagingServiceRegisterUserProcedure,
@@ -98,16 +191,157 @@ func NewMessagingServiceClient(httpClient connect.HTTPClient, baseURL string, op
 			connect.WithSchema(messagingServiceMethods.ByName("GetUserInfo")),
 			connect.WithClientOptions(opts...),
 		),
+		createIncomingWebhook: connect.NewClient[v1.CreateIncomingWebhookRequest, v1.CreateIncomingWebhookResponse](
+			httpClient,
+			baseURL+MessagingServiceCreateIncomingWebhookProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("CreateIncomingWebhook")),
+			connect.WithClientOptions(opts...),
+		),
+		listIncomingWebhooks: connect.NewClient[v1.ListIncomingWebhooksRequest, v1.ListIncomingWebhooksResponse](
+			httpClient,
+			baseURL+MessagingServiceListIncomingWebhooksProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("ListIncomingWebhooks")),
+			connect.WithClientOptions(opts...),
+		),
+		deleteIncomingWebhook: connect.NewClient[v1.DeleteIncomingWebhookRequest, v1.DeleteIncomingWebhookResponse](
+			httpClient,
+			baseURL+MessagingServiceDeleteIncomingWebhookProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("DeleteIncomingWebhook")),
+			connect.WithClientOptions(opts...),
+		),
+		getPushPublicKey: connect.NewClient[v1.GetPushPublicKeyRequest, v1.GetPushPublicKeyResponse](
+			httpClient,
+			baseURL+MessagingServiceGetPushPublicKeyProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("GetPushPublicKey")),
+			connect.WithClientOptions(opts...),
+		),
+		registerPushSubscription: connect.NewClient[v1.RegisterPushSubscriptionRequest, v1.RegisterPushSubscriptionResponse](
+			httpClient,
+			baseURL+MessagingServiceRegisterPushSubscriptionProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("RegisterPushSubscription")),
+			connect.WithClientOptions(opts...),
+		),
+		unregisterPushSubscription: connect.NewClient[v1.UnregisterPushSubscriptionRequest, v1.UnregisterPushSubscriptionResponse](
+			httpClient,
+			baseURL+MessagingServiceUnregisterPushSubscriptionProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("UnregisterPushSubscription")),
+			connect.WithClientOptions(opts...),
+		),
+		setEmail: connect.NewClient[v1.SetEmailRequest, v1.SetEmailResponse](
+			httpClient,
+			baseURL+MessagingServiceSetEmailProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("SetEmail")),
+			connect.WithClientOptions(opts...),
+		),
+		verifyEmail: connect.NewClient[v1.VerifyEmailRequest, v1.VerifyEmailResponse](
+			httpClient,
+			baseURL+MessagingServiceVerifyEmailProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("VerifyEmail")),
+			connect.WithClientOptions(opts...),
+		),
+		getNotificationPreferences: connect.NewClient[v1.GetNotificationPreferencesRequest, v1.GetNotificationPreferencesResponse](
+			httpClient,
+			baseURL+MessagingServiceGetNotificationPreferencesProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("GetNotificationPreferences")),
+			connect.WithClientOptions(opts...),
+		),
+		setNotificationPreferences: connect.NewClient[v1.SetNotificationPreferencesRequest, v1.SetNotificationPreferencesResponse](
+			httpClient,
+			baseURL+MessagingServiceSetNotificationPreferencesProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("SetNotificationPreferences")),
+			connect.WithClientOptions(opts...),
+		),
+		createBot: connect.NewClient[v1.CreateBotRequest, v1.CreateBotResponse](
+			httpClient,
+			baseURL+MessagingServiceCreateBotProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("CreateBot")),
+			connect.WithClientOptions(opts...),
+		),
+		rotateBotKey: connect.NewClient[v1.RotateBotKeyRequest, v1.RotateBotKeyResponse](
+			httpClient,
+			baseURL+MessagingServiceRotateBotKeyProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("RotateBotKey")),
+			connect.WithClientOptions(opts...),
+		),
+		listBots: connect.NewClient[v1.ListBotsRequest, v1.ListBotsResponse](
+			httpClient,
+			baseURL+MessagingServiceListBotsProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("ListBots")),
+			connect.WithClientOptions(opts...),
+		),
+		deleteBot: connect.NewClient[v1.DeleteBotRequest, v1.DeleteBotResponse](
+			httpClient,
+			baseURL+MessagingServiceDeleteBotProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("DeleteBot")),
+			connect.WithClientOptions(opts...),
+		),
+		setBotCommands: connect.NewClient[v1.SetBotCommandsRequest, v1.SetBotCommandsResponse](
+			httpClient,
+			baseURL+MessagingServiceSetBotCommandsProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("SetBotCommands")),
+			connect.WithClientOptions(opts...),
+		),
+		listCommands: connect.NewClient[v1.ListCommandsRequest, v1.ListCommandsResponse](
+			httpClient,
+			baseURL+MessagingServiceListCommandsProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("ListCommands")),
+			connect.WithClientOptions(opts...),
+		),
+		createWebhook: connect.NewClient[v1.CreateWebhookRequest, v1.CreateWebhookResponse](
+			httpClient,
+			baseURL+MessagingServiceCreateWebhookProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("CreateWebhook")),
+			connect.WithClientOptions(opts...),
+		),
+		listWebhooks: connect.NewClient[v1.ListWebhooksRequest, v1.ListWebhooksResponse](
+			httpClient,
+			baseURL+MessagingServiceListWebhooksProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("ListWebhooks")),
+			connect.WithClientOptions(opts...),
+		),
+		deleteWebhook: connect.NewClient[v1.DeleteWebhookRequest, v1.DeleteWebhookResponse](
+			httpClient,
+			baseURL+MessagingServiceDeleteWebhookProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("DeleteWebhook")),
+			connect.WithClientOptions(opts...),
+		),
+		listWebhookDeliveries: connect.NewClient[v1.ListWebhookDeliveriesRequest, v1.ListWebhookDeliveriesResponse](
+			httpClient,
+			baseURL+MessagingServiceListWebhookDeliveriesProcedure,
+			connect.WithSchema(messagingServiceMethods.ByName("ListWebhookDeliveries")),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // messagingServiceClient implements MessagingServiceClient.
 type messagingServiceClient struct {
-	sendDirectMessage *connect.Client[v1.SendDirectMessageRequest, v1.SendDirectMessageResponse]
-	getDMs            *connect.Client[v1.GetDMsRequest, v1.GetDMsResponse]
-	registerUser      *connect.Client[v1.RegisterUserRequest, v1.RegisterUserResponse]
-	login             *connect.Client[v1.LoginRequest, v1.LoginResponse]
-	getUserInfo       *connect.Client[v1.GetUserInfoRequest, v1.GetUserInfoResponse]
+	sendDirectMessage          *connect.Client[v1.SendDirectMessageRequest, v1.SendDirectMessageResponse]
+	getDMs                     *connect.Client[v1.GetDMsRequest, v1.GetDMsResponse]
+	listDMs                    *connect.Client[v1.GetDMsRequest, v1.GetDMsResponse]
+	registerUser               *connect.Client[v1.RegisterUserRequest, v1.RegisterUserResponse]
+	login                      *connect.Client[v1.LoginRequest, v1.LoginResponse]
+	getUserInfo                *connect.Client[v1.GetUserInfoRequest, v1.GetUserInfoResponse]
+	createIncomingWebhook      *connect.Client[v1.CreateIncomingWebhookRequest, v1.CreateIncomingWebhookResponse]
+	listIncomingWebhooks       *connect.Client[v1.ListIncomingWebhooksRequest, v1.ListIncomingWebhooksResponse]
+	deleteIncomingWebhook      *connect.Client[v1.DeleteIncomingWebhookRequest, v1.DeleteIncomingWebhookResponse]
+	getPushPublicKey           *connect.Client[v1.GetPushPublicKeyRequest, v1.GetPushPublicKeyResponse]
+	registerPushSubscription   *connect.Client[v1.RegisterPushSubscriptionRequest, v1.RegisterPushSubscriptionResponse]
+	unregisterPushSubscription *connect.Client[v1.UnregisterPushSubscriptionRequest, v1.UnregisterPushSubscriptionResponse]
+	setEmail                   *connect.Client[v1.SetEmailRequest, v1.SetEmailResponse]
+	verifyEmail                *connect.Client[v1.VerifyEmailRequest, v1.VerifyEmailResponse]
+	getNotificationPreferences *connect.Client[v1.GetNotificationPreferencesRequest, v1.GetNotificationPreferencesResponse]
+	setNotificationPreferences *connect.Client[v1.SetNotificationPreferencesRequest, v1.SetNotificationPreferencesResponse]
+	createBot                  *connect.Client[v1.CreateBotRequest, v1.CreateBotResponse]
+	rotateBotKey               *connect.Client[v1.RotateBotKeyRequest, v1.RotateBotKeyResponse]
+	listBots                   *connect.Client[v1.ListBotsRequest, v1.ListBotsResponse]
+	deleteBot                  *connect.Client[v1.DeleteBotRequest, v1.DeleteBotResponse]
+	setBotCommands             *connect.Client[v1.SetBotCommandsRequest, v1.SetBotCommandsResponse]
+	listCommands               *connect.Client[v1.ListCommandsRequest, v1.ListCommandsResponse]
+	createWebhook              *connect.Client[v1.CreateWebhookRequest, v1.CreateWebhookResponse]
+	listWebhooks               *connect.Client[v1.ListWebhooksRequest, v1.ListWebhooksResponse]
+	deleteWebhook              *connect.Client[v1.DeleteWebhookRequest, v1.DeleteWebhookResponse]
+	listWebhookDeliveries      *connect.Client[v1.ListWebhookDeliveriesRequest, v1.ListWebhookDeliveriesResponse]
 }
 
 // SendDirectMessage calls messaging.v1.MessagingService.SendDirectMessage.
@@ -120,6 +354,11 @@ func (c *messagingServiceClient) GetDMs(ctx context.Context, req *connect.Reques
 	return c.getDMs.CallServerStream(ctx, req)
 }
 
+// ListDMs calls messaging.v1.MessagingService.ListDMs.
+func (c *messagingServiceClient) ListDMs(ctx context.Context, req *connect.Request[v1.GetDMsRequest]) (*connect.Response[v1.GetDMsResponse], error) {
+	return c.listDMs.CallUnary(ctx, req)
+}
+
 // RegisterUser calls messaging.v1.MessagingService.RegisterUser.
 func (c *messagingServiceClient) RegisterUser(ctx context.Context, req *connect.Request[v1.RegisterUserRequest]) (*connect.Response[v1.RegisterUserResponse], error) {
 	return c.registerUser.CallUnary(ctx, req)
@@ -135,13 +374,137 @@ func (c *messagingServiceClient) GetUserInfo(ctx context.Context, req *connect.R
 	return c.getUserInfo.CallUnary(ctx, req)
 }
 
+// CreateIncomingWebhook calls messaging.v1.MessagingService.CreateIncomingWebhook.
+func (c *messagingServiceClient) CreateIncomingWebhook(ctx context.Context, req *connect.Request[v1.CreateIncomingWebhookRequest]) (*connect.Response[v1.CreateIncomingWebhookResponse], error) {
+	return c.createIncomingWebhook.CallUnary(ctx, req)
+}
+
+// ListIncomingWebhooks calls messaging.v1.MessagingService.ListIncomingWebhooks.
+func (c *messagingServiceClient) ListIncomingWebhooks(ctx context.Context, req *connect.Request[v1.ListIncomingWebhooksRequest]) (*connect.Response[v1.ListIncomingWebhooksResponse], error) {
+	return c.listIncomingWebhooks.CallUnary(ctx, req)
+}
+
+// DeleteIncomingWebhook calls messaging.v1.MessagingService.DeleteIncomingWebhook.
+func (c *messagingServiceClient) DeleteIncomingWebhook(ctx context.Context, req *connect.Request[v1.DeleteIncomingWebhookRequest]) (*connect.Response[v1.DeleteIncomingWebhookResponse], error) {
+	return c.deleteIncomingWebhook.CallUnary(ctx, req)
+}
+
+// GetPushPublicKey calls messaging.v1.MessagingService.GetPushPublicKey.
+func (c *messagingServiceClient) GetPushPublicKey(ctx context.Context, req *connect.Request[v1.GetPushPublicKeyRequest]) (*connect.Response[v1.GetPushPublicKeyResponse], error) {
+	return c.getPushPublicKey.CallUnary(ctx, req)
+}
+
+// RegisterPushSubscription calls messaging.v1.MessagingService.RegisterPushSubscription.
+func (c *messagingServiceClient) RegisterPushSubscription(ctx context.Context, req *connect.Request[v1.RegisterPushSubscriptionRequest]) (*connect.Response[v1.RegisterPushSubscriptionResponse], error) {
+	return c.registerPushSubscription.CallUnary(ctx, req)
+}
+
+// UnregisterPushSubscription calls messaging.v1.MessagingService.UnregisterPushSubscription.
+func (c *messagingServiceClient) UnregisterPushSubscription(ctx context.Context, req *connect.Request[v1.UnregisterPushSubscriptionRequest]) (*connect.Response[v1.UnregisterPushSubscriptionResponse], error) {
+	return c.unregisterPushSubscription.CallUnary(ctx, req)
+}
+
+// SetEmail calls messaging.v1.MessagingService.SetEmail.
+func (c *messagingServiceClient) SetEmail(ctx context.Context, req *connect.Request[v1.SetEmailRequest]) (*connect.Response[v1.SetEmailResponse], error) {
+	return c.setEmail.CallUnary(ctx, req)
+}
+
+// VerifyEmail calls messaging.v1.MessagingService.VerifyEmail.
+func (c *messagingServiceClient) VerifyEmail(ctx context.Context, req *connect.Request[v1.VerifyEmailRequest]) (*connect.Response[v1.VerifyEmailResponse], error) {
+	return c.verifyEmail.CallUnary(ctx, req)
+}
+
+// GetNotificationPreferences calls messaging.v1.MessagingService.GetNotificationPreferences.
+func (c *messagingServiceClient) GetNotificationPreferences(ctx context.Context, req *connect.Request[v1.GetNotificationPreferencesRequest]) (*connect.Response[v1.GetNotificationPreferencesResponse], error) {
+	return c.getNotificationPreferences.CallUnary(ctx, req)
+}
+
+// SetNotificationPreferences calls messaging.v1.MessagingService.SetNotificationPreferences.
+func (c *messagingServiceClient) SetNotificationPreferences(ctx context.Context, req *connect.Request[v1.SetNotificationPreferencesRequest]) (*connect.Response[v1.SetNotificationPreferencesResponse], error) {
+	return c.setNotificationPreferences.CallUnary(ctx, req)
+}
+
+// CreateBot calls messaging.v1.MessagingService.CreateBot.
+func (c *messagingServiceClient) CreateBot(ctx context.Context, req *connect.Request[v1.CreateBotRequest]) (*connect.Response[v1.CreateBotResponse], error) {
+	return c.createBot.CallUnary(ctx, req)
+}
+
+// RotateBotKey calls messaging.v1.MessagingService.RotateBotKey.
+func (c *messagingServiceClient) RotateBotKey(ctx context.Context, req *connect.Request[v1.RotateBotKeyRequest]) (*connect.Response[v1.RotateBotKeyResponse], error) {
+	return c.rotateBotKey.CallUnary(ctx, req)
+}
+
+// ListBots calls messaging.v1.MessagingService.ListBots.
+func (c *messagingServiceClient) ListBots(ctx context.Context, req *connect.Request[v1.ListBotsRequest]) (*connect.Response[v1.ListBotsResponse], error) {
+	return c.listBots.CallUnary(ctx, req)
+}
+
+// DeleteBot calls messaging.v1.MessagingService.DeleteBot.
+func (c *messagingServiceClient) DeleteBot(ctx context.Context, req *connect.Request[v1.DeleteBotRequest]) (*connect.Response[v1.DeleteBotResponse], error) {
+	return c.deleteBot.CallUnary(ctx, req)
+}
+
+// SetBotCommands calls messaging.v1.MessagingService.SetBotCommands.
+func (c *messagingServiceClient) SetBotCommands(ctx context.Context, req *connect.Request[v1.SetBotCommandsRequest]) (*connect.Response[v1.SetBotCommandsResponse], error) {
+	return c.setBotCommands.CallUnary(ctx, req)
+}
+
+// ListCommands calls messaging.v1.MessagingService.ListCommands.
+func (c *messagingServiceClient) ListCommands(ctx context.Context, req *connect.Request[v1.ListCommandsRequest]) (*connect.Response[v1.ListCommandsResponse], error) {
+	return c.listCommands.CallUnary(ctx, req)
+}
+
+// CreateWebhook calls messaging.v1.MessagingService.CreateWebhook.
+func (c *messagingServiceClient) CreateWebhook(ctx context.Context, req *connect.Request[v1.CreateWebhookRequest]) (*connect.Response[v1.CreateWebhookResponse], error) {
+	return c.createWebhook.CallUnary(ctx, req)
+}
+
+// ListWebhooks calls messaging.v1.MessagingService.ListWebhooks.
+func (c *messagingServiceClient) ListWebhooks(ctx context.Context, req *connect.Request[v1.ListWebhooksRequest]) (*connect.Response[v1.ListWebhooksResponse], error) {
+	return c.listWebhooks.CallUnary(ctx, req)
+}
+
+// DeleteWebhook calls messaging.v1.MessagingService.DeleteWebhook.
+func (c *messagingServiceClient) DeleteWebhook(ctx context.Context, req *connect.Request[v1.DeleteWebhookRequest]) (*connect.Response[v1.DeleteWebhookResponse], error) {
+	return c.deleteWebhook.CallUnary(ctx, req)
+}
+
+// ListWebhookDeliveries calls messaging.v1.MessagingService.ListWebhookDeliveries.
+func (c *messagingServiceClient) ListWebhookDeliveries(ctx context.Context, req *connect.Request[v1.ListWebhookDeliveriesRequest]) (*connect.Response[v1.ListWebhookDeliveriesResponse], error) {
+	return c.listWebhookDeliveries.CallUnary(ctx, req)
+}
+
 // MessagingServiceHandler is an implementation of the messaging.v1.MessagingService service.
 type MessagingServiceHandler interface {
 	SendDirectMessage(context.Context, *connect.Request[v1.SendDirectMessageRequest]) (*connect.Response[v1.SendDirectMessageResponse], error)
+	// Sends the history since from_date, then every new message. Not available
+	// over REST, where ListDMs returns the history.
 	GetDMs(context.Context, *connect.Request[v1.GetDMsRequest], *connect.ServerStream[v1.GetDMsResponse]) error
+	// The history part of GetDMs
+	ListDMs(context.Context, *connect.Request[v1.GetDMsRequest]) (*connect.Response[v1.GetDMsResponse], error)
 	RegisterUser(context.Context, *connect.Request[v1.RegisterUserRequest]) (*connect.Response[v1.RegisterUserResponse], error)
 	Login(context.Context, *connect.Request[v1.LoginRequest]) (*connect.Response[v1.LoginResponse], error)
 	GetUserInfo(context.Context, *connect.Request[v1.GetUserInfoRequest]) (*connect.Response[v1.GetUserInfoResponse], error)
+	CreateIncomingWebhook(context.Context, *connect.Request[v1.CreateIncomingWebhookRequest]) (*connect.Response[v1.CreateIncomingWebhookResponse], error)
+	ListIncomingWebhooks(context.Context, *connect.Request[v1.ListIncomingWebhooksRequest]) (*connect.Response[v1.ListIncomingWebhooksResponse], error)
+	DeleteIncomingWebhook(context.Context, *connect.Request[v1.DeleteIncomingWebhookRequest]) (*connect.Response[v1.DeleteIncomingWebhookResponse], error)
+	GetPushPublicKey(context.Context, *connect.Request[v1.GetPushPublicKeyRequest]) (*connect.Response[v1.GetPushPublicKeyResponse], error)
+	RegisterPushSubscription(context.Context, *connect.Request[v1.RegisterPushSubscriptionRequest]) (*connect.Response[v1.RegisterPushSubscriptionResponse], error)
+	UnregisterPushSubscription(context.Context, *connect.Request[v1.UnregisterPushSubscriptionRequest]) (*connect.Response[v1.UnregisterPushSubscriptionResponse], error)
+	SetEmail(context.Context, *connect.Request[v1.SetEmailRequest]) (*connect.Response[v1.SetEmailResponse], error)
+	VerifyEmail(context.Context, *connect.Request[v1.VerifyEmailRequest]) (*connect.Response[v1.VerifyEmailResponse], error)
+	GetNotificationPreferences(context.Context, *connect.Request[v1.GetNotificationPreferencesRequest]) (*connect.Response[v1.GetNotificationPreferencesResponse], error)
+	SetNotificationPreferences(context.Context, *connect.Request[v1.SetNotificationPreferencesRequest]) (*connect.Response[v1.SetNotificationPreferencesResponse], error)
+	CreateBot(context.Context, *connect.Request[v1.CreateBotRequest]) (*connect.Response[v1.CreateBotResponse], error)
+	RotateBotKey(context.Context, *connect.Request[v1.RotateBotKeyRequest]) (*connect.Response[v1.RotateBotKeyResponse], error)
+	ListBots(context.Context, *connect.Request[v1.ListBotsRequest]) (*connect.Response[v1.ListBotsResponse], error)
+	DeleteBot(context.Context, *connect.Request[v1.DeleteBotRequest]) (*connect.Response[v1.DeleteBotResponse], error)
+	SetBotCommands(context.Context, *connect.Request[v1.SetBotCommandsRequest]) (*connect.Response[v1.SetBotCommandsResponse], error)
+	ListCommands(context.Context, *connect.Request[v1.ListCommandsRequest]) (*connect.Response[v1.ListCommandsResponse], error)
+	CreateWebhook(context.Context, *connect.Request[v1.CreateWebhookRequest]) (*connect.Response[v1.CreateWebhookResponse], error)
+	ListWebhooks(context.Context, *connect.Request[v1.ListWebhooksRequest]) (*connect.Response[v1.ListWebhooksResponse], error)
+	DeleteWebhook(context.Context, *connect.Request[v1.DeleteWebhookRequest]) (*connect.Response[v1.DeleteWebhookResponse], error)
+	ListWebhookDeliveries(context.Context, *connect.Request[v1.ListWebhookDeliveriesRequest]) (*connect.Response[v1.ListWebhookDeliveriesResponse], error)
 }
 
 // NewMessagingServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -163,6 +526,12 @@ func NewMessagingServiceHandler(svc MessagingServiceHandler, opts ...connect.Han
 		connect.WithSchema(messagingServiceMethods.ByName("GetDMs")),
 		connect.WithHandlerOptions(opts...),
 	)
+	messagingServiceListDMsHandler := connect.NewUnaryHandler(
+		MessagingServiceListDMsProcedure,
+		svc.ListDMs,
+		connect.WithSchema(messagingServiceMethods.ByName("ListDMs")),
+		connect.WithHandlerOptions(opts...),
+	)
 	messagingServiceRegisterUserHandler := connect.NewUnaryHandler(
 		MessagingServiceRegisterUserProcedure,
 		svc.RegisterUser,
@@ -181,18 +550,180 @@ func NewMessagingServiceHandler(svc MessagingServiceHandler, opts ...connect.Han
 		connect.WithSchema(messagingServiceMethods.ByName("GetUserInfo")),
 		connect.WithHandlerOptions(opts...),
 	)
+	messagingServiceCreateIncomingWebhookHandler := connect.NewUnaryHandler(
+		MessagingServiceCreateIncomingWebhookProcedure,
+		svc.CreateIncomingWebhook,
+		connect.WithSchema(messagingServiceMethods.ByName("CreateIncomingWebhook")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceListIncomingWebhooksHandler := connect.NewUnaryHandler(
+		MessagingServiceListIncomingWebhooksProcedure,
+		svc.ListIncomingWebhooks,
+		connect.WithSchema(messagingServiceMethods.ByName("ListIncomingWebhooks")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceDeleteIncomingWebhookHandler := connect.NewUnaryHandler(
+		MessagingServiceDeleteIncomingWebhookProcedure,
+		svc.DeleteIncomingWebhook,
+		connect.WithSchema(messagingServiceMethods.ByName("DeleteIncomingWebhook")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceGetPushPublicKeyHandler := connect.NewUnaryHandler(
+		MessagingServiceGetPushPublicKeyProcedure,
+		svc.GetPushPublicKey,
+		connect.WithSchema(messagingServiceMethods.ByName("GetPushPublicKey")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceRegisterPushSubscriptionHandler := connect.NewUnaryHandler(
+		MessagingServiceRegisterPushSubscriptionProcedure,
+		svc.RegisterPushSubscription,
+		connect.WithSchema(messagingServiceMethods.ByName("RegisterPushSubscription")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceUnregisterPushSubscriptionHandler := connect.NewUnaryHandler(
+		MessagingServiceUnregisterPushSubscriptionProcedure,
+		svc.UnregisterPushSubscription,
+		connect.WithSchema(messagingServiceMethods.ByName("UnregisterPushSubscription")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceSetEmailHandler := connect.NewUnaryHandler(
+		MessagingServiceSetEmailProcedure,
+		svc.SetEmail,
+		connect.WithSchema(messagingServiceMethods.ByName("SetEmail")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceVerifyEmailHandler := connect.NewUnaryHandler(
+		MessagingServiceVerifyEmailProcedure,
+		svc.VerifyEmail,
+		connect.WithSchema(messagingServiceMethods.ByName("VerifyEmail")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceGetNotificationPreferencesHandler := connect.NewUnaryHandler(
+		MessagingServiceGetNotificationPreferencesProcedure,
+		svc.GetNotificationPreferences,
+		connect.WithSchema(messagingServiceMethods.ByName("GetNotificationPreferences")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceSetNotificationPreferencesHandler := connect.NewUnaryHandler(
+		MessagingServiceSetNotificationPreferencesProcedure,
+		svc.SetNotificationPreferences,
+		connect.WithSchema(messagingServiceMethods.ByName("SetNotificationPreferences")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceCreateBotHandler := connect.NewUnaryHandler(
+		MessagingServiceCreateBotProcedure,
+		svc.CreateBot,
+		connect.WithSchema(messagingServiceMethods.ByName("CreateBot")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceRotateBotKeyHandler := connect.NewUnaryHandler(
+		MessagingServiceRotateBotKeyProcedure,
+		svc.RotateBotKey,
+		connect.WithSchema(messagingServiceMethods.ByName("RotateBotKey")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceListBotsHandler := connect.NewUnaryHandler(
+		MessagingServiceListBotsProcedure,
+		svc.ListBots,
+		connect.WithSchema(messagingServiceMethods.ByName("ListBots")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceDeleteBotHandler := connect.NewUnaryHandler(
+		MessagingServiceDeleteBotProcedure,
+		svc.DeleteBot,
+		connect.WithSchema(messagingServiceMethods.ByName("DeleteBot")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceSetBotCommandsHandler := connect.NewUnaryHandler(
+		MessagingServiceSetBotCommandsProcedure,
+		svc.SetBotCommands,
+		connect.WithSchema(messagingServiceMethods.ByName("SetBotCommands")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceListCommandsHandler := connect.NewUnaryHandler(
+		MessagingServiceListCommandsProcedure,
+		svc.ListCommands,
+		connect.WithSchema(messagingServiceMethods.ByName("ListCommands")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceCreateWebhookHandler := connect.NewUnaryHandler(
+		MessagingServiceCreateWebhookProcedure,
+		svc.CreateWebhook,
+		connect.WithSchema(messagingServiceMethods.ByName("CreateWebhook")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceListWebhooksHandler := connect.NewUnaryHandler(
+		MessagingServiceListWebhooksProcedure,
+		svc.ListWebhooks,
+		connect.WithSchema(messagingServiceMethods.ByName("ListWebhooks")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceDeleteWebhookHandler := connect.NewUnaryHandler(
+		MessagingServiceDeleteWebhookProcedure,
+		svc.DeleteWebhook,
+		connect.WithSchema(messagingServiceMethods.ByName("DeleteWebhook")),
+		connect.WithHandlerOptions(opts...),
+	)
+	messagingServiceListWebhookDeliveriesHandler := connect.NewUnaryHandler(
+		MessagingServiceListWebhookDeliveriesProcedure,
+		svc.ListWebhookDeliveries,
+		connect.WithSchema(messagingServiceMethods.ByName("ListWebhookDeliveries")),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/messaging.v1.MessagingService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case MessagingServiceSendDirectMessageProcedure:
 			messagingServiceSendDirectMessageHandler.ServeHTTP(w, r)
 		case MessagingServiceGetDMsProcedure:
 			messagingServiceGetDMsHandler.ServeHTTP(w, r)
+		case MessagingServiceListDMsProcedure:
+			messagingServiceListDMsHandler.ServeHTTP(w, r)
 		case MessagingServiceRegisterUserProcedure:
 			messagingServiceRegisterUserHandler.ServeHTTP(w, r)
 		case MessagingServiceLoginProcedure:
 			messagingServiceLoginHandler.ServeHTTP(w, r)
 		case MessagingServiceGetUserInfoProcedure:
 			messagingServiceGetUserInfoHandler.ServeHTTP(w, r)
+		case MessagingServiceCreateIncomingWebhookProcedure:
+			messagingServiceCreateIncomingWebhookHandler.ServeHTTP(w, r)
+		case MessagingServiceListIncomingWebhooksProcedure:
+			messagingServiceListIncomingWebhooksHandler.ServeHTTP(w, r)
+		case MessagingServiceDeleteIncomingWebhookProcedure:
+			messagingServiceDeleteIncomingWebhookHandler.ServeHTTP(w, r)
+		case MessagingServiceGetPushPublicKeyProcedure:
+			messagingServiceGetPushPublicKeyHandler.ServeHTTP(w, r)
+		case MessagingServiceRegisterPushSubscriptionProcedure:
+			messagingServiceRegisterPushSubscriptionHandler.ServeHTTP(w, r)
+		case MessagingServiceUnregisterPushSubscriptionProcedure:
+			messagingServiceUnregisterPushSubscriptionHandler.ServeHTTP(w, r)
+		case MessagingServiceSetEmailProcedure:
+			messagingServiceSetEmailHandler.ServeHTTP(w, r)
+		case MessagingServiceVerifyEmailProcedure:
+			messagingServiceVerifyEmailHandler.ServeHTTP(w, r)
+		case MessagingServiceGetNotificationPreferencesProcedure:
+			messagingServiceGetNotificationPreferencesHandler.ServeHTTP(w, r)
+		case MessagingServiceSetNotificationPreferencesProcedure:
+			messagingServiceSetNotificationPreferencesHandler.ServeHTTP(w, r)
+		case MessagingServiceCreateBotProcedure:
+			messagingServiceCreateBotHandler.ServeHTTP(w, r)
+		case MessagingServiceRotateBotKeyProcedure:
+			messagingServiceRotateBotKeyHandler.ServeHTTP(w, r)
+		case MessagingServiceListBotsProcedure:
+			messagingServiceListBotsHandler.ServeHTTP(w, r)
+		case MessagingServiceDeleteBotProcedure:
+			messagingServiceDeleteBotHandler.ServeHTTP(w, r)
+		case MessagingServiceSetBotCommandsProcedure:
+			messagingServiceSetBotCommandsHandler.ServeHTTP(w, r)
+		case MessagingServiceListCommandsProcedure:
+			messagingServiceListCommandsHandler.ServeHTTP(w, r)
+		case MessagingServiceCreateWebhookProcedure:
+			messagingServiceCreateWebhookHandler.ServeHTTP(w, r)
+		case MessagingServiceListWebhooksProcedure:
+			messagingServiceListWebhooksHandler.ServeHTTP(w, r)
+		case MessagingServiceDeleteWebhookProcedure:
+			messagingServiceDeleteWebhookHandler.ServeHTTP(w, r)
+		case MessagingServiceListWebhookDeliveriesProcedure:
+			messagingServiceListWebhookDeliveriesHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -210,6 +741,10 @@ func (UnimplementedMessagingServiceHandler) GetDMs(context.Context, *connect.Req
 	return connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.GetDMs is not implemented"))
 }
 
+func (UnimplementedMessagingServiceHandler) ListDMs(context.Context, *connect.Request[v1.GetDMsRequest]) (*connect.Response[v1.GetDMsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.ListDMs is not implemented"))
+}
+
 func (UnimplementedMessagingServiceHandler) RegisterUser(context.Context, *connect.Request[v1.RegisterUserRequest]) (*connect.Response[v1.RegisterUserResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.RegisterUser is not implemented"))
 }
@@ -221,3 +756,83 @@ func (UnimplementedMessagingServiceHandler) Login(context.Context, *connect.Requ
 func (UnimplementedMessagingServiceHandler) GetUserInfo(context.Context, *connect.Request[v1.GetUserInfoRequest]) (*connect.Response[v1.GetUserInfoResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.GetUserInfo is not implemented"))
 }
+
+func (UnimplementedMessagingServiceHandler) CreateIncomingWebhook(context.Context, *connect.Request[v1.CreateIncomingWebhookRequest]) (*connect.Response[v1.CreateIncomingWebhookResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.CreateIncomingWebhook is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) ListIncomingWebhooks(context.Context, *connect.Request[v1.ListIncomingWebhooksRequest]) (*connect.Response[v1.ListIncomingWebhooksResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.ListIncomingWebhooks is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) DeleteIncomingWebhook(context.Context, *connect.Request[v1.DeleteIncomingWebhookRequest]) (*connect.Response[v1.DeleteIncomingWebhookResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.DeleteIncomingWebhook is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) GetPushPublicKey(context.Context, *connect.Request[v1.GetPushPublicKeyRequest]) (*connect.Response[v1.GetPushPublicKeyResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.GetPushPublicKey is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) RegisterPushSubscription(context.Context, *connect.Request[v1.RegisterPushSubscriptionRequest]) (*connect.Response[v1.RegisterPushSubscriptionResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.RegisterPushSubscription is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) UnregisterPushSubscription(context.Context, *connect.Request[v1.UnregisterPushSubscriptionRequest]) (*connect.Response[v1.UnregisterPushSubscriptionResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.UnregisterPushSubscription is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) SetEmail(context.Context, *connect.Request[v1.SetEmailRequest]) (*connect.Response[v1.SetEmailResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.SetEmail is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) VerifyEmail(context.Context, *connect.Request[v1.VerifyEmailRequest]) (*connect.Response[v1.VerifyEmailResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.VerifyEmail is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) GetNotificationPreferences(context.Context, *connect.Request[v1.GetNotificationPreferencesRequest]) (*connect.Response[v1.GetNotificationPreferencesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.GetNotificationPreferences is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) SetNotificationPreferences(context.Context, *connect.Request[v1.SetNotificationPreferencesRequest]) (*connect.Response[v1.SetNotificationPreferencesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.SetNotificationPreferences is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) CreateBot(context.Context, *connect.Request[v1.CreateBotRequest]) (*connect.Response[v1.CreateBotResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.CreateBot is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) RotateBotKey(context.Context, *connect.Request[v1.RotateBotKeyRequest]) (*connect.Response[v1.RotateBotKeyResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.RotateBotKey is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) ListBots(context.Context, *connect.Request[v1.ListBotsRequest]) (*connect.Response[v1.ListBotsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.ListBots is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) DeleteBot(context.Context, *connect.Request[v1.DeleteBotRequest]) (*connect.Response[v1.DeleteBotResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.DeleteBot is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) SetBotCommands(context.Context, *connect.Request[v1.SetBotCommandsRequest]) (*connect.Response[v1.SetBotCommandsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.SetBotCommands is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) ListCommands(context.Context, *connect.Request[v1.ListCommandsRequest]) (*connect.Response[v1.ListCommandsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.ListCommands is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) CreateWebhook(context.Context, *connect.Request[v1.CreateWebhookRequest]) (*connect.Response[v1.CreateWebhookResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.CreateWebhook is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) ListWebhooks(context.Context, *connect.Request[v1.ListWebhooksRequest]) (*connect.Response[v1.ListWebhooksResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.ListWebhooks is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) DeleteWebhook(context.Context, *connect.Request[v1.DeleteWebhookRequest]) (*connect.Response[v1.DeleteWebhookResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.DeleteWebhook is not implemented"))
+}
+
+func (UnimplementedMessagingServiceHandler) ListWebhookDeliveries(context.Context, *connect.Request[v1.ListWebhookDeliveriesRequest]) (*connect.Response[v1.ListWebhookDeliveriesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("messaging.v1.MessagingService.ListWebhookDeliveries is not implemented"))
+}
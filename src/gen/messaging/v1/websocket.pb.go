@@ -0,0 +1,522 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        (unknown)
+// source: messaging/v1/websocket.proto
+
+package messagingv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Sent by clients of the /ws gateway. Binary WebSocket messages hold the
+// protobuf encoding, text messages the JSON encoding.
+type ClientFrame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Chosen by the client and copied into the frames that answer this one.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Types that are valid to be assigned to Frame:
+	//
+	//	*ClientFrame_Subscribe
+	//	*ClientFrame_Send
+	//	*ClientFrame_Typing
+	//	*ClientFrame_Ack
+	Frame         isClientFrame_Frame `protobuf_oneof:"frame"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientFrame) Reset() {
+	*x = ClientFrame{}
+	mi := &file_messaging_v1_websocket_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientFrame) ProtoMessage() {}
+
+func (x *ClientFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_websocket_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientFrame.ProtoReflect.Descriptor instead.
+func (*ClientFrame) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_websocket_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ClientFrame) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ClientFrame) GetFrame() isClientFrame_Frame {
+	if x != nil {
+		return x.Frame
+	}
+	return nil
+}
+
+func (x *ClientFrame) GetSubscribe() *GetDMsRequest {
+	if x != nil {
+		if x, ok := x.Frame.(*ClientFrame_Subscribe); ok {
+			return x.Subscribe
+		}
+	}
+	return nil
+}
+
+func (x *ClientFrame) GetSend() *SendDirectMessageRequest {
+	if x != nil {
+		if x, ok := x.Frame.(*ClientFrame_Send); ok {
+			return x.Send
+		}
+	}
+	return nil
+}
+
+func (x *ClientFrame) GetTyping() *Typing {
+	if x != nil {
+		if x, ok := x.Frame.(*ClientFrame_Typing); ok {
+			return x.Typing
+		}
+	}
+	return nil
+}
+
+func (x *ClientFrame) GetAck() *Ack {
+	if x != nil {
+		if x, ok := x.Frame.(*ClientFrame_Ack); ok {
+			return x.Ack
+		}
+	}
+	return nil
+}
+
+type isClientFrame_Frame interface {
+	isClientFrame_Frame()
+}
+
+type ClientFrame_Subscribe struct {
+	// Sends the history since from_date, then every update of the chat with
+	// user_b. user_a must be the caller.
+	Subscribe *GetDMsRequest `protobuf:"bytes,2,opt,name=subscribe,proto3,oneof"`
+}
+
+type ClientFrame_Send struct {
+	Send *SendDirectMessageRequest `protobuf:"bytes,3,opt,name=send,proto3,oneof"`
+}
+
+type ClientFrame_Typing struct {
+	Typing *Typing `protobuf:"bytes,4,opt,name=typing,proto3,oneof"`
+}
+
+type ClientFrame_Ack struct {
+	Ack *Ack `protobuf:"bytes,5,opt,name=ack,proto3,oneof"`
+}
+
+func (*ClientFrame_Subscribe) isClientFrame_Frame() {}
+
+func (*ClientFrame_Send) isClientFrame_Frame() {}
+
+func (*ClientFrame_Typing) isClientFrame_Frame() {}
+
+func (*ClientFrame_Ack) isClientFrame_Frame() {}
+
+// Tells receiver that the caller is typing
+type Typing struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Receiver      string                 `protobuf:"bytes,1,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Typing) Reset() {
+	*x = Typing{}
+	mi := &file_messaging_v1_websocket_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Typing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Typing) ProtoMessage() {}
+
+func (x *Typing) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_websocket_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Typing.ProtoReflect.Descriptor instead.
+func (*Typing) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_websocket_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Typing) GetReceiver() string {
+	if x != nil {
+		return x.Receiver
+	}
+	return ""
+}
+
+// Tells sender that the caller received these messages
+type Ack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sender        string                 `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
+	MessageIds    []uint64               `protobuf:"varint,2,rep,packed,name=message_ids,json=messageIds,proto3" json:"message_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_messaging_v1_websocket_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_websocket_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_websocket_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Ack) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *Ack) GetMessageIds() []uint64 {
+	if x != nil {
+		return x.MessageIds
+	}
+	return nil
+}
+
+type ServerFrame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The id of the client frame this answers, empty for updates.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The other participant of the chat an update belongs to.
+	UserB string `protobuf:"bytes,2,opt,name=user_b,json=userB,proto3" json:"user_b,omitempty"`
+	// Types that are valid to be assigned to Frame:
+	//
+	//	*ServerFrame_Dms
+	//	*ServerFrame_Sent
+	//	*ServerFrame_Error
+	Frame         isServerFrame_Frame `protobuf_oneof:"frame"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerFrame) Reset() {
+	*x = ServerFrame{}
+	mi := &file_messaging_v1_websocket_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerFrame) ProtoMessage() {}
+
+func (x *ServerFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_websocket_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerFrame.ProtoReflect.Descriptor instead.
+func (*ServerFrame) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_websocket_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ServerFrame) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ServerFrame) GetUserB() string {
+	if x != nil {
+		return x.UserB
+	}
+	return ""
+}
+
+func (x *ServerFrame) GetFrame() isServerFrame_Frame {
+	if x != nil {
+		return x.Frame
+	}
+	return nil
+}
+
+func (x *ServerFrame) GetDms() *GetDMsResponse {
+	if x != nil {
+		if x, ok := x.Frame.(*ServerFrame_Dms); ok {
+			return x.Dms
+		}
+	}
+	return nil
+}
+
+func (x *ServerFrame) GetSent() *SendDirectMessageResponse {
+	if x != nil {
+		if x, ok := x.Frame.(*ServerFrame_Sent); ok {
+			return x.Sent
+		}
+	}
+	return nil
+}
+
+func (x *ServerFrame) GetError() *FrameError {
+	if x != nil {
+		if x, ok := x.Frame.(*ServerFrame_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isServerFrame_Frame interface {
+	isServerFrame_Frame()
+}
+
+type ServerFrame_Dms struct {
+	// The history of a subscription, and its updates.
+	Dms *GetDMsResponse `protobuf:"bytes,3,opt,name=dms,proto3,oneof"`
+}
+
+type ServerFrame_Sent struct {
+	Sent *SendDirectMessageResponse `protobuf:"bytes,4,opt,name=sent,proto3,oneof"`
+}
+
+type ServerFrame_Error struct {
+	Error *FrameError `protobuf:"bytes,5,opt,name=error,proto3,oneof"`
+}
+
+func (*ServerFrame_Dms) isServerFrame_Frame() {}
+
+func (*ServerFrame_Sent) isServerFrame_Frame() {}
+
+func (*ServerFrame_Error) isServerFrame_Frame() {}
+
+// Reports a client frame that failed. Codes are Connect codes, e.g. "unauthenticated".
+type FrameError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FrameError) Reset() {
+	*x = FrameError{}
+	mi := &file_messaging_v1_websocket_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FrameError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FrameError) ProtoMessage() {}
+
+func (x *FrameError) ProtoReflect() protoreflect.Message {
+	mi := &file_messaging_v1_websocket_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FrameError.ProtoReflect.Descriptor instead.
+func (*FrameError) Descriptor() ([]byte, []int) {
+	return file_messaging_v1_websocket_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FrameError) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *FrameError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_messaging_v1_websocket_proto protoreflect.FileDescriptor
+
+const file_messaging_v1_websocket_proto_rawDesc = "" +
+	"\n" +
+	"\x1cmessaging/v1/websocket.proto\x12\fmessaging.v1\x1a\x1cmessaging/v1/messaging.proto\"\xf8\x01\n" +
+	"\vClientFrame\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12;\n" +
+	"\tsubscribe\x18\x02 \x01(\v2\x1b.messaging.v1.GetDMsRequestH\x00R\tsubscribe\x12<\n" +
+	"\x04send\x18\x03 \x01(\v2&.messaging.v1.SendDirectMessageRequestH\x00R\x04send\x12.\n" +
+	"\x06typing\x18\x04 \x01(\v2\x14.messaging.v1.TypingH\x00R\x06typing\x12%\n" +
+	"\x03ack\x18\x05 \x01(\v2\x11.messaging.v1.AckH\x00R\x03ackB\a\n" +
+	"\x05frame\"$\n" +
+	"\x06Typing\x12\x1a\n" +
+	"\breceiver\x18\x01 \x01(\tR\breceiver\">\n" +
+	"\x03Ack\x12\x16\n" +
+	"\x06sender\x18\x01 \x01(\tR\x06sender\x12\x1f\n" +
+	"\vmessage_ids\x18\x02 \x03(\x04R\n" +
+	"messageIds\"\xe0\x01\n" +
+	"\vServerFrame\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x15\n" +
+	"\x06user_b\x18\x02 \x01(\tR\x05userB\x120\n" +
+	"\x03dms\x18\x03 \x01(\v2\x1c.messaging.v1.GetDMsResponseH\x00R\x03dms\x12=\n" +
+	"\x04sent\x18\x04 \x01(\v2'.messaging.v1.SendDirectMessageResponseH\x00R\x04sent\x120\n" +
+	"\x05error\x18\x05 \x01(\v2\x18.messaging.v1.FrameErrorH\x00R\x05errorB\a\n" +
+	"\x05frame\":\n" +
+	"\n" +
+	"FrameError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessageB<Z:github.com/vl0000/gomessenger/gen/messaging/v1;messagingv1b\x06proto3"
+
+var (
+	file_messaging_v1_websocket_proto_rawDescOnce sync.Once
+	file_messaging_v1_websocket_proto_rawDescData []byte
+)
+
+func file_messaging_v1_websocket_proto_rawDescGZIP() []byte {
+	file_messaging_v1_websocket_proto_rawDescOnce.Do(func() {
+		file_messaging_v1_websocket_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_messaging_v1_websocket_proto_rawDesc), len(file_messaging_v1_websocket_proto_rawDesc)))
+	})
+	return file_messaging_v1_websocket_proto_rawDescData
+}
+
+var file_messaging_v1_websocket_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_messaging_v1_websocket_proto_goTypes = []any{
+	(*ClientFrame)(nil),               // 0: messaging.v1.ClientFrame
+	(*Typing)(nil),                    // 1: messaging.v1.Typing
+	(*Ack)(nil),                       // 2: messaging.v1.Ack
+	(*ServerFrame)(nil),               // 3: messaging.v1.ServerFrame
+	(*FrameError)(nil),                // 4: messaging.v1.FrameError
+	(*GetDMsRequest)(nil),             // 5: messaging.v1.GetDMsRequest
+	(*SendDirectMessageRequest)(nil),  // 6: messaging.v1.SendDirectMessageRequest
+	(*GetDMsResponse)(nil),            // 7: messaging.v1.GetDMsResponse
+	(*SendDirectMessageResponse)(nil), // 8: messaging.v1.SendDirectMessageResponse
+}
+var file_messaging_v1_websocket_proto_depIdxs = []int32{
+	5, // 0: messaging.v1.ClientFrame.subscribe:type_name -> messaging.v1.GetDMsRequest
+	6, // 1: messaging.v1.ClientFrame.send:type_name -> messaging.v1.SendDirectMessageRequest
+	1, // 2: messaging.v1.ClientFrame.typing:type_name -> messaging.v1.Typing
+	2, // 3: messaging.v1.ClientFrame.ack:type_name -> messaging.v1.Ack
+	7, // 4: messaging.v1.ServerFrame.dms:type_name -> messaging.v1.GetDMsResponse
+	8, // 5: messaging.v1.ServerFrame.sent:type_name -> messaging.v1.SendDirectMessageResponse
+	4, // 6: messaging.v1.ServerFrame.error:type_name -> messaging.v1.FrameError
+	7, // [7:7] is the sub-list for method output_type
+	7, // [7:7] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_messaging_v1_websocket_proto_init() }
+func file_messaging_v1_websocket_proto_init() {
+	if File_messaging_v1_websocket_proto != nil {
+		return
+	}
+	file_messaging_v1_messaging_proto_init()
+	file_messaging_v1_websocket_proto_msgTypes[0].OneofWrappers = []any{
+		(*ClientFrame_Subscribe)(nil),
+		(*ClientFrame_Send)(nil),
+		(*ClientFrame_Typing)(nil),
+		(*ClientFrame_Ack)(nil),
+	}
+	file_messaging_v1_websocket_proto_msgTypes[3].OneofWrappers = []any{
+		(*ServerFrame_Dms)(nil),
+		(*ServerFrame_Sent)(nil),
+		(*ServerFrame_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_messaging_v1_websocket_proto_rawDesc), len(file_messaging_v1_websocket_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_messaging_v1_websocket_proto_goTypes,
+		DependencyIndexes: file_messaging_v1_websocket_proto_depIdxs,
+		MessageInfos:      file_messaging_v1_websocket_proto_msgTypes,
+	}.Build()
+	File_messaging_v1_websocket_proto = out.File
+	file_messaging_v1_websocket_proto_goTypes = nil
+	file_messaging_v1_websocket_proto_depIdxs = nil
+}
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Attribute keys shared by every log line
+const (
+	REQUEST_ID_KEY string = "request_id"
+	PROCEDURE_KEY  string = "procedure"
+	USER_KEY       string = "user"
+	TRACE_ID_KEY   string = "trace_id"
+)
+
+// Level of the default logger. Changing it takes effect immediately.
+var Level = new(slog.LevelVar)
+
+type contextKey struct{}
+
+// Shared by everything that handles a request, so that attributes added deep
+// inside a handler also show up in the access log
+type holder struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+// Installs the default slog logger. format is either "text" or "json".
+func Setup(w io.Writer, format string, level string) error {
+	if err := SetLevel(level); err != nil {
+		return err
+	}
+
+	opts := &slog.HandlerOptions{Level: Level}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text", "":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("Unknown log format %q, expected text or json", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Accepts debug, info, warn or error
+func SetLevel(level string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("Unknown log level %q, expected debug, info, warn or error", level)
+	}
+	Level.Set(l)
+	return nil
+}
+
+// Returns a copy of ctx whose logger also writes args on every line
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, contextKey{}, &holder{logger: FromContext(ctx).With(args...)})
+}
+
+// Adds args to the logger of ctx in place, so they also reach whoever created
+// it with With(). Falls back to With() when ctx has no logger yet.
+func Annotate(ctx context.Context, args ...any) context.Context {
+	h, ok := ctx.Value(contextKey{}).(*holder)
+	if !ok {
+		return With(ctx, args...)
+	}
+	h.mu.Lock()
+	h.logger = h.logger.With(args...)
+	h.mu.Unlock()
+	return ctx
+}
+
+// Returns the request's logger, or the default one outside of requests
+func FromContext(ctx context.Context) *slog.Logger {
+	if h, ok := ctx.Value(contextKey{}).(*holder); ok {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.logger
+	}
+	return slog.Default()
+}
+
+// Replaces chi's middleware.Logger. It must come after middleware.RequestID
+// so that the access log and everything logged by handlers share the request id.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := With(r.Context(), REQUEST_ID_KEY, middleware.GetReqID(r.Context()))
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		FromContext(ctx).Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", ww.Status(),
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
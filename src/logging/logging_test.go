@@ -0,0 +1,69 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/vl0000/gomessenger/logging"
+)
+
+func TestLogging(t *testing.T) {
+	t.Run("Access log includes attributes added by handlers", func(t *testing.T) {
+		// SETUP
+		var out bytes.Buffer
+		if err := logging.Setup(&out, "json", "info"); err != nil {
+			t.Fatal(err)
+		}
+		defer slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+		handler := middleware.RequestID(logging.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logging.Annotate(r.Context(), logging.PROCEDURE_KEY, "/messaging.v1.MessagingService/Login")
+			logging.FromContext(r.Context()).Info("handled")
+		})))
+		// END SETUP
+
+		req := httptest.NewRequest(http.MethodPost, "/messaging.v1.MessagingService/Login", nil)
+		req.Header.Set(middleware.RequestIDHeader, "abc-123")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected 2 log lines, got %d:\n%s", len(lines), out.String())
+		}
+		for _, line := range lines {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry[logging.REQUEST_ID_KEY] != "abc-123" || entry[logging.PROCEDURE_KEY] == nil {
+				t.Fatalf("Missing request id or procedure in %s", line)
+			}
+		}
+	})
+
+	t.Run("Level can be changed at runtime", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := logging.Setup(&out, "text", "warn"); err != nil {
+			t.Fatal(err)
+		}
+		defer slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+
+		slog.Info("hidden")
+		if err := logging.SetLevel("debug"); err != nil {
+			t.Fatal(err)
+		}
+		slog.Debug("shown")
+
+		if strings.Contains(out.String(), "hidden") || !strings.Contains(out.String(), "shown") {
+			t.Fatalf("Unexpected output:\n%s", out.String())
+		}
+		if err := logging.SetLevel("loud"); err == nil {
+			t.Fatal("Expected an error for an unknown level")
+		}
+	})
+}
@@ -2,28 +2,83 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/httprate"
+	"github.com/vl0000/gomessenger/config"
+	"github.com/vl0000/gomessenger/data"
+	"github.com/vl0000/gomessenger/logging"
+	"github.com/vl0000/gomessenger/push"
 	"github.com/vl0000/gomessenger/server"
+	"github.com/vl0000/gomessenger/tracing"
 )
 
+const USAGE string = `Usage:
+	gomessenger [flags]                          Starts the server
+	gomessenger [flags] backup [file]            Snapshots the database to file, or into backup.dir
+	gomessenger [flags] restore <file>           Replaces the database with a snapshot. The server must be stopped
+	gomessenger [flags] promote <phone_number>   Gives a user the admin role
+	gomessenger [flags] rotate-keys              Re-wraps message data keys with message_key
+	gomessenger [flags] export [file]            Writes users and messages as JSON lines to file or stdout
+	gomessenger [flags] import [file]            Loads an export from file or stdin
+	gomessenger [flags] config print             Shows the effective configuration with secrets redacted
+	gomessenger [flags] vapid-keys               Generates the key pair of Web Push
+`
+
 func main() {
-	s := server.New()
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprint(os.Stderr, USAGE)
+		os.Exit(2)
+	}
+
+	if err := logging.Setup(os.Stderr, cfg.Log.Format, cfg.Log.Level); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(args) > 0 {
+		if err := runCommand(cfg, args[0], args[1:]); err != nil {
+			slog.Error("Command failed", "command", args[0], "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	s, err := server.New(cfg)
+	if err != nil {
+		slog.Error("Could not start the server", "error", err)
+		os.Exit(1)
+	}
 
-	s.Router.Use(middleware.Logger)
-	s.Router.Use(middleware.Timeout(5 * time.Second))
-	s.Router.Use(httprate.LimitByIP(100, 1*time.Minute))
+	shutdown_tracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		slog.Error("Could not set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	s.Router.Use(middleware.RequestID)
+	s.Router.Use(logging.Middleware)
+	s.Router.Use(middleware.Timeout(cfg.HTTP.RequestTimeout))
+	s.Router.Use(s.RateLimit(cfg.HTTP.RateLimit, cfg.HTTP.BotRateLimit, cfg.HTTP.RateLimitWindow))
 	s.LoadRoutes()
 
 	go func() {
 		err := s.Run()
 		if err != nil {
-			log.Println(err)
+			slog.Error("Server stopped", "error", err)
 		}
 	}()
 
@@ -33,4 +88,110 @@ func main() {
 	<-shutdown.Done()
 
 	s.Shutdown()
+	if err := shutdown_tracing(context.Background()); err != nil {
+		slog.Error("Could not flush traces", "error", err)
+	}
+}
+
+func runCommand(cfg *config.Config, command string, args []string) error {
+	ctx := context.Background()
+
+	switch command {
+	case "config":
+		if len(args) != 1 || args[0] != "print" {
+			return errors.New(USAGE)
+		}
+		return cfg.Print(os.Stdout)
+
+	case "vapid-keys":
+		public_key, private_key, err := push.GenerateVAPIDKeys()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("VAPID_PRIVATE_KEY=%s\n# Public key, returned by GetPushPublicKey\n# %s\n", private_key, public_key)
+		return nil
+
+	case "backup":
+		db, err := server.OpenDatabase(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if len(args) == 1 {
+			return data.Backup(ctx, db.DB, args[0])
+		}
+		if cfg.Backup.Dir == "" {
+			return errors.New("A destination file or backup.dir must be given")
+		}
+		path, err := data.BackupToDir(ctx, db.DB, cfg.Backup.Dir, 0)
+		if err != nil {
+			return err
+		}
+		slog.Info("Database backed up", "path", path)
+		return nil
+
+	case "restore":
+		if len(args) != 1 {
+			return errors.New(USAGE)
+		}
+		return data.Restore(ctx, args[0], cfg.Database.Path)
+
+	case "promote":
+		if len(args) != 1 {
+			return errors.New(USAGE)
+		}
+		db, err := server.OpenDatabase(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return server.SetUserRole(db, args[0], server.ROLE_ADMIN)
+
+	case "rotate-keys":
+		db, err := server.OpenDatabase(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		rotated, err := db.RotateDataKeys(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info("Re-wrapped data keys", "count", rotated)
+		return nil
+
+	case "export", "import":
+		db, err := server.OpenDatabase(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if command == "export" {
+			out := os.Stdout
+			if len(args) == 1 {
+				if out, err = os.Create(args[0]); err != nil {
+					return err
+				}
+				defer out.Close()
+			}
+			count, err := db.Export(ctx, out)
+			slog.Info("Exported records", "count", count)
+			return err
+		}
+
+		in := os.Stdin
+		if len(args) == 1 {
+			if in, err = os.Open(args[0]); err != nil {
+				return err
+			}
+			defer in.Close()
+		}
+		count, err := db.Import(ctx, in)
+		slog.Info("Imported records", "count", count)
+		return err
+	}
+
+	return errors.New(USAGE)
 }
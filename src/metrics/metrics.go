@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const NAMESPACE string = "gomessenger"
+
+var (
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "requests_total",
+		Help:      "Handled RPCs by procedure and Connect error code.",
+	}, []string{"procedure", "code"})
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "request_duration_seconds",
+		Help:      "Time spent handling unary RPCs.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"procedure"})
+
+	ActiveStreams = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "active_streams",
+		Help:      "Open server streams by procedure.",
+	}, []string{"procedure"})
+
+	MessagesSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "messages_sent_total",
+		Help:      "Messages stored by SendDirectMessage.",
+	})
+
+	LoginFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "login_failures_total",
+		Help:      "Login attempts with an unknown user or a wrong password.",
+	})
+)
+
+// Registers collectors that belong to a running server. Registering them
+// again, as tests that create several servers do, keeps the first ones.
+func Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		err := prometheus.Register(c)
+		if are := (prometheus.AlreadyRegisteredError{}); errors.As(err, &are) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exposes the sql.DB connection pool statistics
+func DBStats(db *sql.DB) prometheus.Collector {
+	return collectors.NewDBStatsCollector(db, "main")
+}
+
+// Reports how full the subscriber channels of open streams are.
+// fill must return the length and capacity of every channel.
+func ChannelFill(fill func() (queued int, capacity int, fullest float64)) prometheus.Collector {
+	return &channelCollector{
+		fill: fill,
+		queued: prometheus.NewDesc(
+			prometheus.BuildFQName(NAMESPACE, "stream_channel", "queued_messages"),
+			"Messages waiting in subscriber channels to be sent to streams.", nil, nil),
+		capacity: prometheus.NewDesc(
+			prometheus.BuildFQName(NAMESPACE, "stream_channel", "capacity"),
+			"Total capacity of subscriber channels.", nil, nil),
+		fullest: prometheus.NewDesc(
+			prometheus.BuildFQName(NAMESPACE, "stream_channel", "max_fill_ratio"),
+			"Fill ratio of the fullest subscriber channel, 1 means senders block.", nil, nil),
+	}
+}
+
+type channelCollector struct {
+	fill                      func() (int, int, float64)
+	queued, capacity, fullest *prometheus.Desc
+}
+
+func (c *channelCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queued
+	ch <- c.capacity
+	ch <- c.fullest
+}
+
+func (c *channelCollector) Collect(ch chan<- prometheus.Metric) {
+	queued, capacity, fullest := c.fill()
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(queued))
+	ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(capacity))
+	ch <- prometheus.MustNewConstMetric(c.fullest, prometheus.GaugeValue, fullest)
+}
@@ -0,0 +1,41 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vl0000/gomessenger/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("Channel fill is reported", func(t *testing.T) {
+		collector := metrics.ChannelFill(func() (int, int, float64) {
+			return 3, 64, 0.5
+		})
+
+		expected := `
+# HELP gomessenger_stream_channel_max_fill_ratio Fill ratio of the fullest subscriber channel, 1 means senders block.
+# TYPE gomessenger_stream_channel_max_fill_ratio gauge
+gomessenger_stream_channel_max_fill_ratio 0.5
+# HELP gomessenger_stream_channel_queued_messages Messages waiting in subscriber channels to be sent to streams.
+# TYPE gomessenger_stream_channel_queued_messages gauge
+gomessenger_stream_channel_queued_messages 3
+`
+		err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+			"gomessenger_stream_channel_max_fill_ratio", "gomessenger_stream_channel_queued_messages")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Collectors can be registered twice", func(t *testing.T) {
+		fill := func() (int, int, float64) { return 0, 0, 0 }
+		if err := metrics.Register(metrics.ChannelFill(fill)); err != nil {
+			t.Fatal(err)
+		}
+		if err := metrics.Register(metrics.ChannelFill(fill)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
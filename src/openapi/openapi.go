@@ -0,0 +1,315 @@
+// Builds an OpenAPI 3 document from the google.api.http options of protobuf
+// services, so the REST gateway and its documentation can not drift apart.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const VERSION string = "3.0.3"
+
+// Name of the schema of error responses
+const STATUS_SCHEMA string = "google.rpc.Status"
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Matches the variables of path templates, e.g. {user_b} or {name=shelves/*}
+var path_variable = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+// Describes every method of services that has a google.api.http option.
+// Every operation requires the Authorization header except the methods named in public.
+func Generate(info Info, public []string, services ...protoreflect.ServiceDescriptor) *Document {
+	doc := &Document{
+		OpenAPI: VERSION,
+		Info:    info,
+		Paths:   map[string]map[string]*Operation{},
+		Components: Components{
+			Schemas: map[string]*Schema{STATUS_SCHEMA: statusSchema()},
+			SecuritySchemes: map[string]*SecurityScheme{"token": {
+				Type:        "apiKey",
+				In:          "header",
+				Name:        "Authorization",
+				Description: "JWT returned by RegisterUser or Login",
+			}},
+		},
+	}
+
+	for _, service := range services {
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+			if !ok || rule == nil {
+				continue
+			}
+			for _, binding := range append([]*annotations.HttpRule{rule}, rule.AdditionalBindings...) {
+				verb, template := pattern(binding)
+				if template == "" {
+					continue
+				}
+				op := doc.operation(service, method, binding, template)
+				if !contains(public, string(method.Name())) {
+					op.Security = []map[string][]string{{"token": {}}}
+				}
+				path := path_variable.ReplaceAllString(template, "{$1}")
+				if doc.Paths[path] == nil {
+					doc.Paths[path] = map[string]*Operation{}
+				}
+				doc.Paths[path][verb] = op
+			}
+		}
+	}
+	return doc
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func pattern(rule *annotations.HttpRule) (string, string) {
+	switch p := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		return "get", p.Get
+	case *annotations.HttpRule_Put:
+		return "put", p.Put
+	case *annotations.HttpRule_Post:
+		return "post", p.Post
+	case *annotations.HttpRule_Delete:
+		return "delete", p.Delete
+	case *annotations.HttpRule_Patch:
+		return "patch", p.Patch
+	case *annotations.HttpRule_Custom:
+		return strings.ToLower(p.Custom.Kind), p.Custom.Path
+	}
+	return "", ""
+}
+
+func (doc *Document) operation(
+	service protoreflect.ServiceDescriptor,
+	method protoreflect.MethodDescriptor,
+	rule *annotations.HttpRule,
+	template string,
+) *Operation {
+	op := &Operation{
+		OperationID: string(method.Name()),
+		Tags:        []string{string(service.Name())},
+		Responses: map[string]*Response{
+			"200": {Description: "OK", Content: jsonContent(doc.ref(method.Output()))},
+			"default": {
+				Description: "Error",
+				Content:     jsonContent(&Schema{Ref: "#/components/schemas/" + STATUS_SCHEMA}),
+			},
+		},
+	}
+
+	in_path := map[string]bool{}
+	for _, match := range path_variable.FindAllStringSubmatch(template, -1) {
+		in_path[match[1]] = true
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   doc.fieldSchema(fieldByPath(method.Input(), match[1])),
+		})
+	}
+
+	switch rule.Body {
+	case "*":
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(doc.ref(method.Input()))}
+	case "":
+		// Fields that are not in the path are query parameters
+		fields := method.Input().Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			if in_path[string(field.Name())] || field.Kind() == protoreflect.MessageKind {
+				continue
+			}
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:   field.JSONName(),
+				In:     "query",
+				Schema: doc.fieldSchema(field),
+			})
+		}
+	default:
+		field := method.Input().Fields().ByName(protoreflect.Name(rule.Body))
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(doc.fieldSchema(field))}
+	}
+	return op
+}
+
+func fieldByPath(message protoreflect.MessageDescriptor, path string) protoreflect.FieldDescriptor {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		field := message.Fields().ByName(protoreflect.Name(name))
+		if field == nil || i == len(names)-1 {
+			return field
+		}
+		message = field.Message()
+	}
+	return nil
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+// Adds the schema of message to the components and returns a reference to it
+func (doc *Document) ref(message protoreflect.MessageDescriptor) *Schema {
+	name := string(message.FullName())
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := doc.Components.Schemas[name]; ok {
+		return ref
+	}
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	// Registered first so that recursive messages terminate
+	doc.Components.Schemas[name] = schema
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		schema.Properties[field.JSONName()] = doc.fieldSchema(field)
+	}
+	return ref
+}
+
+// Follows the protobuf JSON mapping, e.g. 64 bit integers are strings
+func (doc *Document) fieldSchema(field protoreflect.FieldDescriptor) *Schema {
+	if field == nil {
+		return &Schema{Type: "string"}
+	}
+	if field.IsMap() {
+		return &Schema{Type: "object", AdditionalProperties: doc.singularSchema(field.MapValue())}
+	}
+	if field.IsList() {
+		return &Schema{Type: "array", Items: doc.singularSchema(field)}
+	}
+	return doc.singularSchema(field)
+}
+
+func (doc *Document) singularSchema(field protoreflect.FieldDescriptor) *Schema {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Schema{Type: "integer", Format: "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &Schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &Schema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &Schema{Type: "number", Format: "double"}
+	case protoreflect.BytesKind:
+		return &Schema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		schema := &Schema{Type: "string"}
+		for i := 0; i < values.Len(); i++ {
+			schema.Enum = append(schema.Enum, string(values.Get(i).Name()))
+		}
+		return schema
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return doc.ref(field.Message())
+	}
+	return &Schema{Type: "string"}
+}
+
+// Errors of the REST gateway are google.rpc.Status messages
+func statusSchema() *Schema {
+	return &Schema{Type: "object", Properties: map[string]*Schema{
+		"code":    {Type: "integer", Format: "int32"},
+		"message": {Type: "string"},
+		"details": {Type: "array", Items: &Schema{Type: "object"}},
+	}}
+}
+
+// Serves doc as JSON
+func Handler(doc *Document) (http.Handler, error) {
+	content, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI -> %s", err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(content)
+	}), nil
+}
@@ -0,0 +1,79 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/openapi"
+)
+
+func TestOpenAPI(t *testing.T) {
+	// SETUP
+	service := messagingv1.File_messaging_v1_messaging_proto.Services().ByName("MessagingService")
+	doc := openapi.Generate(openapi.Info{Title: "GoMessenger", Version: "v1"}, []string{"RegisterUser", "Login"}, service)
+	// END SETUP
+
+	t.Run("Annotated methods become operations", func(t *testing.T) {
+		for path, verb := range map[string]string{
+			"/v1/messages":                        "post",
+			"/v1/conversations/{user_b}/messages": "get",
+			"/v1/users":                           "post",
+			"/v1/sessions":                        "post",
+			"/v1/users/{phone_number}":            "get",
+		} {
+			if doc.Paths[path][verb] == nil {
+				t.Errorf("Missing %s %s", verb, path)
+			}
+		}
+		for _, ops := range doc.Paths {
+			for _, op := range ops {
+				if op.OperationID == "GetDMs" {
+					t.Error("Streaming GetDMs should not be exposed")
+				}
+			}
+		}
+	})
+
+	t.Run("Parameters come from the path and the query", func(t *testing.T) {
+		op := doc.Paths["/v1/conversations/{user_b}/messages"]["get"]
+		found := map[string]string{}
+		for _, param := range op.Parameters {
+			found[param.Name] = param.In
+		}
+		if found["user_b"] != "path" || found["userA"] != "query" || found["fromDate"] != "query" {
+			t.Fatalf("Unexpected parameters %v", found)
+		}
+		if doc.Paths["/v1/messages"]["post"].RequestBody == nil {
+			t.Error("POST /v1/messages has no request body")
+		}
+	})
+
+	t.Run("Only public methods skip the token", func(t *testing.T) {
+		if doc.Paths["/v1/users"]["post"].Security != nil || doc.Paths["/v1/sessions"]["post"].Security != nil {
+			t.Error("Public methods require a token")
+		}
+		if doc.Paths["/v1/messages"]["post"].Security == nil {
+			t.Error("SendDirectMessage does not require a token")
+		}
+	})
+
+	t.Run("The handler serves the document as JSON", func(t *testing.T) {
+		handler, err := openapi.Handler(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+		var served openapi.Document
+		if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+			t.Fatal(err)
+		}
+		if served.OpenAPI != openapi.VERSION || len(served.Paths) != len(doc.Paths) {
+			t.Fatalf("Unexpected document %s", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,24 @@
+// The web frontend. The static directory holds the build output of the
+// Svelte apps, including .gz and .br variants when they were precompressed.
+package public
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+// all: is needed because SvelteKit puts its assets in _app, and files starting
+// with _ are otherwise left out
+//
+//go:embed *.html all:static
+var embedded embed.FS
+
+// Returns the embedded frontend, or the files in dir when it is not empty so
+// that frontend changes show up without rebuilding the server
+func Files(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	return embedded
+}
@@ -0,0 +1,354 @@
+// Sends Web Push notifications. Payloads are encrypted for the browser as in
+// RFC 8291, and requests are signed with VAPID as in RFC 8292
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/vl0000/gomessenger/webhooks"
+)
+
+// Size of the single record of an encrypted payload
+const RECORD_SIZE uint32 = 4096
+
+// Largest payload that fits in a record with its padding delimiter and tag
+const MAX_PAYLOAD int = int(RECORD_SIZE) - 86 - 1 - 16
+
+// How long VAPID signatures are valid. Push services reject more than 24 hours
+const VAPID_EXPIRY time.Duration = 12 * time.Hour
+
+var (
+	// The push service no longer knows the subscription and it must be removed
+	ErrGone            = errors.New("Push subscription expired or was removed")
+	ErrPayloadTooLarge = errors.New("Push payload is too large")
+	ErrBadKey          = errors.New("Push key is invalid")
+)
+
+// Keys of a subscription as PushSubscription.toJSON() returns them, in
+// unpadded base64url
+type Subscription struct {
+	Endpoint string
+	// Uncompressed P-256 public key of the browser
+	P256dh string
+	// 16 byte authentication secret
+	Auth string
+}
+
+// Delivers a payload to a subscription. The Web Push sender is used in
+// production, tests can use their own
+type Sender interface {
+	Send(ctx context.Context, sub Subscription, payload []byte) error
+}
+
+type Options struct {
+	// Raw P-256 private key in base64url, as GenerateVAPIDKeys() returns it
+	VAPIDPrivateKey string
+	// mailto: or https: URL push services can use to contact the operator
+	Subject string
+	// How long push services keep notifications for offline browsers
+	TTL time.Duration
+	// Timeout of a single request
+	Timeout time.Duration
+	// Allows endpoints on loopback and private networks, like test push services
+	AllowPrivateAddresses bool
+}
+
+// Sends notifications to push services with the Web Push protocol
+type WebPush struct {
+	opts Options
+	key  *ecdsa.PrivateKey
+	// Uncompressed public key in base64url, the applicationServerKey of browsers
+	public_key string
+	client     *http.Client
+}
+
+func NewWebPush(opts Options) (*WebPush, error) {
+	key, err := parseVAPIDKey(opts.VAPIDPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	public_key, err := key.PublicKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return &WebPush{
+		opts:       opts,
+		key:        key,
+		public_key: base64.RawURLEncoding.EncodeToString(public_key.Bytes()),
+		client:     webhooks.NewClient(opts.Timeout, opts.AllowPrivateAddresses),
+	}, nil
+}
+
+// Returns a new VAPID key pair in base64url. The public key is what browsers
+// pass to pushManager.subscribe() as applicationServerKey
+func GenerateVAPIDKeys() (public_key string, private_key string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+func parseVAPIDKey(private_key string) (*ecdsa.PrivateKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(private_key)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key -> %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key -> %w", err)
+	}
+	// The uncompressed point is 0x04 || X || Y
+	point := key.PublicKey().Bytes()
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}, nil
+}
+
+// The applicationServerKey browsers must subscribe with
+func (p *WebPush) PublicKey() string {
+	return p.public_key
+}
+
+// Returns an error unless the keys of sub can be encrypted for
+func ValidateSubscription(sub Subscription) error {
+	if _, err := decodeKey(sub.P256dh, 65); err != nil {
+		return err
+	}
+	if _, err := decodeKey(sub.Auth, 16); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Browsers usually send unpadded base64url, but some libraries pad it
+func decodeKey(value string, length int) ([]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		raw, err = base64.URLEncoding.DecodeString(value)
+	}
+	if err != nil || len(raw) != length {
+		return nil, ErrBadKey
+	}
+	return raw, nil
+}
+
+// Encrypts payload for sub with the aes128gcm content encoding of RFC 8188,
+// with the key derivation of RFC 8291
+func Encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MAX_PAYLOAD {
+		return nil, ErrPayloadTooLarge
+	}
+	ua_raw, err := decodeKey(sub.P256dh, 65)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := decodeKey(sub.Auth, 16)
+	if err != nil {
+		return nil, err
+	}
+	ua_public, err := ecdh.P256().NewPublicKey(ua_raw)
+	if err != nil {
+		return nil, ErrBadKey
+	}
+
+	// Every message uses a new key pair and salt
+	as_private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	return encrypt(ua_public, auth, payload, as_private, salt)
+}
+
+func encrypt(ua_public *ecdh.PublicKey, auth []byte, payload []byte, as_private *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	as_public := as_private.PublicKey().Bytes()
+	ecdh_secret, err := as_private.ECDH(ua_public)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, nonce, err := deriveKeys(ecdh_secret, auth, salt, ua_public.Bytes(), as_public)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last and only record
+	ciphertext := gcm.Seal(nil, nonce, append(bytes.Clone(payload), 0x02), nil)
+
+	// Header: salt || record size || key id length || key id (the sender's public key)
+	body := make([]byte, 0, 16+4+1+len(as_public)+len(ciphertext))
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, RECORD_SIZE)
+	body = append(body, byte(len(as_public)))
+	body = append(body, as_public...)
+	return append(body, ciphertext...), nil
+}
+
+// Returns the content encryption key and nonce of RFC 8291 section 3.4
+func deriveKeys(ecdh_secret []byte, auth []byte, salt []byte, ua_public []byte, as_public []byte) (cek []byte, nonce []byte, err error) {
+	prk_key, err := hkdf.Extract(sha256.New, ecdh_secret, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	key_info := "WebPush: info\x00" + string(ua_public) + string(as_public)
+	ikm, err := hkdf.Expand(sha256.New, prk_key, key_info, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cek, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16); err != nil {
+		return nil, nil, err
+	}
+	nonce, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	return cek, nonce, err
+}
+
+// Decrypts a payload of Encrypt() with the browser's private key. Push services
+// can not do this, it is what browsers do with the notifications they receive
+func Decrypt(body []byte, ua_private *ecdh.PrivateKey, auth []byte) ([]byte, error) {
+	if len(body) < 21 || len(body) < 21+int(body[20]) {
+		return nil, errors.New("Encrypted payload is too short")
+	}
+	salt := body[:16]
+	id_length := int(body[20])
+	as_public := body[21 : 21+id_length]
+	ciphertext := body[21+id_length:]
+
+	key, err := ecdh.P256().NewPublicKey(as_public)
+	if err != nil {
+		return nil, err
+	}
+	ecdh_secret, err := ua_private.ECDH(key)
+	if err != nil {
+		return nil, err
+	}
+	cek, nonce, err := deriveKeys(ecdh_secret, auth, salt, ua_private.PublicKey().Bytes(), as_public)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Padding is zeros after the 0x02 delimiter
+	end := bytes.LastIndexByte(plaintext, 0x02)
+	if end < 0 {
+		return nil, errors.New("Encrypted payload has no delimiter")
+	}
+	return plaintext[:end], nil
+}
+
+// Returns the Authorization header for requests to endpoint: a JWT signed with
+// the VAPID key whose audience is the push service, and the public key
+func (p *WebPush) Authorization(endpoint string) (string, error) {
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, err := json.Marshal(map[string]any{
+		"aud": target.Scheme + "://" + target.Host,
+		"exp": time.Now().Add(VAPID_EXPIRY).Unix(),
+		"sub": p.opts.Subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, p.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// ES256 signatures are r || s, 32 bytes each
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", unsigned, base64.RawURLEncoding.EncodeToString(signature), p.public_key), nil
+}
+
+func (p *WebPush) Send(ctx context.Context, sub Subscription, payload []byte) error {
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+	authorization, err := p.Authorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(p.opts.TTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	switch {
+	case res.StatusCode == http.StatusGone || res.StatusCode == http.StatusNotFound:
+		return ErrGone
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return fmt.Errorf("Push service answered %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package push_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vl0000/gomessenger/push"
+	"github.com/vl0000/gomessenger/webhooks"
+)
+
+func TestPush(t *testing.T) {
+	// SETUP
+	public_key, private_key, err := push.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	browser_key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	subscription := func(endpoint string) push.Subscription {
+		return push.Subscription{
+			Endpoint: endpoint,
+			P256dh:   base64.RawURLEncoding.EncodeToString(browser_key.PublicKey().Bytes()),
+			Auth:     base64.RawURLEncoding.EncodeToString(auth),
+		}
+	}
+	sender := func(allow_private bool) *push.WebPush {
+		sender, err := push.NewWebPush(push.Options{
+			VAPIDPrivateKey:       private_key,
+			Subject:               "mailto:ops@example.com",
+			TTL:                   time.Hour,
+			Timeout:               time.Second,
+			AllowPrivateAddresses: allow_private,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sender
+	}
+	// END SETUP
+
+	t.Run("Payloads decrypt with the browser's key", func(t *testing.T) {
+		body, err := push.Encrypt(subscription("https://push.example.com/1"), []byte(`{"content": "Hi"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plaintext, err := push.Decrypt(body, browser_key, auth)
+		if err != nil || string(plaintext) != `{"content": "Hi"}` {
+			t.Fatalf("Unexpected plaintext %q, %v", plaintext, err)
+		}
+
+		other_key, _ := ecdh.P256().GenerateKey(rand.Reader)
+		if _, err = push.Decrypt(body, other_key, auth); err == nil {
+			t.Fatal("Payload decrypted with another key")
+		}
+		if _, err = push.Encrypt(subscription("https://push.example.com/1"), make([]byte, push.MAX_PAYLOAD+1)); !errors.Is(err, push.ErrPayloadTooLarge) {
+			t.Fatalf("Expected a large payload to be refused, got %v", err)
+		}
+	})
+
+	t.Run("Push service receives signed and encrypted requests", func(t *testing.T) {
+		// SETUP
+		received := make(chan []byte, 1)
+		var service *httptest.Server
+		service = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := verifyVAPID(r.Header.Get("Authorization"), public_key, service.URL); err != nil {
+				t.Error(err)
+			}
+			if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") != "3600" {
+				t.Errorf("Unexpected headers %v", r.Header)
+			}
+			body, _ := io.ReadAll(r.Body)
+			received <- body
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer service.Close()
+		// END SETUP
+
+		if err := sender(true).Send(context.TODO(), subscription(service.URL+"/send/1"), []byte("Hello")); err != nil {
+			t.Fatal(err)
+		}
+		plaintext, err := push.Decrypt(<-received, browser_key, auth)
+		if err != nil || string(plaintext) != "Hello" {
+			t.Fatalf("Unexpected plaintext %q, %v", plaintext, err)
+		}
+	})
+
+	t.Run("Expired subscriptions are reported", func(t *testing.T) {
+		// SETUP
+		service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		}))
+		defer service.Close()
+		// END SETUP
+
+		err := sender(true).Send(context.TODO(), subscription(service.URL+"/send/1"), []byte("Hello"))
+		if !errors.Is(err, push.ErrGone) {
+			t.Fatalf("Expected ErrGone, got %v", err)
+		}
+		err = sender(false).Send(context.TODO(), subscription(service.URL+"/send/1"), []byte("Hello"))
+		if !errors.Is(err, webhooks.ErrPrivateAddress) {
+			t.Fatalf("Expected private endpoints to be refused, got %v", err)
+		}
+	})
+}
+
+// Checks an Authorization header the way push services do
+func verifyVAPID(header string, public_key string, audience string) error {
+	token, key, ok := strings.Cut(strings.TrimPrefix(header, "vapid t="), ", k=")
+	if !ok || key != public_key {
+		return errors.New("Authorization does not have the VAPID key")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("VAPID token is not a JWT")
+	}
+
+	claims := map[string]any{}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return err
+	}
+	if claims["aud"] != audience || claims["sub"] != "mailto:ops@example.com" {
+		return errors.New("Unexpected VAPID claims")
+	}
+
+	point, _ := base64.RawURLEncoding.DecodeString(key)
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if len(point) != 65 || len(signature) != 64 {
+		return errors.New("VAPID key or signature has the wrong size")
+	}
+	verifier := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(point[1:33]),
+		Y:     new(big.Int).SetBytes(point[33:]),
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(verifier, digest[:], r, s) {
+		return errors.New("VAPID signature does not verify")
+	}
+	return nil
+}
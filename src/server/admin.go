@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"connectrpc.com/connect"
+	adminv1 "github.com/vl0000/gomessenger/gen/admin/v1"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/logging"
+)
+
+func (s *MessagingServer) BackupDatabase(
+	ctx context.Context,
+	req *connect.Request[adminv1.BackupDatabaseRequest],
+) (*connect.Response[adminv1.BackupDatabaseResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+
+	if s.BackupDir == "" {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, nil)
+	}
+
+	response, err := DoBackupDatabaseWork(s.Db, ctx, s.BackupDir, s.BackupKeep)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) SetLogLevel(
+	ctx context.Context,
+	req *connect.Request[adminv1.SetLogLevelRequest],
+) (*connect.Response[adminv1.SetLogLevelResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+
+	previous := logging.Level.Level()
+	if err := logging.SetLevel(req.Msg.Level); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	logging.FromContext(ctx).Warn("Log level changed", "from", previous.String(), "to", logging.Level.Level().String())
+
+	return connect.NewResponse(&adminv1.SetLogLevelResponse{
+		PreviousLevel: strings.ToLower(previous.String()),
+	}), nil
+}
+
+func (s *MessagingServer) ListUsers(
+	ctx context.Context,
+	req *connect.Request[adminv1.ListUsersRequest],
+) (*connect.Response[adminv1.ListUsersResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+
+	response, err := DoListUsersWork(s.Db, ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) GetUser(
+	ctx context.Context,
+	req *connect.Request[adminv1.GetUserRequest],
+) (*connect.Response[adminv1.GetUserResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if req.Msg.PhoneNumber == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, nil)
+	}
+
+	response, err := DoGetUserWork(s.Db, ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) SuspendUser(
+	ctx context.Context,
+	req *connect.Request[adminv1.SuspendUserRequest],
+) (*connect.Response[adminv1.SuspendUserResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if req.Msg.PhoneNumber == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, nil)
+	}
+
+	response, err := DoSuspendUserWork(s.Db, ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	if req.Msg.Suspended {
+		s.endSessions(req.Msg.PhoneNumber)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) DeleteUser(
+	ctx context.Context,
+	req *connect.Request[adminv1.DeleteUserRequest],
+) (*connect.Response[adminv1.DeleteUserResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if req.Msg.PhoneNumber == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, nil)
+	}
+
+	response, err := DoDeleteUserWork(s.Db, ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	s.endSessions(req.Msg.PhoneNumber)
+	for _, bot := range response.DeletedBots {
+		s.endSessions(bot)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+// Also removes the message from both participants' open streams
+func (s *MessagingServer) DeleteMessage(
+	ctx context.Context,
+	req *connect.Request[adminv1.DeleteMessageRequest],
+) (*connect.Response[adminv1.DeleteMessageResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+
+	message, err := DoDeleteMessageWork(s.Db, ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &messagingv1.GetDMsResponse{ExpiredIds: []uint64{message.GetId()}}
+	s.notifyStream(message.Sender, message.Receiver, res)
+	s.notifyStream(message.Receiver, message.Sender, res)
+
+	return connect.NewResponse(&adminv1.DeleteMessageResponse{}), nil
+}
+
+func (s *MessagingServer) ForceLogout(
+	ctx context.Context,
+	req *connect.Request[adminv1.ForceLogoutRequest],
+) (*connect.Response[adminv1.ForceLogoutResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if req.Msg.PhoneNumber == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, nil)
+	}
+
+	response, err := DoForceLogoutWork(s.Db, ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	s.endSessions(req.Msg.PhoneNumber)
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) GetStats(
+	ctx context.Context,
+	req *connect.Request[adminv1.GetStatsRequest],
+) (*connect.Response[adminv1.GetStatsResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAdminRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+
+	response, err := DoGetStatsWork(s.Db, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.ConnsMu.RLock()
+	response.ActiveStreams = int64(len(s.Conns))
+	s.ConnsMu.RUnlock()
+
+	return connect.NewResponse(response), nil
+}
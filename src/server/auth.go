@@ -1,19 +1,47 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-chi/jwtauth/v5"
+	"github.com/vl0000/gomessenger/data"
+	"github.com/vl0000/gomessenger/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const JWT_DURATION time.Duration = 480 * time.Hour
 
-func CheckUserExists(db *sql.DB, phone_number string) (bool, error) {
-	q, err := db.Query(`
-		SELECT * FROM users WHERE phone_number = ? LIMIT 1;`,
-		phone_number,
-	)
+// Values of the "role" column and JWT claim
+const (
+	ROLE_USER  string = "user"
+	ROLE_ADMIN string = "admin"
+	ROLE_BOT   string = "bot"
+)
+
+// API keys are "gmk_<id>_<secret>"
+const API_KEY_PREFIX string = "gmk_"
+
+func CheckUserExists(db *data.Store, ctx context.Context, phone_number string) (exists bool, err error) {
+	ctx, span := tracing.Start(ctx, "CheckUserExists", attribute.String("user.phone_number", phone_number))
+	defer func() { tracing.End(span, err) }()
+
+	stmt, err := db.Stmt(`
+		SELECT * FROM users WHERE phone_number = ? LIMIT 1;`)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := stmt.QueryContext(ctx, phone_number)
 	if err != nil {
 		return false, err
 	}
@@ -25,15 +53,72 @@ func CheckUserExists(db *sql.DB, phone_number string) (bool, error) {
 	return true, nil
 }
 
+var (
+	ErrUserNotFound  = errors.New("User not found")
+	ErrSuspended     = errors.New("User is suspended")
+	ErrTokenRevoked  = errors.New("Token was revoked")
+	ErrInvalidAPIKey = errors.New("API key is invalid or revoked")
+	ErrInvalidHook   = errors.New("Incoming webhook does not exist or its token is wrong")
+	ErrSessionEnded  = errors.New("Session was ended by an admin")
+)
+
+// Checks that the user a token was issued to may still use it. Returns
+// ErrUserNotFound, ErrSuspended or ErrTokenRevoked when they may not.
+func CheckSession(db *data.Store, ctx context.Context, phone_number string, issued_at time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "CheckSession", attribute.String("user.phone_number", phone_number))
+	defer func() { tracing.End(span, err) }()
+
+	stmt, err := db.Stmt(`
+		SELECT suspended, tokens_valid_after FROM users WHERE phone_number = ? LIMIT 1;`)
+	if err != nil {
+		return err
+	}
+
+	var suspended bool
+	var tokens_valid_after int64
+	err = stmt.QueryRowContext(ctx, phone_number).Scan(&suspended, &tokens_valid_after)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if suspended {
+		return ErrSuspended
+	}
+	if issued_at.Unix() < tokens_valid_after {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// Returns the role the user has now, which is what admin procedures trust
+// rather than the role claim of a token issued before a demotion
+func UserRole(db *data.Store, ctx context.Context, phone_number string) (string, error) {
+	stmt, err := db.Stmt(`SELECT role FROM users WHERE phone_number = ? LIMIT 1;`)
+	if err != nil {
+		return "", err
+	}
+
+	var role string
+	err = stmt.QueryRowContext(ctx, phone_number).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	return role, err
+}
+
 func GenJWTString(
 	token_auth *jwtauth.JWTAuth,
 	phone_number string,
 	username string,
+	role string,
 ) (string, error) {
 
 	_, jwt_str, err := token_auth.Encode(map[string]interface{}{
 		"username": username,
 		"sub":      phone_number,
+		"role":     role,
 		"iat":      time.Now().Unix(),
 		"exp":      time.Now().Add(JWT_DURATION).Unix(),
 	})
@@ -42,3 +127,99 @@ func GenJWTString(
 	}
 	return jwt_str, nil
 }
+
+func SetUserRole(db *data.Store, phone_number string, role string) error {
+	res, err := db.Exec(`UPDATE users SET role = ? WHERE phone_number = ?;`, role, phone_number)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("User %s not found", phone_number)
+	}
+	return nil
+}
+
+// Tells API keys apart from JWTs in the Authorization header
+func IsAPIKey(value string) bool {
+	return strings.HasPrefix(value, API_KEY_PREFIX)
+}
+
+// Returns a random id, a random secret and the hash of the secret that is
+// stored. The secrets have 256 bits, so a plain hash is enough to store them
+func newSecret() (id string, secret string, hash []byte) {
+	id_bytes := make([]byte, 8)
+	rand.Read(id_bytes)
+	secret_bytes := make([]byte, 32)
+	rand.Read(secret_bytes)
+
+	secret = base64.RawURLEncoding.EncodeToString(secret_bytes)
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(id_bytes), secret, sum[:]
+}
+
+func secretMatches(secret string, hash []byte) bool {
+	sum := sha256.Sum256([]byte(secret))
+	return subtle.ConstantTimeCompare(sum[:], hash) == 1
+}
+
+// Returns a new API key, its id and the hash of its secret
+func newAPIKey() (key string, id string, hash []byte) {
+	id, secret, hash := newSecret()
+	return API_KEY_PREFIX + id + "_" + secret, id, hash
+}
+
+// Returns the bot and the scopes of a key that is valid and not revoked
+func CheckAPIKey(db *data.Store, ctx context.Context, key string) (bot string, username string, scopes []string, err error) {
+	ctx, span := tracing.Start(ctx, "CheckAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	id, secret, ok := strings.Cut(strings.TrimPrefix(key, API_KEY_PREFIX), "_")
+	if !IsAPIKey(key) || !ok {
+		return "", "", nil, ErrInvalidAPIKey
+	}
+
+	stmt, err := db.Stmt(`SELECT k.bot, u.username, k.hash, k.scopes FROM api_keys k
+		JOIN users u ON u.phone_number = k.bot
+		WHERE k.id = ? AND k.revoked_at IS NULL;`)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	var hash []byte
+	var scope_list string
+	err = stmt.QueryRowContext(ctx, id).Scan(&bot, &username, &hash, &scope_list)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil, ErrInvalidAPIKey
+	} else if err != nil {
+		return "", "", nil, err
+	}
+
+	if !secretMatches(secret, hash) {
+		return "", "", nil, ErrInvalidAPIKey
+	}
+	return bot, username, strings.Split(scope_list, ","), nil
+}
+
+// Returns the conversation an incoming webhook posts to when token is its token
+func CheckIncomingWebhook(db *data.Store, ctx context.Context, id string, token string) (owner string, receiver string, err error) {
+	ctx, span := tracing.Start(ctx, "CheckIncomingWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	stmt, err := db.Stmt(`SELECT owner, receiver, hash FROM incoming_webhooks WHERE id = ?;`)
+	if err != nil {
+		return "", "", err
+	}
+
+	var hash []byte
+	err = stmt.QueryRowContext(ctx, id).Scan(&owner, &receiver, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", ErrInvalidHook
+	} else if err != nil {
+		return "", "", err
+	}
+
+	if !secretMatches(token, hash) {
+		return "", "", ErrInvalidHook
+	}
+	return owner, receiver, nil
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"connectrpc.com/connect"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/gen/messaging/v1/messagingv1connect"
+)
+
+// Scopes of API keys
+const (
+	SCOPE_MESSAGES_SEND string = "messages:send"
+	SCOPE_MESSAGES_READ string = "messages:read"
+	SCOPE_USERS_READ    string = "users:read"
+)
+
+var SCOPES = []string{SCOPE_MESSAGES_SEND, SCOPE_MESSAGES_READ, SCOPE_USERS_READ}
+
+// Procedures bots can call and the scope each needs. Every other procedure is
+// denied to API keys
+var PROCEDURE_SCOPES = map[string]string{
+	messagingv1connect.MessagingServiceSendDirectMessageProcedure: SCOPE_MESSAGES_SEND,
+	messagingv1connect.MessagingServiceGetDMsProcedure:            SCOPE_MESSAGES_READ,
+	messagingv1connect.MessagingServiceListDMsProcedure:           SCOPE_MESSAGES_READ,
+	messagingv1connect.MessagingServiceGetUserInfoProcedure:       SCOPE_USERS_READ,
+}
+
+// Bots are users whose phone number is "bot-<name>"
+const BOT_ID_PREFIX string = "bot-"
+
+const MAX_BOTS_PER_OWNER int = 20
+
+// Lifetime of the token an API key is exchanged for. It only has to outlive
+// the request it was made for
+const BOT_TOKEN_DURATION time.Duration = time.Minute
+
+// Checks an API key and returns a token of its bot, so the procedures'
+// validation treats bots like any other user
+func (s *MessagingServer) exchangeAPIKey(ctx context.Context, key string, scope string) (string, error) {
+	bot, username, scopes, err := CheckAPIKey(s.Db, ctx, key)
+	if err != nil {
+		return "", connect.NewError(connect.CodeUnauthenticated, err)
+	}
+	if scope == "" || !slices.Contains(scopes, scope) {
+		return "", connect.NewError(connect.CodePermissionDenied, errors.New("API key lacks the scope "+scope))
+	}
+
+	_, jwt_str, err := s.TokenAuth.Encode(map[string]interface{}{
+		"username": username,
+		"sub":      bot,
+		"role":     ROLE_BOT,
+		"iat":      time.Now().Unix(),
+		"exp":      time.Now().Add(BOT_TOKEN_DURATION).Unix(),
+	})
+	if err != nil {
+		return "", connect.NewError(connect.CodeInternal, err)
+	}
+	return jwt_str, nil
+}
+
+// Replaces API keys in the Authorization header with a token of their bot.
+// Runs before the logging interceptor so bots are logged like users
+type apiKeyInterceptor struct {
+	s *MessagingServer
+}
+
+func (i *apiKeyInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if key := req.Header().Get("Authorization"); IsAPIKey(key) {
+			jwt_str, err := i.s.exchangeAPIKey(ctx, key, PROCEDURE_SCOPES[req.Spec().Procedure])
+			if err != nil {
+				return nil, err
+			}
+			req.Header().Set("Authorization", jwt_str)
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *apiKeyInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *apiKeyInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if key := conn.RequestHeader().Get("Authorization"); IsAPIKey(key) {
+			jwt_str, err := i.s.exchangeAPIKey(ctx, key, PROCEDURE_SCOPES[conn.Spec().Procedure])
+			if err != nil {
+				return err
+			}
+			conn.RequestHeader().Set("Authorization", jwt_str)
+		}
+		return next(ctx, conn)
+	}
+}
+
+func (s *MessagingServer) CreateBot(
+	ctx context.Context,
+	req *connect.Request[messagingv1.CreateBotRequest],
+) (*connect.Response[messagingv1.CreateBotResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateCreateBotRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoCreateBotWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) RotateBotKey(
+	ctx context.Context,
+	req *connect.Request[messagingv1.RotateBotKeyRequest],
+) (*connect.Response[messagingv1.RotateBotKeyResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateRotateBotKeyRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoRotateBotKeyWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) ListBots(
+	ctx context.Context,
+	req *connect.Request[messagingv1.ListBotsRequest],
+) (*connect.Response[messagingv1.ListBotsResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoListBotsWork(s.Db, ctx, token.Subject())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) DeleteBot(
+	ctx context.Context,
+	req *connect.Request[messagingv1.DeleteBotRequest],
+) (*connect.Response[messagingv1.DeleteBotResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoDeleteBotWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
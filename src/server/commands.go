@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/vl0000/gomessenger/data"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/webhooks"
+)
+
+// Messages that start with COMMAND_PREFIX and the name of a built-in or of a
+// command of the receiving bot are commands. Other names are sent as text.
+// Starting a message with the prefix twice sends the rest of the text as a
+// message that starts with one
+const COMMAND_PREFIX string = "/"
+
+const SHRUG string = `¯\_(ツ)_/¯`
+
+// Commands run by the server in every chat
+var BUILTIN_COMMANDS = []*messagingv1.BotCommand{
+	{Name: "me", Description: "Describes what you are doing, e.g. /me waves"},
+	{Name: "shrug", Description: "Appends " + SHRUG + " to the text"},
+	{Name: "mute", Description: "Stops notifications from this chat, e.g. /mute 8h. Without a duration until /unmute"},
+	{Name: "unmute", Description: "Resumes notifications from this chat"},
+	{Name: "help", Description: "Lists the commands of this chat"},
+}
+
+var command_regex = regexp.MustCompile(`(?s)^/([a-z][a-z0-9_-]{0,31})(?:\s+(.*))?$`)
+
+type Command struct {
+	Name string
+	// Text after the name
+	Args string
+}
+
+// Parses "/<name> <args>". Text like "/etc/hosts" is not a command
+func ParseCommand(content string) (Command, bool) {
+	match := command_regex.FindStringSubmatch(content)
+	if match == nil {
+		return Command{}, false
+	}
+	return Command{Name: match[1], Args: strings.TrimSpace(match[2])}, true
+}
+
+func isBuiltinCommand(name string) bool {
+	return slices.ContainsFunc(BUILTIN_COMMANDS, func(command *messagingv1.BotCommand) bool {
+		return command.Name == name
+	})
+}
+
+// Returns true when bot registered the command name
+func IsBotCommand(db *data.Store, ctx context.Context, bot string, name string) (bool, error) {
+	var found bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM bot_commands WHERE bot = ? AND name = ?);`,
+		bot, name).Scan(&found)
+	return found, err
+}
+
+// Returns true while user muted the chat with other
+func isMuted(tx *sql.Tx, ctx context.Context, user string, other string) (bool, error) {
+	var muted bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM mutes WHERE user = ? AND muted = ?
+		AND (until IS NULL OR until > datetime('now')));`, user, other).Scan(&muted)
+	return muted, err
+}
+
+// Adds a command.invoked delivery for the owner of bot, if bot registered command
+func enqueueBotCommand(tx *sql.Tx, ctx context.Context, bot string, command string, subject string) error {
+	var owner string
+	err := tx.QueryRowContext(ctx, `SELECT u.bot_owner FROM bot_commands c
+		JOIN users u ON u.phone_number = c.bot WHERE c.bot = ? AND c.name = ?;`, bot, command).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return webhooks.Enqueue(ctx, tx, webhooks.EVENT_COMMAND_INVOKED, owner, subject)
+}
+
+// Runs built-in commands, and sends bot commands and every other message,
+// including unknown commands, to the receiver. msg must already be validated
+func (s *MessagingServer) handleMessage(
+	ctx context.Context,
+	msg *messagingv1.SendDirectMessageRequest,
+) (*messagingv1.SendDirectMessageResponse, error) {
+	exists, err := CheckUserExists(s.Db, ctx, msg.Message.Receiver)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	} else if !exists {
+		return nil, connect.NewError(connect.CodeNotFound, ErrUserNotFound)
+	}
+
+	content := msg.Message.Content
+	if strings.HasPrefix(content, COMMAND_PREFIX+COMMAND_PREFIX) {
+		return s.sendMessage(ctx, msg, strings.TrimPrefix(content, COMMAND_PREFIX), "")
+	}
+
+	command, ok := ParseCommand(content)
+	if !ok {
+		return s.sendMessage(ctx, msg, content, "")
+	}
+	if isBuiltinCommand(command.Name) {
+		return s.runBuiltinCommand(ctx, msg, command)
+	}
+
+	registered, err := IsBotCommand(s.Db, ctx, msg.Message.Receiver, command.Name)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	if !registered {
+		// Text like "/etc is full"
+		return s.sendMessage(ctx, msg, content, "")
+	}
+	return s.sendMessage(ctx, msg, content, command.Name)
+}
+
+// Stores the message with content and delivers it live. bot_command is the
+// command of the receiving bot it invokes, if any
+func (s *MessagingServer) sendMessage(
+	ctx context.Context,
+	msg *messagingv1.SendDirectMessageRequest,
+	content string,
+	bot_command string,
+) (*messagingv1.SendDirectMessageResponse, error) {
+	send := &messagingv1.SendDirectMessageRequest{
+		Message: &messagingv1.Message{
+			Sender:   msg.Message.Sender,
+			Receiver: msg.Message.Receiver,
+			Content:  content,
+		},
+		TtlSeconds: msg.TtlSeconds,
+	}
+
+	var message *messagingv1.Message
+	var err error
+	if bot_command != "" {
+		message, err = DoInvokeBotCommandWork(s.Db, ctx, send, bot_command)
+	} else {
+		message, err = DoSendDirectMessageWork(s.Db, ctx, send)
+	}
+	var connect_err *connect.Error
+	if errors.As(err, &connect_err) {
+		return nil, err
+	} else if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	s.deliverMessage(ctx, message)
+	return &messagingv1.SendDirectMessageResponse{Message: message}, nil
+}
+
+func (s *MessagingServer) runBuiltinCommand(
+	ctx context.Context,
+	msg *messagingv1.SendDirectMessageRequest,
+	command Command,
+) (*messagingv1.SendDirectMessageResponse, error) {
+	sender, receiver := msg.Message.Sender, msg.Message.Receiver
+
+	switch command.Name {
+	case "me":
+		if command.Args == "" {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("Usage: /me <action>"))
+		}
+		user, err := DoGetUserInfoWork(s.Db, ctx, &messagingv1.GetUserInfoRequest{PhoneNumber: sender})
+		if err != nil {
+			return nil, err
+		}
+		return s.sendMessage(ctx, msg, "* "+user.Username+" "+command.Args, "")
+
+	case "shrug":
+		return s.sendMessage(ctx, msg, strings.TrimSpace(command.Args+" "+SHRUG), "")
+
+	case "mute":
+		notice := "Muted this chat until you /unmute it"
+		var until *time.Time
+		if command.Args != "" {
+			duration, err := time.ParseDuration(command.Args)
+			if err != nil || duration <= 0 {
+				return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("Usage: /mute [duration, e.g. 30m or 8h]"))
+			}
+			end := time.Now().Add(duration)
+			until = &end
+			notice = "Muted this chat until " + end.UTC().Format(time.DateTime) + " UTC"
+		}
+		if err := DoMuteWork(s.Db, ctx, sender, receiver, until); err != nil {
+			return nil, connect.NewError(connect.CodeUnknown, err)
+		}
+		return &messagingv1.SendDirectMessageResponse{Notice: notice}, nil
+
+	case "unmute":
+		if err := DoUnmuteWork(s.Db, ctx, sender, receiver); err != nil {
+			return nil, connect.NewError(connect.CodeUnknown, err)
+		}
+		return &messagingv1.SendDirectMessageResponse{Notice: "Unmuted this chat"}, nil
+
+	case "help":
+		commands, err := DoListCommandsWork(s.Db, ctx, receiver)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeUnknown, err)
+		}
+		lines := []string{}
+		for _, command := range commands.Commands {
+			lines = append(lines, COMMAND_PREFIX+command.Name+" - "+command.Description)
+		}
+		return &messagingv1.SendDirectMessageResponse{Notice: strings.Join(lines, "\n")}, nil
+	}
+
+	return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("Unknown command /%s", command.Name))
+}
+
+func (s *MessagingServer) SetBotCommands(
+	ctx context.Context,
+	req *connect.Request[messagingv1.SetBotCommandsRequest],
+) (*connect.Response[messagingv1.SetBotCommandsResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSetBotCommandsRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoSetBotCommandsWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) ListCommands(
+	ctx context.Context,
+	req *connect.Request[messagingv1.ListCommandsRequest],
+) (*connect.Response[messagingv1.ListCommandsResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+
+	response, err := DoListCommandsWork(s.Db, ctx, req.Msg.UserB)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
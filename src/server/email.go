@@ -0,0 +1,359 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"connectrpc.com/connect"
+	"github.com/vl0000/gomessenger/email"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/logging"
+)
+
+// How long a verification token can be used
+const EMAIL_TOKEN_DURATION time.Duration = 24 * time.Hour
+
+// Verification emails to a user are sent at most this often, to any address
+const EMAIL_RESEND_DELAY time.Duration = time.Minute
+
+// Chats listed in a digest. Messages of the others are only counted
+const DIGEST_CHAT_LIMIT int = 10
+
+// Longer previews are cut
+const DIGEST_PREVIEW_LIMIT int = 200
+
+// Time between digests of each notification preference
+var DIGEST_FREQUENCIES = map[string]time.Duration{
+	"off":    0,
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+var ErrEmailDisabled = errors.New("Email is not configured on this server")
+
+// Sends verification emails and digests over the SMTP server of opts
+func (s *MessagingServer) SetupEmail(opts email.Options) error {
+	sender, err := email.NewSMTP(opts)
+	if err != nil {
+		return err
+	}
+	s.Email = sender
+	return nil
+}
+
+// Records that user just saw their messages, so digests leave out everything
+// they received until now
+func (s *MessagingServer) markActive(ctx context.Context, user string) {
+	_, err := s.Db.ExecContext(ctx, `UPDATE users SET last_active_at = datetime('now') WHERE phone_number = ?;`, user)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Could not record activity", "error", err)
+	}
+}
+
+func (s *MessagingServer) scheduleDigests() {
+	defer s.digests.Done()
+	ticker := time.NewTicker(s.DigestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		sent, err := s.SendDigests(context.Background())
+		if err != nil {
+			slog.Error("Could not send digests", "error", err)
+		}
+		if sent > 0 {
+			slog.Info("Digests sent", "count", sent)
+		}
+	}
+}
+
+type digestRecipient struct {
+	user        string
+	address     string
+	after_id    uint64
+	active_at   string
+	last_digest sql.NullString
+	frequency   time.Duration
+}
+
+// Emails every user with a verified address the messages they did not see
+// since their last digest, if their preference makes one due. Returns how
+// many digests were sent
+func (s *MessagingServer) SendDigests(ctx context.Context) (int, error) {
+	rows, err := s.Db.QueryContext(ctx, `SELECT phone_number, email, digest_message_id, COALESCE(last_active_at, ''),
+		last_digest_at, email_digest FROM users
+		WHERE email IS NOT NULL AND email_verified AND email_digest != 'off' AND suspended = 0;`)
+	if err != nil {
+		return 0, err
+	}
+	recipients := []digestRecipient{}
+	for rows.Next() {
+		var recipient digestRecipient
+		var preference string
+		err := rows.Scan(&recipient.user, &recipient.address, &recipient.after_id, &recipient.active_at,
+			&recipient.last_digest, &preference)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		recipient.frequency = DIGEST_FREQUENCIES[preference]
+		recipients = append(recipients, recipient)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, recipient := range recipients {
+		if s.stop != nil {
+			select {
+			case <-s.stop:
+				return sent, nil
+			default:
+			}
+		}
+		if recipient.frequency == 0 {
+			continue
+		}
+		if recipient.last_digest.Valid {
+			last, err := time.Parse(time.DateTime, recipient.last_digest.String)
+			if err == nil && time.Since(last) < recipient.frequency {
+				continue
+			}
+		}
+
+		ok, err := s.sendDigest(ctx, recipient)
+		if err != nil {
+			slog.Warn("Digest failed", "user", recipient.user, "error", err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+type digestChat struct {
+	sender    string
+	username  string
+	count     int
+	latest_id uint64
+}
+
+// Sends recipient a digest of their unread messages. Returns false when
+// there are none
+func (s *MessagingServer) sendDigest(ctx context.Context, recipient digestRecipient) (bool, error) {
+	// Muted chats are left out like they are from push notifications
+	rows, err := s.Db.QueryContext(ctx, `SELECT m.sender, u.username, COUNT(*), MAX(m.id) FROM messages m
+		JOIN users u ON u.phone_number = m.sender
+		WHERE m.receiver = ? AND m.id > ? AND m.timestamp > ?
+		AND (m.expires_at IS NULL OR m.expires_at > datetime('now'))
+		AND NOT EXISTS (SELECT 1 FROM mutes WHERE user = m.receiver AND muted = m.sender
+		AND (until IS NULL OR until > datetime('now')))
+		GROUP BY m.sender ORDER BY MAX(m.id) DESC;`, recipient.user, recipient.after_id, recipient.active_at)
+	if err != nil {
+		return false, err
+	}
+	chats := []digestChat{}
+	for rows.Next() {
+		var chat digestChat
+		if err := rows.Scan(&chat.sender, &chat.username, &chat.count, &chat.latest_id); err != nil {
+			rows.Close()
+			return false, err
+		}
+		chats = append(chats, chat)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+	if len(chats) == 0 {
+		return false, nil
+	}
+
+	total, latest_id := 0, uint64(0)
+	for _, chat := range chats {
+		total += chat.count
+		latest_id = max(latest_id, chat.latest_id)
+	}
+
+	body := &strings.Builder{}
+	fmt.Fprintf(body, "You have %s in %s.\n\n", plural(total, "unread message"), plural(len(chats), "chat"))
+	for i, chat := range chats {
+		if i == DIGEST_CHAT_LIMIT {
+			fmt.Fprintf(body, "...and %s more.\n\n", plural(len(chats)-i, "chat"))
+			break
+		}
+		fmt.Fprintf(body, "%s (%s): %d new\n", chat.username, chat.sender, chat.count)
+		if s.DigestPreviews {
+			preview, err := s.digestPreview(ctx, chat.latest_id, chat.sender, recipient.user)
+			if err != nil {
+				// The digest is still worth sending without the preview
+				slog.Warn("Digest preview failed", "user", recipient.user, "id", chat.latest_id, "error", err)
+			} else {
+				fmt.Fprintf(body, "  %s\n", preview)
+			}
+		}
+		body.WriteString("\n")
+	}
+	body.WriteString("You can change how often you get these emails in the notification settings of the app.\n")
+
+	err = s.Email.Send(ctx, email.Message{
+		To:      recipient.address,
+		Subject: "You have " + plural(total, "unread message"),
+		Body:    body.String(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.Db.ExecContext(ctx, `UPDATE users SET last_digest_at = datetime('now'), digest_message_id = ?
+		WHERE phone_number = ?;`, latest_id, recipient.user)
+	return true, err
+}
+
+// Content of a message on one line, cut to DIGEST_PREVIEW_LIMIT
+func (s *MessagingServer) digestPreview(ctx context.Context, id uint64, sender string, receiver string) (string, error) {
+	var content string
+	var encrypted bool
+	err := s.Db.QueryRowContext(ctx, `SELECT content, encrypted FROM messages WHERE id = ?;`, id).Scan(&content, &encrypted)
+	if err != nil {
+		return "", err
+	}
+	content, err = s.Db.DecryptContent(ctx, sender, receiver, content, encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	content = strings.Join(strings.Fields(content), " ")
+	if len(content) > DIGEST_PREVIEW_LIMIT {
+		content = content[:DIGEST_PREVIEW_LIMIT]
+		for !utf8.ValidString(content) {
+			content = content[:len(content)-1]
+		}
+		content += "..."
+	}
+	return content, nil
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func (s *MessagingServer) SetEmail(
+	ctx context.Context,
+	req *connect.Request[messagingv1.SetEmailRequest],
+) (*connect.Response[messagingv1.SetEmailResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSetEmailRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	verification, err := DoSetEmailWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	if verification != "" {
+		err = s.Email.Send(ctx, email.Message{
+			To:      req.Msg.Email,
+			Subject: "Verify your email address",
+			Body: "Enter this token in the app to receive notifications at this address:\n\n" +
+				verification + "\n\nIt expires in " + EMAIL_TOKEN_DURATION.String() +
+				". If you did not ask for this email, you can ignore it.\n",
+		})
+		if err != nil {
+			logging.FromContext(ctx).Warn("Could not send a verification email", "error", err)
+			return nil, connect.NewError(connect.CodeUnavailable, errors.New("Could not send the verification email"))
+		}
+	}
+
+	return connect.NewResponse(&messagingv1.SetEmailResponse{}), nil
+}
+
+func (s *MessagingServer) VerifyEmail(
+	ctx context.Context,
+	req *connect.Request[messagingv1.VerifyEmailRequest],
+) (*connect.Response[messagingv1.VerifyEmailResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoVerifyEmailWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) GetNotificationPreferences(
+	ctx context.Context,
+	req *connect.Request[messagingv1.GetNotificationPreferencesRequest],
+) (*connect.Response[messagingv1.GetNotificationPreferencesResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoGetNotificationPreferencesWork(s.Db, ctx, token.Subject())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) SetNotificationPreferences(
+	ctx context.Context,
+	req *connect.Request[messagingv1.SetNotificationPreferencesRequest],
+) (*connect.Response[messagingv1.SetNotificationPreferencesResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSetNotificationPreferencesRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoSetNotificationPreferencesWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
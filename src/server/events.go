@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/logging"
+	"github.com/vl0000/gomessenger/metrics"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// How many missed messages a resumed /events stream replays
+const EVENTS_RESUME_LIMIT int = 1000
+
+// Label of /events streams in the active streams metric
+const EVENTS_METRICS_LABEL string = "/events"
+
+// Streams the messages the caller receives as Server-Sent Events. Event ids are
+// message ids, so clients that reconnect with Last-Event-ID get what they missed
+func (s *MessagingServer) ServeEvents(w http.ResponseWriter, r *http.Request) {
+	token, err := s.validateStreamRequest(r.Context(), r, SCOPE_MESSAGES_READ)
+	if err != nil {
+		http.Error(w, "Unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var last_id uint64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		if last_id, err = strconv.ParseUint(header, 10, 64); err != nil {
+			http.Error(w, "Last-Event-ID must be a message id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Registered before the missed messages are read so none are lost in between
+	feed := s.addFeed(token.Subject())
+	defer s.removeFeed(token.Subject(), feed)
+
+	missed, err := DoGetIncomingMessagesWork(s.Db, r.Context(), token.Subject(), last_id, EVENTS_RESUME_LIMIT)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Could not read missed messages", "error", err)
+		http.Error(w, "Could not read missed messages", http.StatusInternalServerError)
+		return
+	}
+
+	s.markActive(r.Context(), token.Subject())
+
+	metrics.ActiveStreams.WithLabelValues(EVENTS_METRICS_LABEL).Inc()
+	defer metrics.ActiveStreams.WithLabelValues(EVENTS_METRICS_LABEL).Dec()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher := http.NewResponseController(w)
+
+	fmt.Fprintf(w, "retry: %d\n\n", RECONNECT_DELAY.Milliseconds())
+	for _, message := range missed {
+		if err = writeEvent(w, message); err != nil {
+			return
+		}
+		last_id = message.GetId()
+	}
+	if err = flusher.Flush(); err != nil {
+		return
+	}
+
+	var heartbeat <-chan time.Time
+	if s.HeartbeatInterval > 0 {
+		ticker := time.NewTicker(s.HeartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	// The request timeout must not end the stream, but a client that
+	// disconnects before it fires still does
+	done := r.Context().Done()
+	ended := s.sessionEnded(token.Subject())
+	for {
+		select {
+		case message, ok := <-feed:
+			if !ok {
+				// The feed overflowed, the client resumes from last_id
+				return
+			}
+			// Already sent with the missed messages
+			if message.GetId() <= last_id {
+				continue
+			}
+			if err = writeEvent(w, message); err != nil {
+				return
+			}
+			last_id = message.GetId()
+		case <-heartbeat:
+			if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-done:
+			if !errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+				return
+			}
+			done = nil
+			continue
+		case <-ended:
+			return
+		case <-s.stop:
+			return
+		}
+		if err = flusher.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, message *messagingv1.Message) error {
+	payload, err := protojson.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", message.GetId(), payload)
+	return err
+}
+
+func (s *MessagingServer) addFeed(user string) chan *messagingv1.Message {
+	feed := make(chan *messagingv1.Message, CHANNEL_SIZE)
+	s.feedsMu.Lock()
+	defer s.feedsMu.Unlock()
+	if s.feeds[user] == nil {
+		s.feeds[user] = map[chan *messagingv1.Message]struct{}{}
+	}
+	s.feeds[user][feed] = struct{}{}
+	return feed
+}
+
+// Closes feed unless notifyFeeds() already did
+func (s *MessagingServer) removeFeed(user string, feed chan *messagingv1.Message) {
+	s.feedsMu.Lock()
+	defer s.feedsMu.Unlock()
+	if _, ok := s.feeds[user][feed]; !ok {
+		return
+	}
+	delete(s.feeds[user], feed)
+	if len(s.feeds[user]) == 0 {
+		delete(s.feeds, user)
+	}
+	close(feed)
+}
+
+// Sends message to every /events stream of receiver. A full feed is closed
+// instead of dropping the message, and its client resumes with Last-Event-ID
+func (s *MessagingServer) notifyFeeds(receiver string, message *messagingv1.Message) {
+	s.feedsMu.Lock()
+	defer s.feedsMu.Unlock()
+
+	for feed := range s.feeds[receiver] {
+		select {
+		case feed <- message:
+		default:
+			slog.Warn("Event feed is full, closing it", "user", receiver)
+			delete(s.feeds[receiver], feed)
+			close(feed)
+		}
+	}
+	if len(s.feeds[receiver]) == 0 {
+		delete(s.feeds, receiver)
+	}
+}
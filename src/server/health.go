@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/vl0000/gomessenger/gen/admin/v1/adminv1connect"
+	"github.com/vl0000/gomessenger/gen/messaging/v1/messagingv1connect"
+	"github.com/vl0000/gomessenger/logging"
+)
+
+// Maximum time a readiness check may spend pinging the database
+const READY_TIMEOUT time.Duration = 2 * time.Second
+
+var ErrDraining = errors.New("Server is shutting down")
+
+// Returns nil when the server can handle requests. It stops being ready as
+// soon as Shutdown() is called, before the database is closed.
+func (s *MessagingServer) Ready(ctx context.Context) error {
+	if s.draining.Load() {
+		return ErrDraining
+	}
+	ctx, cancel := context.WithTimeout(ctx, READY_TIMEOUT)
+	defer cancel()
+	if err := s.Db.PingContext(ctx); err != nil {
+		return fmt.Errorf("DB unreachable -> %s", err)
+	}
+	return nil
+}
+
+// Liveness only tells that the process answers, restarting it would not fix a
+// database that is unreachable
+func (s *MessagingServer) ServeHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok\n"))
+}
+
+func (s *MessagingServer) ServeReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if err := s.Ready(r.Context()); err != nil {
+		logging.FromContext(r.Context()).Warn("Not ready", "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// Routes of the listener on HealthAddr
+func (s *MessagingServer) healthHandler() http.Handler {
+	router := chi.NewRouter()
+	router.Get("/healthz", s.ServeHealthz)
+	router.Get("/readyz", s.ServeReadyz)
+	return router
+}
+
+func (s *MessagingServer) serveHealth() {
+	slog.Info("Serving health probes", "address", s.HealthAddr)
+	if err := s.healthServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Health probes stopped", "error", err)
+	}
+}
+
+// Implements grpc.health.v1.Health. Every service shares the server's readiness
+type healthChecker struct {
+	s *MessagingServer
+}
+
+func (c *healthChecker) Check(ctx context.Context, req *grpchealth.CheckRequest) (*grpchealth.CheckResponse, error) {
+	switch req.Service {
+	case "", messagingv1connect.MessagingServiceName, adminv1connect.AdminServiceName:
+	default:
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("Unknown service %s", req.Service))
+	}
+
+	if err := c.s.Ready(ctx); err != nil {
+		return &grpchealth.CheckResponse{Status: grpchealth.StatusNotServing}, nil
+	}
+	return &grpchealth.CheckResponse{Status: grpchealth.StatusServing}, nil
+}
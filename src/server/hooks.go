@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-chi/chi/v5"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/logging"
+)
+
+// Incoming webhooks are served on INCOMING_WEBHOOK_PATH + "<id>/<token>"
+const INCOMING_WEBHOOK_PATH string = "/hooks/"
+
+// Largest body an incoming webhook accepts
+const INCOMING_WEBHOOK_MAX_BODY int64 = 64 << 10
+
+// Body of incoming webhook requests. It is the shape Slack accepts, so tools
+// that can post to Slack can post here too
+type incomingWebhookPayload struct {
+	Text string `json:"text"`
+}
+
+// Posts the text of the request as a message of the webhook's owner. Answers
+// like Slack does, with "ok" or a short error in plain text
+func (s *MessagingServer) ServeIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	owner, receiver, err := CheckIncomingWebhook(s.Db, r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "token"))
+	if errors.Is(err, ErrInvalidHook) {
+		http.Error(w, "invalid_token", http.StatusNotFound)
+		return
+	} else if err != nil {
+		logging.FromContext(r.Context()).Error("Could not check an incoming webhook", "error", err)
+		http.Error(w, "internal_error", http.StatusInternalServerError)
+		return
+	}
+	// The token does not expire, so only suspension stops it
+	if err = CheckSession(s.Db, r.Context(), owner, time.Now()); err != nil {
+		http.Error(w, "account_inactive", http.StatusForbidden)
+		return
+	}
+
+	payload, err := readIncomingWebhookPayload(w, r)
+	if err != nil {
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+		return
+	}
+	if payload.Text == "" {
+		http.Error(w, "no_text", http.StatusBadRequest)
+		return
+	}
+
+	ctx := logging.Annotate(r.Context(), logging.PROCEDURE_KEY, INCOMING_WEBHOOK_PATH, logging.USER_KEY, owner)
+	message, err := DoSendDirectMessageWork(s.Db, ctx, &messagingv1.SendDirectMessageRequest{
+		Message: &messagingv1.Message{Sender: owner, Receiver: receiver, Content: payload.Text},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Could not post an incoming webhook's message", "error", err)
+		http.Error(w, "internal_error", http.StatusInternalServerError)
+		return
+	}
+	s.deliverMessage(ctx, message)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok")
+}
+
+// Reads JSON bodies, and form bodies with the JSON in the payload field
+func readIncomingWebhookPayload(w http.ResponseWriter, r *http.Request) (*incomingWebhookPayload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, INCOMING_WEBHOOK_MAX_BODY)
+	payload := &incomingWebhookPayload{}
+
+	media_type, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if media_type == "application/x-www-form-urlencoded" {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		return payload, json.Unmarshal([]byte(r.PostForm.Get("payload")), payload)
+	}
+	return payload, json.NewDecoder(r.Body).Decode(payload)
+}
+
+func (s *MessagingServer) CreateIncomingWebhook(
+	ctx context.Context,
+	req *connect.Request[messagingv1.CreateIncomingWebhookRequest],
+) (*connect.Response[messagingv1.CreateIncomingWebhookResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateCreateIncomingWebhookRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoCreateIncomingWebhookWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) ListIncomingWebhooks(
+	ctx context.Context,
+	req *connect.Request[messagingv1.ListIncomingWebhooksRequest],
+) (*connect.Response[messagingv1.ListIncomingWebhooksResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoListIncomingWebhooksWork(s.Db, ctx, token.Subject())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) DeleteIncomingWebhook(
+	ctx context.Context,
+	req *connect.Request[messagingv1.DeleteIncomingWebhookRequest],
+) (*connect.Response[messagingv1.DeleteIncomingWebhookResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoDeleteIncomingWebhookWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
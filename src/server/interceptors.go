@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/vl0000/gomessenger/logging"
+	"github.com/vl0000/gomessenger/metrics"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Adds the procedure and the caller's phone number to the request's logger
+// and logs every failed procedure
+type loggingInterceptor struct {
+	s *MessagingServer
+}
+
+// The token is only read for logging, validateXRequest methods still check it
+func (i *loggingInterceptor) annotate(ctx context.Context, procedure string, header http.Header) context.Context {
+	user := ""
+	if token, err := i.s.TokenAuth.Decode(header.Get("Authorization")); err == nil {
+		user = token.Subject()
+	}
+	args := []any{logging.PROCEDURE_KEY, procedure, logging.USER_KEY, user}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		args = append(args, logging.TRACE_ID_KEY, span.TraceID().String())
+	}
+	return logging.Annotate(ctx, args...)
+}
+
+func (i *loggingInterceptor) logResult(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	logger := logging.FromContext(ctx)
+	switch connect.CodeOf(err) {
+	case connect.CodeUnknown, connect.CodeInternal, connect.CodeDataLoss:
+		logger.Error("procedure failed", "code", connect.CodeOf(err).String(), "error", err)
+	default:
+		logger.Warn("procedure failed", "code", connect.CodeOf(err).String(), "error", err)
+	}
+}
+
+func (i *loggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx = i.annotate(ctx, req.Spec().Procedure, req.Header())
+		res, err := next(ctx, req)
+		i.logResult(ctx, err)
+		return res, err
+	}
+}
+
+func (i *loggingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *loggingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx = i.annotate(ctx, conn.Spec().Procedure, conn.RequestHeader())
+		logging.FromContext(ctx).Debug("stream opened")
+		err := next(ctx, conn)
+		i.logResult(ctx, err)
+		logging.FromContext(ctx).Debug("stream closed")
+		return err
+	}
+}
+
+// Counts RPCs by procedure and code, times unary RPCs and tracks open streams
+type metricsInterceptor struct{}
+
+func codeLabel(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return connect.CodeOf(err).String()
+}
+
+func (i *metricsInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		start := time.Now()
+		res, err := next(ctx, req)
+		metrics.RequestDuration.WithLabelValues(req.Spec().Procedure).Observe(time.Since(start).Seconds())
+		metrics.RequestsTotal.WithLabelValues(req.Spec().Procedure, codeLabel(err)).Inc()
+		return res, err
+	}
+}
+
+func (i *metricsInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *metricsInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		procedure := conn.Spec().Procedure
+		metrics.ActiveStreams.WithLabelValues(procedure).Inc()
+		defer metrics.ActiveStreams.WithLabelValues(procedure).Dec()
+
+		err := next(ctx, conn)
+		metrics.RequestsTotal.WithLabelValues(procedure, codeLabel(err)).Inc()
+		return err
+	}
+}
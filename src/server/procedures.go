@@ -5,13 +5,25 @@ import (
 	"crypto/pbkdf2"
 	"crypto/rand"
 	"crypto/sha512"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"connectrpc.com/connect"
 	"github.com/go-chi/jwtauth/v5"
+	"github.com/vl0000/gomessenger/data"
+	adminv1 "github.com/vl0000/gomessenger/gen/admin/v1"
 	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/logging"
+	"github.com/vl0000/gomessenger/metrics"
+	"github.com/vl0000/gomessenger/tracing"
+	"github.com/vl0000/gomessenger/webhooks"
 )
 
 const (
@@ -20,11 +32,13 @@ const (
 )
 
 func DoRegisterUserWork(
-	db *sql.DB,
+	db *data.Store,
 	token_auth *jwtauth.JWTAuth,
 	ctx context.Context,
 	msg *messagingv1.RegisterUserRequest,
 ) (*messagingv1.RegisterUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoRegisterUserWork")
+	defer span.End()
 
 	salt := make([]byte, 24)
 	rand.Read(salt)
@@ -34,7 +48,13 @@ func DoRegisterUserWork(
 		return nil, err
 	}
 
-	_, err = db.Exec(`INSERT INTO users (
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO users (
 		username,phone_number, password, salt)
 		VALUES(?, ?, ?, ?);`,
 		msg.Username,
@@ -46,27 +66,41 @@ func DoRegisterUserWork(
 		return nil, err
 	}
 
-	jwt_str, err := GenJWTString(token_auth, msg.PhoneNumber, msg.Username)
+	if err = webhooks.Enqueue(ctx, tx, webhooks.EVENT_USER_REGISTERED, "", msg.PhoneNumber); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	jwt_str, err := GenJWTString(token_auth, msg.PhoneNumber, msg.Username, ROLE_USER)
 	if err != nil {
 		return nil, err
 	}
 
+	logging.FromContext(ctx).Info("User registered", "phone_number", msg.PhoneNumber)
+
 	return &messagingv1.RegisterUserResponse{
 		JwtToken: jwt_str,
 	}, nil
 }
 
 func DoLoginWork(
-	db *sql.DB,
+	db *data.Store,
 	token_auth *jwtauth.JWTAuth,
 	ctx context.Context,
 	msg *messagingv1.LoginRequest,
 ) (*messagingv1.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoLoginWork")
+	defer span.End()
 
-	q, err := db.Query(`
-		SELECT * FROM users WHERE phone_number = ? LIMIT 1;`,
-		msg.PhoneNumber,
-	)
+	stmt, err := db.Stmt(`
+		SELECT phone_number, username, password, salt, role, suspended FROM users WHERE phone_number = ? LIMIT 1;`)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := stmt.QueryContext(ctx, msg.PhoneNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -74,14 +108,27 @@ func DoLoginWork(
 	defer q.Close()
 
 	if q.Next() {
-		var stored_password, phone_number, username, salt string
+		var stored_password, phone_number, username, salt, role string
+		var suspended bool
 
-		q.Scan(&phone_number, &username, &stored_password, &salt)
+		if err = q.Scan(&phone_number, &username, &stored_password, &salt, &role, &suspended); err != nil {
+			return nil, err
+		}
 		hashed_password, err := pbkdf2.Key(sha512.New, msg.Password, []byte(salt), PBKDF_ITER, PBKDF_KEY_LEN)
 
-		if err == nil || string(hashed_password) == stored_password {
+		// The account's state is only revealed to callers who know its password
+		if err == nil && subtle.ConstantTimeCompare(hashed_password, []byte(stored_password)) == 1 {
+			// Bots have no password and authenticate with API keys
+			if role == ROLE_BOT {
+				logging.FromContext(ctx).Warn("Login of a bot", "phone_number", msg.PhoneNumber)
+				return nil, connect.NewError(connect.CodePermissionDenied, errors.New("Bots authenticate with API keys"))
+			}
+			if suspended {
+				logging.FromContext(ctx).Warn("Login of a suspended user", "phone_number", msg.PhoneNumber)
+				return nil, connect.NewError(connect.CodePermissionDenied, ErrSuspended)
+			}
 
-			jwt_str, err := GenJWTString(token_auth, phone_number, username)
+			jwt_str, err := GenJWTString(token_auth, phone_number, username, role)
 			if err != nil {
 				return nil, err
 			}
@@ -93,45 +140,139 @@ func DoLoginWork(
 		}
 	}
 
+	metrics.LoginFailures.Inc()
+	logging.FromContext(ctx).Warn("Login failed", "phone_number", msg.PhoneNumber)
 	return nil, errors.New("User not found")
 }
 
 func DoSendDirectMessageWork(
-	db *sql.DB,
+	db *data.Store,
+	ctx context.Context,
+	msg *messagingv1.SendDirectMessageRequest,
+) (*messagingv1.Message, error) {
+	ctx, span := tracing.Start(ctx, "DoSendDirectMessageWork")
+	defer span.End()
+
+	return storeMessage(db, ctx, msg, "")
+}
+
+// Sends a message that invokes a command of the receiving bot, and tells the
+// bot's owner about it with a command.invoked webhook
+func DoInvokeBotCommandWork(
+	db *data.Store,
 	ctx context.Context,
 	msg *messagingv1.SendDirectMessageRequest,
+	command string,
 ) (*messagingv1.Message, error) {
+	ctx, span := tracing.Start(ctx, "DoInvokeBotCommandWork")
+	defer span.End()
+
+	return storeMessage(db, ctx, msg, command)
+}
 
+// Stores a message with the webhook events it causes. bot_command is the
+// command of the receiving bot the message invokes, if any
+func storeMessage(
+	db *data.Store,
+	ctx context.Context,
+	msg *messagingv1.SendDirectMessageRequest,
+	bot_command string,
+) (*messagingv1.Message, error) {
 	timestamp := time.Now().Format(time.DateTime)
-	_, err := db.Exec(`INSERT INTO messages (sender, receiver, content, timestamp) VALUES
-		(?, ?, ?, datetime('now'));
-		`, msg.Message.Sender, msg.Message.Receiver, msg.Message.Content, timestamp)
 
+	// Expiry is compared against datetime('now'), which is in UTC
+	var expires_at *string
+	if msg.TtlSeconds != nil {
+		expiry := time.Now().UTC().Add(time.Duration(*msg.TtlSeconds) * time.Second).Format(time.DateTime)
+		expires_at = &expiry
+	}
+
+	content, encrypted, err := db.EncryptContent(ctx, msg.Message.Sender, msg.Message.Receiver, msg.Message.Content)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	stmt, err := db.Stmt(`INSERT INTO messages (sender, receiver, content, encrypted, timestamp, expires_at) VALUES
+		(?, ?, ?, ?, datetime('now'), ?);
+		`)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.StmtContext(ctx, stmt).ExecContext(ctx, msg.Message.Sender, msg.Message.Receiver, content, encrypted, expires_at)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	message_id := uint64(id)
+
+	subject := strconv.FormatUint(message_id, 10)
+	if err = webhooks.Enqueue(ctx, tx, webhooks.EVENT_MESSAGE_SENT, msg.Message.Sender, subject); err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	muted, err := isMuted(tx, ctx, msg.Message.Receiver, msg.Message.Sender)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	if !muted {
+		if err = webhooks.Enqueue(ctx, tx, webhooks.EVENT_MESSAGE_RECEIVED, msg.Message.Receiver, subject); err != nil {
+			return nil, connect.NewError(connect.CodeUnknown, err)
+		}
+	}
+	if bot_command != "" {
+		if err = enqueueBotCommand(tx, ctx, msg.Message.Receiver, bot_command, subject); err != nil {
+			return nil, connect.NewError(connect.CodeUnknown, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	metrics.MessagesSent.Inc()
+	logging.FromContext(ctx).Debug("Message stored", "id", message_id, "receiver", msg.Message.Receiver)
+
 	a := messagingv1.Message{
+		Id:        &message_id,
 		Sender:    msg.Message.Sender,
 		Receiver:  msg.Message.Receiver,
 		Content:   msg.Message.Content,
 		Timestamp: &timestamp,
+		ExpiresAt: expires_at,
 	}
 
 	return &a, nil
 }
 
 func DoGetDMsWork(
-	db *sql.DB,
+	db *data.Store,
 	ctx context.Context,
 	msg *messagingv1.GetDMsRequest,
 ) (*messagingv1.GetDMsResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoGetDMsWork")
+	defer span.End()
+
 	res := &messagingv1.GetDMsResponse{}
 
-	rows, err := db.Query(`SELECT * FROM messages WHERE
+	// Expired messages are hidden here even if the purge has not removed them yet
+	stmt, err := db.Stmt(`SELECT id, sender, receiver, content, encrypted, timestamp, expires_at FROM messages WHERE
 			sender IN (?, ?) AND receiver IN (?, ?) AND
-			timestamp BETWEEN ? AND datetime('now')
-			;`, msg.UserA, msg.UserB, msg.UserB, msg.UserA, msg.FromDate)
+			timestamp BETWEEN ? AND datetime('now') AND
+			(expires_at IS NULL OR expires_at > datetime('now'))
+			;`)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
 
+	rows, err := stmt.QueryContext(ctx, msg.UserA, msg.UserB, msg.UserB, msg.UserA, msg.FromDate)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnknown, err)
 	}
@@ -141,35 +282,150 @@ func DoGetDMsWork(
 	for rows.Next() {
 		var id uint64
 		var sender, receiver, content, timestamp string
-		err := rows.Scan(&id, &sender, &receiver, &content, &timestamp)
+		var encrypted bool
+		var expires_at sql.NullString
+		err := rows.Scan(&id, &sender, &receiver, &content, &encrypted, &timestamp, &expires_at)
 
 		if err != nil {
 			return res, connect.NewError(connect.CodeUnknown, err)
 		}
 
-		res.Messages = append(
-			res.Messages,
-			&messagingv1.Message{
-				Id:        &id,
-				Sender:    sender,
-				Receiver:  receiver,
-				Content:   content,
-				Timestamp: &timestamp,
-			})
+		// One unreadable message must not hide the rest of the chat
+		content, err = db.DecryptContent(ctx, sender, receiver, content, encrypted)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Skipping message that can not be decrypted", "id", id, "error", err)
+			continue
+		}
+
+		message := &messagingv1.Message{
+			Id:        &id,
+			Sender:    sender,
+			Receiver:  receiver,
+			Content:   content,
+			Timestamp: &timestamp,
+		}
+		if expires_at.Valid {
+			message.ExpiresAt = &expires_at.String
+		}
+
+		res.Messages = append(res.Messages, message)
 	}
+
+	logging.FromContext(ctx).Debug("Messages retrieved", "count", len(res.Messages))
 	return res, nil
 }
 
+// Returns up to limit messages received by receiver with an id above after_id,
+// oldest first. Used to resume /events streams from Last-Event-ID
+func DoGetIncomingMessagesWork(
+	db *data.Store,
+	ctx context.Context,
+	receiver string,
+	after_id uint64,
+	limit int,
+) ([]*messagingv1.Message, error) {
+	ctx, span := tracing.Start(ctx, "DoGetIncomingMessagesWork")
+	defer span.End()
+
+	stmt, err := db.Stmt(`SELECT id, sender, receiver, content, encrypted, timestamp, expires_at FROM messages WHERE
+			receiver = ? AND id > ? AND
+			(expires_at IS NULL OR expires_at > datetime('now'))
+			ORDER BY id LIMIT ?;`)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	rows, err := stmt.QueryContext(ctx, receiver, after_id, limit)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	defer rows.Close()
+
+	messages := []*messagingv1.Message{}
+	for rows.Next() {
+		var id uint64
+		var sender, receiver, content, timestamp string
+		var encrypted bool
+		var expires_at sql.NullString
+		if err := rows.Scan(&id, &sender, &receiver, &content, &encrypted, &timestamp, &expires_at); err != nil {
+			return messages, connect.NewError(connect.CodeUnknown, err)
+		}
+
+		content, err = db.DecryptContent(ctx, sender, receiver, content, encrypted)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Skipping message that can not be decrypted", "id", id, "error", err)
+			continue
+		}
+
+		message := &messagingv1.Message{
+			Id:        &id,
+			Sender:    sender,
+			Receiver:  receiver,
+			Content:   content,
+			Timestamp: &timestamp,
+		}
+		if expires_at.Valid {
+			message.ExpiresAt = &expires_at.String
+		}
+		messages = append(messages, message)
+	}
+
+	logging.FromContext(ctx).Debug("Missed messages retrieved", "count", len(messages))
+	return messages, rows.Err()
+}
+
+// Deletes every expired message and returns them so that open streams can be notified
+func DoPurgeExpiredMessagesWork(
+	db *data.Store,
+	ctx context.Context,
+) ([]*messagingv1.Message, error) {
+	ctx, span := tracing.Start(ctx, "DoPurgeExpiredMessagesWork")
+	defer span.End()
+
+	rows, err := db.QueryContext(ctx, `DELETE FROM messages WHERE
+		expires_at IS NOT NULL AND expires_at <= datetime('now')
+		RETURNING id, sender, receiver;`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var expired []*messagingv1.Message
+	for rows.Next() {
+		var id uint64
+		var sender, receiver string
+		if err := rows.Scan(&id, &sender, &receiver); err != nil {
+			return expired, err
+		}
+		expired = append(expired, &messagingv1.Message{
+			Id:       &id,
+			Sender:   sender,
+			Receiver: receiver,
+		})
+	}
+
+	if len(expired) > 0 {
+		logging.FromContext(ctx).Info("Expired messages purged", "count", len(expired))
+	}
+	return expired, rows.Err()
+}
+
 func DoGetUserInfoWork(
-	db *sql.DB,
+	db *data.Store,
 	ctx context.Context,
 	req *messagingv1.GetUserInfoRequest,
 ) (*messagingv1.GetUserInfoResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoGetUserInfoWork")
+	defer span.End()
 
-	q, err := db.Query(`
-		SELECT * FROM users WHERE phone_number = ? LIMIT 1;`,
-		req.PhoneNumber,
-	)
+	stmt, err := db.Stmt(`
+		SELECT phone_number, username, password, salt FROM users WHERE phone_number = ? LIMIT 1;`)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := stmt.QueryContext(ctx, req.PhoneNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -194,3 +450,988 @@ func DoGetUserInfoWork(
 
 	return nil, connect.NewError(connect.CodeNotFound, nil)
 }
+
+func DoBackupDatabaseWork(
+	db *data.Store,
+	ctx context.Context,
+	dir string,
+	keep int,
+) (*adminv1.BackupDatabaseResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoBackupDatabaseWork")
+	defer span.End()
+
+	path, err := data.BackupToDir(ctx, db.DB, dir, keep)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	logging.FromContext(ctx).Info("Database backed up", "path", path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return &adminv1.BackupDatabaseResponse{
+		Path:      path,
+		SizeBytes: info.Size(),
+	}, nil
+}
+
+const (
+	DEFAULT_PAGE_SIZE int32 = 50
+	MAX_PAGE_SIZE     int32 = 500
+)
+
+// Pages through users ordered by phone number. The page token is the last
+// phone number of the previous page.
+func DoListUsersWork(
+	db *data.Store,
+	ctx context.Context,
+	msg *adminv1.ListUsersRequest,
+) (*adminv1.ListUsersResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoListUsersWork")
+	defer span.End()
+
+	page_size := msg.PageSize
+	if page_size <= 0 {
+		page_size = DEFAULT_PAGE_SIZE
+	} else if page_size > MAX_PAGE_SIZE {
+		page_size = MAX_PAGE_SIZE
+	}
+	after, err := base64.RawURLEncoding.DecodeString(msg.PageToken)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	stmt, err := db.Stmt(`
+		SELECT phone_number, username, role, suspended FROM users
+		WHERE phone_number > ? ORDER BY phone_number LIMIT ?;`)
+	if err != nil {
+		return nil, err
+	}
+
+	// One more row than asked for tells whether there is a next page
+	rows, err := stmt.QueryContext(ctx, string(after), page_size+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &adminv1.ListUsersResponse{}
+	for rows.Next() {
+		user := &adminv1.User{}
+		if err := rows.Scan(&user.PhoneNumber, &user.Username, &user.Role, &user.Suspended); err != nil {
+			return nil, err
+		}
+		res.Users = append(res.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Users) > int(page_size) {
+		res.Users = res.Users[:page_size]
+		res.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(res.Users[page_size-1].PhoneNumber))
+	}
+	return res, nil
+}
+
+func DoGetUserWork(
+	db *data.Store,
+	ctx context.Context,
+	msg *adminv1.GetUserRequest,
+) (*adminv1.GetUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoGetUserWork")
+	defer span.End()
+
+	stmt, err := db.Stmt(`
+		SELECT phone_number, username, role, suspended,
+			(SELECT COUNT(*) FROM messages WHERE sender = users.phone_number),
+			(SELECT COUNT(*) FROM messages WHERE receiver = users.phone_number)
+		FROM users WHERE phone_number = ? LIMIT 1;`)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &adminv1.User{}
+	err = stmt.QueryRowContext(ctx, msg.PhoneNumber).Scan(
+		&user.PhoneNumber, &user.Username, &user.Role, &user.Suspended,
+		&user.MessagesSent, &user.MessagesReceived,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, connect.NewError(connect.CodeNotFound, ErrUserNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &adminv1.GetUserResponse{User: user}, nil
+}
+
+func DoSuspendUserWork(
+	db *data.Store,
+	ctx context.Context,
+	msg *adminv1.SuspendUserRequest,
+) (*adminv1.SuspendUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoSuspendUserWork")
+	defer span.End()
+
+	res, err := db.ExecContext(ctx, `UPDATE users SET suspended = ? WHERE phone_number = ?;`,
+		msg.Suspended, msg.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, connect.NewError(connect.CodeNotFound, ErrUserNotFound)
+	}
+
+	logging.FromContext(ctx).Warn("User suspension changed", "phone_number", msg.PhoneNumber, "suspended", msg.Suspended)
+	return &adminv1.SuspendUserResponse{}, nil
+}
+
+// Deletes a user, their messages and the data keys of their conversations in one transaction
+func DoDeleteUserWork(
+	db *data.Store,
+	ctx context.Context,
+	msg *adminv1.DeleteUserRequest,
+) (*adminv1.DeleteUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoDeleteUserWork")
+	defer span.End()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM users WHERE phone_number = ?;`,
+		msg.PhoneNumber).Scan(&exists)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, connect.NewError(connect.CodeNotFound, ErrUserNotFound)
+	}
+
+	// Bots cannot outlive their owner, nobody could manage them anymore
+	rows, err := tx.QueryContext(ctx, `SELECT phone_number FROM users WHERE bot_owner = ?;`, msg.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	var bots []string
+	for rows.Next() {
+		var bot string
+		if err = rows.Scan(&bot); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		bots = append(bots, bot)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var deleted_messages int64
+	for _, phone_number := range append(bots, msg.PhoneNumber) {
+		n, err := deleteAccount(db, ctx, tx, phone_number)
+		if err != nil {
+			return nil, err
+		}
+		deleted_messages += n
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Warn("User deleted", "phone_number", msg.PhoneNumber,
+		"deleted_messages", deleted_messages, "deleted_bots", bots)
+	return &adminv1.DeleteUserResponse{DeletedMessages: deleted_messages, DeletedBots: bots}, nil
+}
+
+// Deletes the user and everything that refers to them, returning how many
+// messages were deleted
+func deleteAccount(db *data.Store, ctx context.Context, tx *sql.Tx, phone_number string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE sender = ? OR receiver = ?;`,
+		phone_number, phone_number)
+	if err != nil {
+		return 0, err
+	}
+	deleted_messages, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = db.DeleteDataKeys(ctx, tx, phone_number); err != nil {
+		return 0, err
+	}
+
+	// Explicit so that nothing is left behind when foreign keys are not enforced
+	_, err = tx.ExecContext(ctx, `DELETE FROM webhook_deliveries
+		WHERE webhook_id IN (SELECT id FROM webhooks WHERE owner = ?);`, phone_number)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM webhooks WHERE owner = ?;`, phone_number); err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM incoming_webhooks WHERE owner = ? OR receiver = ?;`,
+		phone_number, phone_number)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE user = ?;`, phone_number); err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM mutes WHERE user = ? OR muted = ?;`, phone_number, phone_number)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM bot_commands WHERE bot = ?;`, phone_number); err != nil {
+		return 0, err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM api_keys WHERE bot = ?;`, phone_number); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM users WHERE phone_number = ?;`, phone_number)
+	return deleted_messages, err
+}
+
+// Returns the deleted message so that open streams can be notified
+func DoDeleteMessageWork(
+	db *data.Store,
+	ctx context.Context,
+	msg *adminv1.DeleteMessageRequest,
+) (*messagingv1.Message, error) {
+	ctx, span := tracing.Start(ctx, "DoDeleteMessageWork")
+	defer span.End()
+
+	var id uint64
+	var sender, receiver string
+	err := db.QueryRowContext(ctx, `DELETE FROM messages WHERE id = ? RETURNING id, sender, receiver;`,
+		msg.Id).Scan(&id, &sender, &receiver)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, connect.NewError(connect.CodeNotFound, nil)
+	} else if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Warn("Message deleted", "id", id)
+	return &messagingv1.Message{Id: &id, Sender: sender, Receiver: receiver}, nil
+}
+
+func DoForceLogoutWork(
+	db *data.Store,
+	ctx context.Context,
+	msg *adminv1.ForceLogoutRequest,
+) (*adminv1.ForceLogoutResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoForceLogoutWork")
+	defer span.End()
+
+	// Tokens only carry the second they were issued in, so tokens from the
+	// current second are revoked too
+	valid_after := time.Now().Unix() + 1
+	res, err := db.ExecContext(ctx, `UPDATE users SET tokens_valid_after = ? WHERE phone_number = ?;`,
+		valid_after, msg.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, connect.NewError(connect.CodeNotFound, ErrUserNotFound)
+	}
+
+	logging.FromContext(ctx).Warn("User logged out by an admin", "phone_number", msg.PhoneNumber)
+	return &adminv1.ForceLogoutResponse{}, nil
+}
+
+// Everything but active_streams, which only the server knows
+func DoGetStatsWork(
+	db *data.Store,
+	ctx context.Context,
+) (*adminv1.GetStatsResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoGetStatsWork")
+	defer span.End()
+
+	res := &adminv1.GetStatsResponse{}
+	err := db.QueryRowContext(ctx, `SELECT
+		(SELECT COUNT(*) FROM users),
+		(SELECT COUNT(*) FROM users WHERE suspended),
+		(SELECT COUNT(*) FROM messages),
+		(SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size());`,
+	).Scan(&res.Users, &res.SuspendedUsers, &res.Messages, &res.DatabaseSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Creates a webhook of owner, or a global webhook when owner is empty
+func DoCreateWebhookWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	msg *messagingv1.CreateWebhookRequest,
+) (*messagingv1.CreateWebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoCreateWebhookWork")
+	defer span.End()
+
+	var owner_column *string
+	if owner != "" {
+		owner_column = &owner
+	}
+	secret := webhooks.NewSecret()
+	created_at := time.Now().UTC().Format(time.DateTime)
+
+	res, err := db.ExecContext(ctx, `INSERT INTO webhooks (owner, url, secret, events, created_at)
+		VALUES (?, ?, ?, ?, ?);`,
+		owner_column, msg.Url, secret, strings.Join(msg.Events, ","), created_at)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Webhook created", "id", id, "global", owner == "", "events", msg.Events)
+	return &messagingv1.CreateWebhookResponse{
+		Webhook: &messagingv1.Webhook{
+			Id:        uint64(id),
+			Url:       msg.Url,
+			Events:    msg.Events,
+			Global:    owner == "",
+			CreatedAt: created_at,
+		},
+		Secret: secret,
+	}, nil
+}
+
+// Lists the webhooks of owner, and the global ones too when admin is set
+func DoListWebhooksWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	admin bool,
+) (*messagingv1.ListWebhooksResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoListWebhooksWork")
+	defer span.End()
+
+	rows, err := db.QueryContext(ctx, `SELECT id, owner IS NULL, url, events, created_at FROM webhooks
+		WHERE owner = ? OR (? AND owner IS NULL) ORDER BY id;`, owner, admin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &messagingv1.ListWebhooksResponse{}
+	for rows.Next() {
+		webhook := &messagingv1.Webhook{}
+		var events string
+		if err := rows.Scan(&webhook.Id, &webhook.Global, &webhook.Url, &events, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		webhook.Events = strings.Split(events, ",")
+		res.Webhooks = append(res.Webhooks, webhook)
+	}
+	return res, rows.Err()
+}
+
+// Returns NotFound unless the webhook belongs to owner, or is global and admin is set
+func checkWebhookOwner(db *data.Store, ctx context.Context, id uint64, owner string, admin bool) error {
+	var found bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM webhooks
+		WHERE id = ? AND (owner = ? OR (? AND owner IS NULL)));`, id, owner, admin).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return connect.NewError(connect.CodeNotFound, errors.New("Webhook not found"))
+	}
+	return nil
+}
+
+// Deletes the webhook together with its deliveries
+func DoDeleteWebhookWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	admin bool,
+	msg *messagingv1.DeleteWebhookRequest,
+) (*messagingv1.DeleteWebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoDeleteWebhookWork")
+	defer span.End()
+
+	if err := checkWebhookOwner(db, ctx, msg.Id, owner, admin); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = ?;`, msg.Id); err != nil {
+		return nil, err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?;`, msg.Id); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Webhook deleted", "id", msg.Id)
+	return &messagingv1.DeleteWebhookResponse{}, nil
+}
+
+func DoListWebhookDeliveriesWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	admin bool,
+	msg *messagingv1.ListWebhookDeliveriesRequest,
+) (*messagingv1.ListWebhookDeliveriesResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoListWebhookDeliveriesWork")
+	defer span.End()
+
+	if err := checkWebhookOwner(db, ctx, msg.WebhookId, owner, admin); err != nil {
+		return nil, err
+	}
+
+	page_size := msg.PageSize
+	if page_size <= 0 {
+		page_size = DEFAULT_PAGE_SIZE
+	} else if page_size > MAX_PAGE_SIZE {
+		page_size = MAX_PAGE_SIZE
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id, event, status, attempts, response_code, error,
+		created_at, next_attempt_at FROM webhook_deliveries
+		WHERE webhook_id = ? ORDER BY id DESC LIMIT ?;`, msg.WebhookId, page_size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &messagingv1.ListWebhookDeliveriesResponse{}
+	for rows.Next() {
+		delivery := &messagingv1.WebhookDelivery{}
+		err := rows.Scan(&delivery.Id, &delivery.Event, &delivery.Status, &delivery.Attempts,
+			&delivery.ResponseCode, &delivery.Error, &delivery.CreatedAt, &delivery.NextAttemptAt)
+		if err != nil {
+			return nil, err
+		}
+		res.Deliveries = append(res.Deliveries, delivery)
+	}
+	return res, rows.Err()
+}
+
+// Creates a bot of owner with its first API key
+func DoCreateBotWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	msg *messagingv1.CreateBotRequest,
+) (*messagingv1.CreateBotResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoCreateBotWork")
+	defer span.End()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bot_id := BOT_ID_PREFIX + msg.Name
+	_, err = tx.ExecContext(ctx, `INSERT INTO users (phone_number, username, password, salt, role, bot_owner)
+		VALUES (?, ?, '', '', ?, ?);`, bot_id, msg.Username, ROLE_BOT, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	key, key_id, err := insertAPIKey(tx, ctx, bot_id, msg.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Bot created", "bot", bot_id, "scopes", msg.Scopes)
+	return &messagingv1.CreateBotResponse{
+		Bot: &messagingv1.Bot{
+			Id:       bot_id,
+			Username: msg.Username,
+			Scopes:   msg.Scopes,
+			KeyId:    key_id,
+		},
+		ApiKey: key,
+	}, nil
+}
+
+func insertAPIKey(tx webhooks.Execer, ctx context.Context, bot string, scopes []string) (key string, id string, err error) {
+	key, id, hash := newAPIKey()
+	_, err = tx.ExecContext(ctx, `INSERT INTO api_keys (id, bot, hash, scopes, created_at)
+		VALUES (?, ?, ?, ?, datetime('now'));`, id, bot, hash, strings.Join(scopes, ","))
+	return key, id, err
+}
+
+// Revokes the active key of a bot of owner and creates a new one
+func DoRotateBotKeyWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	msg *messagingv1.RotateBotKeyRequest,
+) (*messagingv1.RotateBotKeyResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoRotateBotKeyWork")
+	defer span.End()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bot := &messagingv1.Bot{Id: msg.BotId, Scopes: msg.Scopes}
+	var scopes sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT u.username, k.scopes FROM users u
+		LEFT JOIN api_keys k ON k.bot = u.phone_number AND k.revoked_at IS NULL
+		WHERE u.phone_number = ? AND u.bot_owner = ?;`, msg.BotId, owner).Scan(&bot.Username, &scopes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("Bot not found"))
+	} else if err != nil {
+		return nil, err
+	}
+	if len(bot.Scopes) == 0 && scopes.Valid {
+		bot.Scopes = strings.Split(scopes.String, ",")
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE api_keys SET revoked_at = datetime('now')
+		WHERE bot = ? AND revoked_at IS NULL;`, msg.BotId)
+	if err != nil {
+		return nil, err
+	}
+
+	key, key_id, err := insertAPIKey(tx, ctx, msg.BotId, bot.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	bot.KeyId = key_id
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Bot key rotated", "bot", msg.BotId, "scopes", bot.Scopes)
+	return &messagingv1.RotateBotKeyResponse{Bot: bot, ApiKey: key}, nil
+}
+
+func DoListBotsWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+) (*messagingv1.ListBotsResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoListBotsWork")
+	defer span.End()
+
+	rows, err := db.QueryContext(ctx, `SELECT u.phone_number, u.username, k.id, k.scopes FROM users u
+		LEFT JOIN api_keys k ON k.bot = u.phone_number AND k.revoked_at IS NULL
+		WHERE u.bot_owner = ? ORDER BY u.phone_number;`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &messagingv1.ListBotsResponse{}
+	for rows.Next() {
+		bot := &messagingv1.Bot{}
+		var key_id, scopes sql.NullString
+		if err := rows.Scan(&bot.Id, &bot.Username, &key_id, &scopes); err != nil {
+			return nil, err
+		}
+		bot.KeyId = key_id.String
+		if scopes.Valid {
+			bot.Scopes = strings.Split(scopes.String, ",")
+		}
+		res.Bots = append(res.Bots, bot)
+	}
+	return res, rows.Err()
+}
+
+// Deletes a bot of owner like DeleteUser() deletes users
+func DoDeleteBotWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	msg *messagingv1.DeleteBotRequest,
+) (*messagingv1.DeleteBotResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoDeleteBotWork")
+	defer span.End()
+
+	var found bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users
+		WHERE phone_number = ? AND bot_owner = ?);`, msg.BotId, owner).Scan(&found)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("Bot not found"))
+	}
+
+	if _, err = DoDeleteUserWork(db, ctx, &adminv1.DeleteUserRequest{PhoneNumber: msg.BotId}); err != nil {
+		return nil, err
+	}
+	return &messagingv1.DeleteBotResponse{}, nil
+}
+
+// Creates an incoming webhook that posts messages from owner to msg.Receiver
+func DoCreateIncomingWebhookWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	msg *messagingv1.CreateIncomingWebhookRequest,
+) (*messagingv1.CreateIncomingWebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoCreateIncomingWebhookWork")
+	defer span.End()
+
+	id, token, hash := newSecret()
+	created_at := time.Now().UTC().Format(time.DateTime)
+
+	_, err := db.ExecContext(ctx, `INSERT INTO incoming_webhooks (id, owner, receiver, name, hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?);`, id, owner, msg.Receiver, msg.Name, hash, created_at)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Incoming webhook created", "id", id, "receiver", msg.Receiver)
+	return &messagingv1.CreateIncomingWebhookResponse{
+		IncomingWebhook: &messagingv1.IncomingWebhook{
+			Id:        id,
+			Receiver:  msg.Receiver,
+			Name:      msg.Name,
+			CreatedAt: created_at,
+		},
+		Path: INCOMING_WEBHOOK_PATH + id + "/" + token,
+	}, nil
+}
+
+func DoListIncomingWebhooksWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+) (*messagingv1.ListIncomingWebhooksResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoListIncomingWebhooksWork")
+	defer span.End()
+
+	rows, err := db.QueryContext(ctx, `SELECT id, receiver, name, created_at FROM incoming_webhooks
+		WHERE owner = ? ORDER BY created_at, id;`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &messagingv1.ListIncomingWebhooksResponse{}
+	for rows.Next() {
+		hook := &messagingv1.IncomingWebhook{}
+		if err := rows.Scan(&hook.Id, &hook.Receiver, &hook.Name, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		res.IncomingWebhooks = append(res.IncomingWebhooks, hook)
+	}
+	return res, rows.Err()
+}
+
+func DoDeleteIncomingWebhookWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	msg *messagingv1.DeleteIncomingWebhookRequest,
+) (*messagingv1.DeleteIncomingWebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoDeleteIncomingWebhookWork")
+	defer span.End()
+
+	res, err := db.ExecContext(ctx, `DELETE FROM incoming_webhooks WHERE id = ? AND owner = ?;`, msg.Id, owner)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("Incoming webhook not found"))
+	}
+
+	logging.FromContext(ctx).Info("Incoming webhook deleted", "id", msg.Id)
+	return &messagingv1.DeleteIncomingWebhookResponse{}, nil
+}
+
+// Replaces the commands of a bot of owner
+func DoSetBotCommandsWork(
+	db *data.Store,
+	ctx context.Context,
+	owner string,
+	msg *messagingv1.SetBotCommandsRequest,
+) (*messagingv1.SetBotCommandsResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoSetBotCommandsWork")
+	defer span.End()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	defer tx.Rollback()
+
+	var found bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users
+		WHERE phone_number = ? AND bot_owner = ?);`, msg.BotId, owner).Scan(&found)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	if !found {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("Bot not found"))
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM bot_commands WHERE bot = ?;`, msg.BotId); err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	for _, command := range msg.Commands {
+		_, err = tx.ExecContext(ctx, `INSERT INTO bot_commands (bot, name, description) VALUES (?, ?, ?);`,
+			msg.BotId, command.Name, command.Description)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeUnknown, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	logging.FromContext(ctx).Info("Bot commands set", "bot", msg.BotId, "commands", len(msg.Commands))
+	return &messagingv1.SetBotCommandsResponse{}, nil
+}
+
+// Lists the built-in commands and the commands of user_b
+func DoListCommandsWork(
+	db *data.Store,
+	ctx context.Context,
+	user_b string,
+) (*messagingv1.ListCommandsResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoListCommandsWork")
+	defer span.End()
+
+	res := &messagingv1.ListCommandsResponse{Commands: slices.Clone(BUILTIN_COMMANDS)}
+
+	rows, err := db.QueryContext(ctx, `SELECT name, description FROM bot_commands WHERE bot = ? ORDER BY name;`, user_b)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		command := &messagingv1.BotCommand{}
+		if err := rows.Scan(&command.Name, &command.Description); err != nil {
+			return nil, err
+		}
+		res.Commands = append(res.Commands, command)
+	}
+	return res, rows.Err()
+}
+
+// Mutes the chat of user with muted until the given time, or until it is
+// unmuted when until is nil
+func DoMuteWork(
+	db *data.Store,
+	ctx context.Context,
+	user string,
+	muted string,
+	until *time.Time,
+) error {
+	ctx, span := tracing.Start(ctx, "DoMuteWork")
+	defer span.End()
+
+	var until_column *string
+	if until != nil {
+		formatted := until.UTC().Format(time.DateTime)
+		until_column = &formatted
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO mutes (user, muted, until) VALUES (?, ?, ?)
+		ON CONFLICT (user, muted) DO UPDATE SET until = excluded.until;`, user, muted, until_column)
+	return err
+}
+
+func DoUnmuteWork(
+	db *data.Store,
+	ctx context.Context,
+	user string,
+	muted string,
+) error {
+	ctx, span := tracing.Start(ctx, "DoUnmuteWork")
+	defer span.End()
+
+	_, err := db.ExecContext(ctx, `DELETE FROM mutes WHERE user = ? AND muted = ?;`, user, muted)
+	return err
+}
+
+// Stores a push subscription of user. Subscribing an endpoint again replaces its keys
+func DoRegisterPushSubscriptionWork(
+	db *data.Store,
+	ctx context.Context,
+	user string,
+	msg *messagingv1.RegisterPushSubscriptionRequest,
+) (*messagingv1.RegisterPushSubscriptionResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoRegisterPushSubscriptionWork")
+	defer span.End()
+
+	sub := msg.Subscription
+	_, err := db.ExecContext(ctx, `INSERT INTO push_subscriptions (endpoint, user, p256dh, auth, created_at)
+		VALUES (?, ?, ?, ?, datetime('now'))
+		ON CONFLICT (endpoint) DO UPDATE SET user = excluded.user, p256dh = excluded.p256dh, auth = excluded.auth;`,
+		sub.Endpoint, user, sub.P256Dh, sub.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Push subscription registered")
+	return &messagingv1.RegisterPushSubscriptionResponse{}, nil
+}
+
+func DoUnregisterPushSubscriptionWork(
+	db *data.Store,
+	ctx context.Context,
+	user string,
+	msg *messagingv1.UnregisterPushSubscriptionRequest,
+) (*messagingv1.UnregisterPushSubscriptionResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoUnregisterPushSubscriptionWork")
+	defer span.End()
+
+	res, err := db.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE endpoint = ? AND user = ?;`, msg.Endpoint, user)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("Push subscription not found"))
+	}
+	return &messagingv1.UnregisterPushSubscriptionResponse{}, nil
+}
+
+// Sets the email address of user and returns a new verification token for
+// it. Removes the address when msg.Email is empty. The token is empty when
+// there is nothing to verify
+func DoSetEmailWork(
+	db *data.Store,
+	ctx context.Context,
+	user string,
+	msg *messagingv1.SetEmailRequest,
+) (string, error) {
+	ctx, span := tracing.Start(ctx, "DoSetEmailWork")
+	defer span.End()
+
+	if msg.Email == "" {
+		_, err := db.ExecContext(ctx, `UPDATE users SET email = NULL, email_verified = 0, email_token = NULL,
+			email_token_expires_at = NULL WHERE phone_number = ?;`, user)
+		if err != nil {
+			return "", connect.NewError(connect.CodeUnknown, err)
+		}
+		logging.FromContext(ctx).Info("Email address removed")
+		return "", nil
+	}
+
+	var current sql.NullString
+	var verified bool
+	var sent_at sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT email, email_verified, email_sent_at FROM users WHERE phone_number = ?;`,
+		user).Scan(&current, &verified, &sent_at)
+	if err != nil {
+		return "", connect.NewError(connect.CodeUnknown, err)
+	}
+	if verified && current.String == msg.Email {
+		return "", nil
+	}
+	// Changing the address does not reset the delay, or it could be used to send emails to anyone
+	resend_after := time.Now().UTC().Add(-EMAIL_RESEND_DELAY).Format(time.DateTime)
+	if sent_at.Valid && sent_at.String > resend_after {
+		return "", connect.NewError(connect.CodeResourceExhausted,
+			errors.New("A verification email was just sent, wait a minute before requesting another"))
+	}
+
+	_, token, hash := newSecret()
+	expiry := time.Now().UTC().Add(EMAIL_TOKEN_DURATION).Format(time.DateTime)
+	_, err = db.ExecContext(ctx, `UPDATE users SET email = ?, email_verified = 0, email_token = ?,
+		email_token_expires_at = ?, email_sent_at = datetime('now') WHERE phone_number = ?;`, msg.Email, hash, expiry, user)
+	if err != nil {
+		return "", connect.NewError(connect.CodeUnknown, err)
+	}
+
+	logging.FromContext(ctx).Info("Email address set")
+	return token, nil
+}
+
+func DoVerifyEmailWork(
+	db *data.Store,
+	ctx context.Context,
+	user string,
+	msg *messagingv1.VerifyEmailRequest,
+) (*messagingv1.VerifyEmailResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoVerifyEmailWork")
+	defer span.End()
+
+	var hash []byte
+	err := db.QueryRowContext(ctx, `SELECT email_token FROM users WHERE phone_number = ?
+		AND email_token IS NOT NULL AND email_token_expires_at > datetime('now');`, user).Scan(&hash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	if err != nil || !secretMatches(msg.Token, hash) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("Verification token is invalid or expired"))
+	}
+
+	_, err = db.ExecContext(ctx, `UPDATE users SET email_verified = 1, email_token = NULL,
+		email_token_expires_at = NULL WHERE phone_number = ?;`, user)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	logging.FromContext(ctx).Info("Email address verified")
+	return &messagingv1.VerifyEmailResponse{}, nil
+}
+
+func DoGetNotificationPreferencesWork(
+	db *data.Store,
+	ctx context.Context,
+	user string,
+) (*messagingv1.GetNotificationPreferencesResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoGetNotificationPreferencesWork")
+	defer span.End()
+
+	preferences := &messagingv1.NotificationPreferences{}
+	var email sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT email, email_verified, email_digest, push FROM users WHERE phone_number = ?;`,
+		user).Scan(&email, &preferences.EmailVerified, &preferences.EmailDigest, &preferences.Push)
+	if err != nil {
+		return nil, err
+	}
+	preferences.Email = email.String
+
+	return &messagingv1.GetNotificationPreferencesResponse{Preferences: preferences}, nil
+}
+
+func DoSetNotificationPreferencesWork(
+	db *data.Store,
+	ctx context.Context,
+	user string,
+	msg *messagingv1.SetNotificationPreferencesRequest,
+) (*messagingv1.SetNotificationPreferencesResponse, error) {
+	ctx, span := tracing.Start(ctx, "DoSetNotificationPreferencesWork")
+	defer span.End()
+
+	_, err := db.ExecContext(ctx, `UPDATE users SET email_digest = COALESCE(?, email_digest),
+		push = COALESCE(?, push) WHERE phone_number = ?;`, msg.EmailDigest, msg.Push, user)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := DoGetNotificationPreferencesWork(db, ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &messagingv1.SetNotificationPreferencesResponse{Preferences: res.Preferences}, nil
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+
+	"connectrpc.com/connect"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/logging"
+	"github.com/vl0000/gomessenger/push"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Longer message contents are cut in notifications, which must fit in one
+// encrypted record
+const PUSH_CONTENT_LIMIT int = 1000
+
+var ErrPushDisabled = errors.New("Web Push is not configured on this server")
+
+// Sends Web Push notifications signed with the VAPID key of opts
+func (s *MessagingServer) SetupPush(opts push.Options) error {
+	sender, err := push.NewWebPush(opts)
+	if err != nil {
+		return err
+	}
+	s.Push = sender
+	s.PushPublicKey = sender.PublicKey()
+	return nil
+}
+
+// Returns true when receiver gets messages of sender live, from a stream of
+// their chat or from /events
+func (s *MessagingServer) hasStream(receiver string, sender string) bool {
+	s.ConnsMu.Lock()
+	_, ok := s.Conns[receiver+sender]
+	s.ConnsMu.Unlock()
+	if ok {
+		return true
+	}
+
+	s.feedsMu.Lock()
+	defer s.feedsMu.Unlock()
+	return len(s.feeds[receiver]) > 0
+}
+
+// Sends message to every push subscription of its receiver, unless they muted
+// the chat. Subscriptions are read right away so later mutes do not apply, but
+// the sender does not wait for the push services
+func (s *MessagingServer) pushMessage(ctx context.Context, message *messagingv1.Message) {
+	subs, err := s.pushSubscriptions(ctx, message.Receiver, message.Sender)
+	if err != nil {
+		logging.FromContext(ctx).Error("Could not read push subscriptions", "error", err)
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	content := message.Content
+	if len(content) > PUSH_CONTENT_LIMIT {
+		content = content[:PUSH_CONTENT_LIMIT]
+		for !utf8.ValidString(content) {
+			content = content[:len(content)-1]
+		}
+	}
+	payload, err := protojson.Marshal(&messagingv1.Message{
+		Id:        message.Id,
+		Sender:    message.Sender,
+		Receiver:  message.Receiver,
+		Content:   content,
+		Timestamp: message.Timestamp,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Could not encode a push notification", "error", err)
+		return
+	}
+
+	// The request ends before the pushes do
+	ctx = context.WithoutCancel(ctx)
+	s.pushes.Add(1)
+	go func() {
+		defer s.pushes.Done()
+
+		for _, sub := range subs {
+			err := s.Push.Send(ctx, sub, payload)
+			if errors.Is(err, push.ErrGone) {
+				logging.FromContext(ctx).Info("Removing an expired push subscription", "receiver", message.Receiver)
+				_, err = s.Db.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE endpoint = ?;`, sub.Endpoint)
+			}
+			if err != nil {
+				logging.FromContext(ctx).Warn("Push notification failed", "receiver", message.Receiver, "error", err)
+			}
+		}
+	}()
+}
+
+// Subscriptions of receiver, or none while receiver muted the chat with sender
+// or turned push notifications off
+func (s *MessagingServer) pushSubscriptions(ctx context.Context, receiver string, sender string) ([]push.Subscription, error) {
+	rows, err := s.Db.QueryContext(ctx, `SELECT endpoint, p256dh, auth FROM push_subscriptions
+		WHERE user = ? AND NOT EXISTS (SELECT 1 FROM mutes WHERE user = ? AND muted = ?
+		AND (until IS NULL OR until > datetime('now')))
+		AND EXISTS (SELECT 1 FROM users WHERE phone_number = ? AND push);`, receiver, receiver, sender, receiver)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []push.Subscription{}
+	for rows.Next() {
+		var sub push.Subscription
+		if err := rows.Scan(&sub.Endpoint, &sub.P256dh, &sub.Auth); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *MessagingServer) GetPushPublicKey(
+	ctx context.Context,
+	req *connect.Request[messagingv1.GetPushPublicKeyRequest],
+) (*connect.Response[messagingv1.GetPushPublicKeyResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if s.Push == nil {
+		return nil, connect.NewError(connect.CodeUnimplemented, ErrPushDisabled)
+	}
+
+	return connect.NewResponse(&messagingv1.GetPushPublicKeyResponse{VapidPublicKey: s.PushPublicKey}), nil
+}
+
+func (s *MessagingServer) RegisterPushSubscription(
+	ctx context.Context,
+	req *connect.Request[messagingv1.RegisterPushSubscriptionRequest],
+) (*connect.Response[messagingv1.RegisterPushSubscriptionResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateRegisterPushSubscriptionRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoRegisterPushSubscriptionWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) UnregisterPushSubscription(
+	ctx context.Context,
+	req *connect.Request[messagingv1.UnregisterPushSubscriptionRequest],
+) (*connect.Response[messagingv1.UnregisterPushSubscriptionResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := DoUnregisterPushSubscriptionWork(s.Db, ctx, token.Subject(), req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
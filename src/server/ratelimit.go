@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/httprate"
+)
+
+type rateLimitKey struct{}
+
+// Limits every request to limit per IP, and requests with a valid API key
+// also to bot_limit per bot. The IP limit is applied first so that clients
+// can not make the server look up keys at any rate
+func (s *MessagingServer) RateLimit(limit int, bot_limit int, window time.Duration) func(http.Handler) http.Handler {
+	by_ip := httprate.LimitByIP(limit, window)
+	by_bot := httprate.Limit(bot_limit, window, httprate.WithKeyFuncs(func(r *http.Request) (string, error) {
+		return r.Context().Value(rateLimitKey{}).(string), nil
+	}))
+
+	return func(next http.Handler) http.Handler {
+		bots := by_bot(next)
+		return by_ip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := streamToken(r); IsAPIKey(key) {
+				if bot, _, _, err := CheckAPIKey(s.Db, r.Context(), key); err == nil {
+					bots.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), rateLimitKey{}, bot)))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
@@ -1,44 +1,107 @@
 package server
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
 
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"connectrpc.com/grpcreflect"
+	"connectrpc.com/otelconnect"
+	"connectrpc.com/vanguard"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/vl0000/gomessenger/gen/admin/v1/adminv1connect"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
 	"github.com/vl0000/gomessenger/gen/messaging/v1/messagingv1connect"
+	"github.com/vl0000/gomessenger/metrics"
+	"github.com/vl0000/gomessenger/openapi"
+	"github.com/vl0000/gomessenger/public"
+	"github.com/vl0000/gomessenger/tracing"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
 
-func ServeHTML(path string) http.HandlerFunc {
-	if page, err := os.ReadFile(path); err != nil {
-		log.Printf("%s Not found\n", path)
-	} else {
+func (s *MessagingServer) LoadRoutes() {
 
-		return func(w http.ResponseWriter, r *http.Request) {
-			_, err = w.Write(page)
-			if err != nil {
-				log.Println(err)
-			}
-		}
+	// Loads static files for svelte apps
+	static := newStaticFiles(public.Files(s.PublicDir), s.PublicDir == "")
+	s.Router.Get("/*", static.ServeStatic)
+	s.Router.Head("/*", static.ServeStatic)
+
+	// Loads the paths for the messaging service
+	// Clients' W3C trace context is trusted so their spans become the parents of ours
+	otel_interceptor, err := otelconnect.NewInterceptor(otelconnect.WithTrustRemote(), otelconnect.WithoutMetrics())
+	if err != nil {
+		slog.Error("Could not create the tracing interceptor", "error", err)
 	}
+	interceptors := connect.WithInterceptors(otel_interceptor, &apiKeyInterceptor{s}, &loggingInterceptor{s}, &metricsInterceptor{})
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html")
-		http.Error(w, "Not Found", 404)
+	if s.Metrics {
+		err := metrics.Register(metrics.DBStats(s.Db.DB), metrics.ChannelFill(s.channelFill))
+		if err != nil {
+			slog.Error("Could not register metrics", "error", err)
+		}
+		s.Router.Handle("/metrics", promhttp.Handler())
 	}
-}
 
-func (s *MessagingServer) LoadRoutes() {
+	path, handler := messagingv1connect.NewMessagingServiceHandler(s, interceptors)
+	s.Router.Handle(path+"*", h2c.NewHandler(handler, &http2.Server{}))
+	s.loadREST(path, handler)
 
-	// Loads static files for svelte apps
-	s.Router.Handle("/*", http.FileServer(http.Dir("./public/static/")))
+	// Live updates for clients that cannot keep Connect streams open
+	s.Router.With(tracing.Middleware("/ws")).Get("/ws", s.ServeWebSocket)
+	s.Router.With(tracing.Middleware("/events")).Get("/events", s.ServeEvents)
 
-	// Loads the paths for the messaging service
-	path, handler := messagingv1connect.NewMessagingServiceHandler(s)
+	// Lets CI pipelines and scripts post messages with a bare curl
+	s.Router.With(tracing.Middleware(INCOMING_WEBHOOK_PATH)).Post(INCOMING_WEBHOOK_PATH+"{id}/{token}", s.ServeIncomingWebhook)
+
+	path, handler = adminv1connect.NewAdminServiceHandler(s, interceptors)
+	s.Router.Handle(path+"*", h2c.NewHandler(handler, &http2.Server{}))
+
+	// Probes for load balancers and container orchestrators
+	s.Router.Get("/healthz", s.ServeHealthz)
+	s.Router.Get("/readyz", s.ServeReadyz)
+	path, handler = grpchealth.NewHandler(&healthChecker{s})
 	s.Router.Handle(path+"*", h2c.NewHandler(handler, &http2.Server{}))
 
-	s.Router.Get("/", ServeHTML("./public/login.html"))
-	s.Router.Get("/signup", ServeHTML("./public/signup.html"))
-	s.Router.Get("/chat", ServeHTML("./public/chat.html"))
+	// Describes the services above to grpcurl, Postman and similar tools
+	if s.Reflection {
+		reflector := grpcreflect.NewStaticReflector(
+			messagingv1connect.MessagingServiceName,
+			adminv1connect.AdminServiceName,
+			grpchealth.HealthV1ServiceName,
+		)
+		path, handler = grpcreflect.NewHandlerV1(reflector)
+		s.Router.Handle(path+"*", h2c.NewHandler(handler, &http2.Server{}))
+		path, handler = grpcreflect.NewHandlerV1Alpha(reflector)
+		s.Router.Handle(path+"*", h2c.NewHandler(handler, &http2.Server{}))
+	}
+
+	s.Router.Get("/", static.ServeFile("login.html"))
+	s.Router.Get("/signup", static.ServeFile("signup.html"))
+	s.Router.Get("/chat", static.ServeFile("chat.html"))
+}
+
+// Serves the REST mappings of messaging.proto under /v1/ by transcoding them
+// to the Connect handler, and their OpenAPI document on /openapi.json
+func (s *MessagingServer) loadREST(path string, handler http.Handler) {
+	transcoder, err := vanguard.NewTranscoder([]*vanguard.Service{vanguard.NewService(path, handler)})
+	if err != nil {
+		slog.Error("Could not create the REST gateway", "error", err)
+		return
+	}
+	s.Router.Handle("/v1/*", transcoder)
+
+	doc := openapi.Generate(
+		openapi.Info{Title: "GoMessenger", Version: "v1"},
+		[]string{"RegisterUser", "Login"},
+		messagingv1.File_messaging_v1_messaging_proto.Services().ByName("MessagingService"),
+	)
+	openapi_handler, err := openapi.Handler(doc)
+	if err != nil {
+		slog.Error("Could not create the OpenAPI document", "error", err)
+		return
+	}
+	s.Router.Get("/openapi.json", openapi_handler.ServeHTTP)
 }
@@ -2,73 +2,444 @@ package server
 
 import (
 	"context"
-	"database/sql"
-	"log"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
-	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
 
+	"github.com/vl0000/gomessenger/certs"
+	"github.com/vl0000/gomessenger/config"
 	"github.com/vl0000/gomessenger/data"
+	"github.com/vl0000/gomessenger/email"
 	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
-	"golang.org/x/net/http2"
-	"golang.org/x/net/http2/h2c"
+	"github.com/vl0000/gomessenger/push"
+	"github.com/vl0000/gomessenger/tracing"
+	"github.com/vl0000/gomessenger/webhooks"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
-const CHANNEL_SIZE int = 32
+const (
+	CHANNEL_SIZE int = 32
+	// Suggested wait before clients reopen streams ended by Shutdown()
+	RECONNECT_DELAY time.Duration = time.Second
+	// How often expired messages are deleted from the database
+	PURGE_INTERVAL time.Duration = 10 * time.Second
+)
 
 type MessagingServer struct {
 	Addr      string
 	Router    *chi.Mux
-	Db        *sql.DB
+	Db        *data.Store
 	TokenAuth *jwtauth.JWTAuth
 	// Used to communicate with server streams opened with GetDMs()
-	Conns map[string]chan *messagingv1.Message
+	Conns   map[string]chan *messagingv1.GetDMsResponse
+	ConnsMu sync.RWMutex
+	// Used to send incoming messages to every /events stream of a user
+	feeds   map[string]map[chan *messagingv1.Message]struct{}
+	feedsMu sync.Mutex
+	// Closed by endSessions() to end every stream of a user
+	sessions   map[string]chan struct{}
+	sessionsMu sync.Mutex
+	// Snapshots are written here by BackupDatabase() and the backup schedule
+	BackupDir string
+	// Scheduled backups are disabled when this is 0
+	BackupInterval time.Duration
+	// How many snapshots to keep in BackupDir. 0 keeps all of them
+	BackupKeep int
+	// Serves Prometheus metrics on /metrics
+	Metrics bool
+	// Serves the frontend from this directory instead of the embedded copy
+	PublicDir string
+	// Serves the gRPC reflection services so tools can list the procedures
+	Reflection bool
+	// Time between the comments that keep idle /events streams open. 0 disables them
+	HeartbeatInterval time.Duration
+	// How long Shutdown() reports the server as not ready before closing the
+	// database, so load balancers stop sending requests first
+	DrainDelay time.Duration
+	// How long Shutdown() waits for running requests before closing connections
+	ShutdownTimeout time.Duration
+	// Serves HTTPS instead of h2c when set
+	TLSConfig *tls.Config
+	// Replaces the certificate of TLSConfig when its files change
+	Certs              *certs.Reloader
+	CertReloadInterval time.Duration
+	// A plain HTTP listener on this address redirects to HTTPS
+	RedirectAddr string
+	// A plain HTTP listener on this address serves the health probes, so they
+	// work whatever the TLS and client certificate settings are
+	HealthAddr string
+	// Sends outgoing webhooks. Events are still written to the outbox when nil
+	Webhooks *webhooks.Dispatcher
+	// Running webhook dispatcher, Shutdown() waits for it
+	dispatcher sync.WaitGroup
+	// Notifies receivers without an open stream of their messages. Web Push
+	// is disabled when nil
+	Push          push.Sender
+	PushPublicKey string
+	// Pushes that are still running, Shutdown() waits for them
+	pushes sync.WaitGroup
+	// Sends verification emails and digests. Email is disabled when nil
+	Email email.Sender
+	// Time between checks for due digests. 0 disables digests
+	DigestInterval time.Duration
+	// Digests include the latest message of every chat
+	DigestPreviews bool
+	// Running digest schedule, Shutdown() waits for it
+	digests sync.WaitGroup
+	// Closed by Shutdown() to stop background jobs and end open streams
+	stop           chan struct{}
+	draining       atomic.Bool
+	httpServer     *http.Server
+	redirectServer *http.Server
+	healthServer   *http.Server
 }
 
-func New() *MessagingServer {
-	server := MessagingServer{}
+// Opens the database described by cfg, including message encryption
+func OpenDatabase(cfg *config.Config) (*data.Store, error) {
+	db, err := data.SetupDatabase(cfg.Database.Path, data.Options{
+		SchemaPath:      cfg.Database.SchemaPath,
+		JournalMode:     cfg.Database.JournalMode,
+		BusyTimeout:     cfg.Database.BusyTimeout,
+		ForeignKeys:     cfg.Database.ForeignKeys,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	if host, ok := os.LookupEnv("HOST"); ok {
-		server.Addr = host
-	} else {
-		server.Addr = "localhost:3000"
+	if db.Keyring, err = data.ParseKeyring(cfg.MessageKey, cfg.MessageKeyPrevious); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("message_key -> %s", err)
 	}
+	return db, nil
+}
 
-	if key := os.Getenv("SECRET_KEY"); len(key) < 64 {
-		log.Fatal("The secret key must be at least 64 characters long")
+func New(cfg *config.Config) (*MessagingServer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
-	if _, ok := os.LookupEnv("DB_SCHEMA_PATH"); !ok {
-		os.Setenv("DB_SCHEMA_PATH", "./data/database.sql")
+	db, err := OpenDatabase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Could not setup DB. Error:\n\t%s", err)
 	}
 
-	db, err := data.SetupTestDatabase(os.Getenv("DB_PATH"))
-	if err != nil || db == nil {
-		log.Fatalf("Could not setup DB. Error:\n\t%s", err)
+	s := &MessagingServer{
+		Addr:              cfg.Host,
+		Router:            chi.NewRouter(),
+		Db:                db,
+		TokenAuth:         jwtauth.New("HS256", []byte(cfg.SecretKey), nil),
+		BackupDir:         cfg.Backup.Dir,
+		BackupInterval:    cfg.Backup.Interval,
+		BackupKeep:        cfg.Backup.Keep,
+		Metrics:           cfg.Metrics.Enabled,
+		PublicDir:         cfg.HTTP.PublicDir,
+		Reflection:        cfg.HTTP.Reflection,
+		HeartbeatInterval: cfg.HTTP.HeartbeatInterval,
+		DrainDelay:        cfg.HTTP.DrainDelay,
+		ShutdownTimeout:   cfg.HTTP.ShutdownTimeout,
+		HealthAddr:        cfg.HTTP.HealthAddr,
+		DigestInterval:    cfg.Email.DigestInterval,
+		DigestPreviews:    cfg.Email.Previews,
 	}
-	server.Db = db
 
-	server.Router = chi.NewRouter()
+	if cfg.Webhooks.Enabled {
+		s.SetupWebhooks(webhooks.Options{
+			MaxAttempts:           cfg.Webhooks.MaxAttempts,
+			RetryDelay:            cfg.Webhooks.RetryDelay,
+			MaxRetryDelay:         cfg.Webhooks.MaxRetryDelay,
+			Timeout:               cfg.Webhooks.Timeout,
+			AllowPrivateAddresses: cfg.Webhooks.AllowPrivateAddresses,
+		})
+	}
+
+	if cfg.Push.VAPIDPrivateKey != "" {
+		err = s.SetupPush(push.Options{
+			VAPIDPrivateKey:       cfg.Push.VAPIDPrivateKey,
+			Subject:               cfg.Push.Subject,
+			TTL:                   cfg.Push.TTL,
+			Timeout:               cfg.Push.Timeout,
+			AllowPrivateAddresses: cfg.Push.AllowPrivateAddresses,
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if cfg.Email.SMTPAddr != "" {
+		err = s.SetupEmail(email.Options{
+			Addr:        cfg.Email.SMTPAddr,
+			Username:    cfg.Email.Username,
+			Password:    cfg.Email.Password,
+			ImplicitTLS: cfg.Email.ImplicitTLS,
+			From:        cfg.Email.From,
+			Timeout:     cfg.Email.Timeout,
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
 
-	return &server
+	if cfg.TLS.CertFile != "" {
+		s.TLSConfig, s.Certs, err = certs.ServerConfig(certs.Options{
+			CertFile:     cfg.TLS.CertFile,
+			KeyFile:      cfg.TLS.KeyFile,
+			ClientCAFile: cfg.TLS.ClientCAFile,
+			ClientAuth:   cfg.TLS.ClientAuth,
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		s.CertReloadInterval = cfg.TLS.ReloadInterval
+		s.RedirectAddr = cfg.TLS.RedirectAddr
+	}
+	return s, nil
 }
 
 func (s *MessagingServer) Run() error {
-	s.TokenAuth = jwtauth.New("HS256", []byte(os.Getenv("SECRET_KEY")), nil)
-	s.Conns = make(map[string]chan *messagingv1.Message)
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serves requests on listener until Shutdown() is called
+func (s *MessagingServer) Serve(listener net.Listener) error {
+	s.Conns = make(map[string]chan *messagingv1.GetDMsResponse)
+	s.feeds = make(map[string]map[chan *messagingv1.Message]struct{})
+	s.stop = make(chan struct{})
+
+	go s.purgeExpiredMessages()
+	if s.Webhooks != nil {
+		s.dispatcher.Add(1)
+		go func() {
+			defer s.dispatcher.Done()
+			s.Webhooks.Run(s.stop)
+		}()
+	}
+	if s.BackupDir != "" && s.BackupInterval > 0 {
+		go s.scheduleBackups()
+	}
+	if s.Email != nil && s.DigestInterval > 0 {
+		s.digests.Add(1)
+		go s.scheduleDigests()
+	}
+
+	if s.HealthAddr != "" {
+		s.healthServer = &http.Server{
+			Addr:              s.HealthAddr,
+			Handler:           s.healthHandler(),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go s.serveHealth()
+	}
+
+	s.httpServer = &http.Server{Addr: s.Addr, Handler: s.Router, Protocols: new(http.Protocols)}
+	s.httpServer.Protocols.SetHTTP1(true)
 
-	log.Printf("Starting server in address: %s", s.Addr)
-	return http.ListenAndServe(s.Addr, h2c.NewHandler(s.Router, &http2.Server{}))
+	var err error
+	if s.TLSConfig != nil {
+		if s.Certs != nil && s.CertReloadInterval > 0 {
+			go s.Certs.Watch(s.CertReloadInterval, s.stop)
+		}
+		if s.RedirectAddr != "" {
+			_, port, _ := net.SplitHostPort(listener.Addr().String())
+			s.redirectServer = &http.Server{
+				Addr:              s.RedirectAddr,
+				Handler:           certs.RedirectHandler(port),
+				ReadHeaderTimeout: 5 * time.Second,
+			}
+			go s.serveRedirects()
+		}
+
+		s.httpServer.TLSConfig = s.TLSConfig
+		s.httpServer.Protocols.SetHTTP2(true)
+		slog.Info("Starting server", "address", listener.Addr().String(), "tls", true)
+		err = s.httpServer.ServeTLS(listener, "", "")
+	} else {
+		// HTTP/2 without TLS is served by net/http itself rather than by a hijacking
+		// h2c handler, so Shutdown() also waits for requests on HTTP/2 connections
+		s.httpServer.Protocols.SetUnencryptedHTTP2(true)
+		slog.Info("Starting server", "address", listener.Addr().String())
+		err = s.httpServer.Serve(listener)
+	}
+
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
+// Redirects plain HTTP requests on RedirectAddr to the HTTPS listener
+func (s *MessagingServer) serveRedirects() {
+	slog.Info("Redirecting HTTP to HTTPS", "address", s.RedirectAddr)
+	if err := s.redirectServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("HTTP redirect stopped", "error", err)
+	}
+}
+
+// Stops the server in an order that loses no requests: it reports itself as
+// not ready, ends open streams, waits for running requests and background
+// jobs and only then closes the database.
 func (s *MessagingServer) Shutdown() {
-	log.Println("Shutting down")
+	slog.Info("Shutting down", "drain_delay", s.DrainDelay)
+	s.draining.Store(true)
+	time.Sleep(s.DrainDelay)
+
+	if s.stop != nil {
+		close(s.stop)
+	}
+
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+		defer cancel()
+		if s.redirectServer != nil {
+			s.redirectServer.Shutdown(ctx)
+		}
+		if s.healthServer != nil {
+			s.healthServer.Shutdown(ctx)
+		}
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			slog.Warn("Requests still running after the shutdown timeout, closing their connections", "error", err)
+			s.httpServer.Close()
+		}
+	}
+
+	s.pushes.Wait()
+	s.digests.Wait()
+	s.dispatcher.Wait()
 	s.Db.Close()
+}
+
+func (s *MessagingServer) scheduleBackups() {
+	ticker := time.NewTicker(s.BackupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		path, err := data.BackupToDir(context.Background(), s.Db.DB, s.BackupDir, s.BackupKeep)
+		if err != nil {
+			slog.Error("Scheduled backup failed", "error", err)
+			continue
+		}
+		slog.Info("Database backed up", "path", path)
+	}
+}
+
+// Used by the stream channel metrics
+func (s *MessagingServer) channelFill() (int, int, float64) {
+	s.ConnsMu.RLock()
+	defer s.ConnsMu.RUnlock()
+
+	queued, capacity, fullest := 0, 0, 0.0
 	for _, channel := range s.Conns {
-		close(channel)
+		queued += len(channel)
+		capacity += cap(channel)
+		if ratio := float64(len(channel)) / float64(cap(channel)); ratio > fullest {
+			fullest = ratio
+		}
+	}
+	return queued, capacity, fullest
+}
+
+// Sends a response to the GetDMs() stream that user_a opened for their chat with user_b.
+// The send never blocks, so a stream that stopped reading only loses its own updates
+func (s *MessagingServer) notifyStream(user_a string, user_b string, res *messagingv1.GetDMsResponse) {
+	s.ConnsMu.RLock()
+	channel, ok := s.Conns[user_a+user_b]
+	s.ConnsMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	select {
+	case channel <- res:
+	default:
+		slog.Warn("Stream channel is full, dropping update", "user", user_a)
+	}
+}
+
+// Forgets the channel of a GetDMs() stream that ended, unless a newer stream replaced it
+func (s *MessagingServer) removeStream(key string, channel chan *messagingv1.GetDMsResponse) {
+	s.ConnsMu.Lock()
+	defer s.ConnsMu.Unlock()
+	if s.Conns[key] == channel {
+		delete(s.Conns, key)
+	}
+}
+
+// Returns a channel that is closed when the streams of user must end
+func (s *MessagingServer) sessionEnded(user string) <-chan struct{} {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]chan struct{})
+	}
+	ended, ok := s.sessions[user]
+	if !ok {
+		ended = make(chan struct{})
+		s.sessions[user] = ended
+	}
+	return ended
+}
+
+// Ends the GetDMs(), /ws and /events streams of user, which remove their
+// entries from s.Conns and s.feeds as they return. Called once a user can no
+// longer authenticate, so the streams do not outlive the tokens they opened with
+func (s *MessagingServer) endSessions(user string) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	if ended, ok := s.sessions[user]; ok {
+		close(ended)
+		delete(s.sessions, user)
+	}
+}
+
+// Periodically deletes disappearing messages and tells both participants' streams
+func (s *MessagingServer) purgeExpiredMessages() {
+	ticker := time.NewTicker(PURGE_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		expired, err := DoPurgeExpiredMessagesWork(s.Db, context.Background())
+		if err != nil {
+			slog.Error("Could not purge expired messages", "error", err)
+			continue
+		}
+
+		for _, message := range expired {
+			res := &messagingv1.GetDMsResponse{ExpiredIds: []uint64{message.GetId()}}
+			s.notifyStream(message.Sender, message.Receiver, res)
+			s.notifyStream(message.Receiver, message.Sender, res)
+		}
 	}
 }
 
@@ -81,20 +452,34 @@ func (s *MessagingServer) SendDirectMessage(
 		return nil, err
 	}
 
-	if err := s.validateSendDirectMessageRequest(req); err != nil {
+	if err := s.validateSendDirectMessageRequest(ctx, req); err != nil {
 		return nil, err
 	}
 
-	res, err := DoSendDirectMessageWork(s.Db, ctx, req.Msg)
+	// Commands typed into the chat are handled here as well
+	res, err := s.handleMessage(ctx, req.Msg)
 	if err != nil {
-		return nil, connect.NewError(connect.CodeUnknown, err)
+		return nil, err
 	}
 
-	if channel, ok := s.Conns[req.Msg.Message.Receiver+req.Msg.Message.Sender]; ok {
-		channel <- res
-	}
+	return connect.NewResponse(res), nil
+}
 
-	return connect.NewResponse(&messagingv1.SendDirectMessageResponse{Message: res}), nil
+// Sends a stored message to the receiver's open streams and wakes the webhooks
+func (s *MessagingServer) deliverMessage(ctx context.Context, message *messagingv1.Message) {
+	_, span := tracing.Start(ctx, "notifyStream")
+	defer span.End()
+
+	s.notifyStream(message.Receiver, message.Sender, &messagingv1.GetDMsResponse{
+		Messages: []*messagingv1.Message{message},
+	})
+	s.notifyFeeds(message.Receiver, message)
+	s.notifyWebhooks()
+	if s.hasStream(message.Receiver, message.Sender) {
+		s.markActive(ctx, message.Receiver)
+	} else if s.Push != nil {
+		s.pushMessage(ctx, message)
+	}
 }
 
 func (s *MessagingServer) GetDMs(
@@ -107,7 +492,7 @@ func (s *MessagingServer) GetDMs(
 		return err
 	}
 
-	err := s.validateGetDMsRequest(req)
+	err := s.validateGetDMsRequest(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -116,29 +501,74 @@ func (s *MessagingServer) GetDMs(
 	if err != nil {
 		return connect.NewError(connect.CodeUnknown, err)
 	}
+	s.markActive(ctx, req.Msg.UserA)
 
 	if err = stream.Conn().Send(res); err != nil {
 		return err
 	}
 
-	s.Conns[req.Msg.UserA+req.Msg.UserB] = make(chan *messagingv1.Message, CHANNEL_SIZE)
-
+	key := req.Msg.UserA + req.Msg.UserB
+	channel := make(chan *messagingv1.GetDMsResponse, CHANNEL_SIZE)
+	s.ConnsMu.Lock()
+	s.Conns[key] = channel
+	s.ConnsMu.Unlock()
+	defer s.removeStream(key, channel)
+
+	// The request timeout must not end the stream, but a client that
+	// disconnects before it fires still does
+	done := ctx.Done()
+	ended := s.sessionEnded(req.Msg.UserA)
 	for {
-		if channel, ok := s.Conns[req.Msg.UserA+req.Msg.UserB]; ok {
-
-			err = stream.Send(&messagingv1.GetDMsResponse{
-				Messages: []*messagingv1.Message{<-channel},
-			})
-
-			if err != nil {
+		select {
+		case res := <-channel:
+			if err = stream.Send(res); err != nil {
 				return err
 			}
-		} else {
-			break
+		case <-done:
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil
+			}
+			done = nil
+		case <-ended:
+			return connect.NewError(connect.CodeUnauthenticated, ErrSessionEnded)
+		case <-s.stop:
+			return reconnectError()
 		}
 	}
+}
 
-	return nil
+// Ends streams during shutdown. Unavailable tells clients to retry, and the
+// RetryInfo detail tells them when
+func reconnectError() error {
+	err := connect.NewError(connect.CodeUnavailable, ErrDraining)
+	if detail, detail_err := connect.NewErrorDetail(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(RECONNECT_DELAY),
+	}); detail_err == nil {
+		err.AddDetail(detail)
+	}
+	return err
+}
+
+func (s *MessagingServer) ListDMs(
+	ctx context.Context,
+	req *connect.Request[messagingv1.GetDMsRequest],
+) (*connect.Response[messagingv1.GetDMsResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateGetDMsRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	res, err := DoGetDMsWork(s.Db, ctx, req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	s.markActive(ctx, req.Msg.UserA)
+
+	return connect.NewResponse(res), nil
 }
 
 func (s *MessagingServer) RegisterUser(
@@ -153,7 +583,7 @@ func (s *MessagingServer) RegisterUser(
 		return nil, err
 	}
 
-	if err := s.validateRegistrationRequest(req); err != nil {
+	if err := s.validateRegistrationRequest(ctx, req); err != nil {
 		return nil, err
 	}
 
@@ -161,6 +591,7 @@ func (s *MessagingServer) RegisterUser(
 	if err != nil {
 		return nil, err
 	}
+	s.notifyWebhooks()
 	return connect.NewResponse(response), nil
 
 }
@@ -174,7 +605,7 @@ func (s *MessagingServer) Login(
 		return nil, err
 	}
 
-	if err := s.validateLoginRequest(req); err != nil {
+	if err := s.validateLoginRequest(ctx, req); err != nil {
 		return nil, err
 	}
 
@@ -195,7 +626,7 @@ func (s *MessagingServer) GetUserInfo(
 		return nil, err
 	}
 
-	err := s.validateGetUserInfo(req)
+	err := s.validateGetUserInfo(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,25 +1,52 @@
 package server_test
 
 import (
+	"bufio"
 	"context"
+	"crypto/ecdh"
 	"crypto/pbkdf2"
 	"crypto/rand"
 	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"connectrpc.com/connect"
+	"connectrpc.com/grpcreflect"
+	"github.com/coder/websocket"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/vl0000/gomessenger/data"
+	"github.com/vl0000/gomessenger/email"
+	adminv1 "github.com/vl0000/gomessenger/gen/admin/v1"
 	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/gen/messaging/v1/messagingv1connect"
+	"github.com/vl0000/gomessenger/push"
 	"github.com/vl0000/gomessenger/server"
+	"github.com/vl0000/gomessenger/webhooks"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 func newTestingServer() (*server.MessagingServer, error) {
 
 	os.Remove("./testing.db")
+	os.Remove("./testing.db-wal")
+	os.Remove("./testing.db-shm")
 	os.Setenv("DB_SCHEMA_PATH", "./../data/database.sql")
 	db, err := data.SetupTestDatabase("./testing.db")
 	if err != nil || db == nil {
@@ -32,6 +59,46 @@ func newTestingServer() (*server.MessagingServer, error) {
 	}, nil
 }
 
+// Messages reference users, so both ends of a conversation must exist
+func createTestUsers(s *server.MessagingServer, phone_numbers ...string) error {
+	for _, phone_number := range phone_numbers {
+		_, err := s.Db.Exec(`INSERT INTO users (username, phone_number, password, salt)
+			VALUES(?, ?, '', '');`, phone_number, phone_number)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records pushes instead of sending them. Endpoints in gone get ErrGone
+type testPushSender struct {
+	sent chan string
+	gone map[string]bool
+}
+
+func (p *testPushSender) Send(ctx context.Context, sub push.Subscription, payload []byte) error {
+	if p.gone[sub.Endpoint] {
+		return push.ErrGone
+	}
+	message := &messagingv1.Message{}
+	if err := protojson.Unmarshal(payload, message); err != nil {
+		return err
+	}
+	p.sent <- sub.Endpoint + " " + message.Content
+	return nil
+}
+
+// Records emails instead of sending them
+type testEmailSender struct {
+	sent []email.Message
+}
+
+func (e *testEmailSender) Send(ctx context.Context, msg email.Message) error {
+	e.sent = append(e.sent, msg)
+	return nil
+}
+
 func TestServer(t *testing.T) {
 	t.Run("Message persists in db", func(t *testing.T) {
 		message_req := messagingv1.SendDirectMessageRequest{
@@ -46,6 +113,9 @@ func TestServer(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
 
 		// END SETUP
 		req := connect.NewRequest(&message_req)
@@ -73,6 +143,9 @@ func TestServer(t *testing.T) {
 			UserB:    "654-321",
 			FromDate: time.Now().Add(-24 * time.Hour).Format(time.DateTime),
 		})
+		if err = createTestUsers(s, req.Msg.UserA, req.Msg.UserB); err != nil {
+			t.Fatal(err)
+		}
 		_, err = s.Db.Exec(`INSERT INTO messages (sender, receiver, content, timestamp) VALUES
 			(?, ?, ?, datetime('now'));
 			`, req.Msg.UserA, req.Msg.UserB, "Hello, World!")
@@ -90,14 +163,151 @@ func TestServer(t *testing.T) {
 		}
 	})
 
+	t.Run("Unreadable messages do not hide the rest of a chat", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := make([]byte, data.KEY_SIZE)
+		rand.Read(key)
+		if s.Db.Keyring, err = data.NewKeyring(key); err != nil {
+			t.Fatal(err)
+		}
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		// A row marked as encrypted that no key can open
+		_, err = s.Db.Exec(`INSERT INTO messages (sender, receiver, content, encrypted, timestamp) VALUES
+			('123-456', '654-321', 'enc1:broken', 1, datetime('now'));`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		// Plaintext that looks like ciphertext is stored and returned as sent
+		for _, content := range []string{"enc1:x", "Hello!!!"} {
+			_, err = server.DoSendDirectMessageWork(s.Db, context.TODO(), &messagingv1.SendDirectMessageRequest{
+				Message: &messagingv1.Message{Sender: "123-456", Receiver: "654-321", Content: content},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res, err := server.DoGetDMsWork(s.Db, context.TODO(), &messagingv1.GetDMsRequest{
+			UserA:    "123-456",
+			UserB:    "654-321",
+			FromDate: time.Now().Add(-24 * time.Hour).Format(time.DateTime),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var contents []string
+		for _, message := range res.GetMessages() {
+			contents = append(contents, message.Content)
+		}
+		if !slices.Equal(contents, []string{"enc1:x", "Hello!!!"}) {
+			t.Fatalf("Expected the readable messages, got %v", contents)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Expired messages are hidden and purged", func(t *testing.T) {
+
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		ttl := uint32(60)
+		message, err := server.DoSendDirectMessageWork(s.Db, context.TODO(), &messagingv1.SendDirectMessageRequest{
+			Message: &messagingv1.Message{
+				Sender:   "123-456",
+				Receiver: "654-321",
+				Content:  "This will disappear",
+			},
+			TtlSeconds: &ttl,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Pretend the TTL has already run out
+		_, err = s.Db.Exec(`UPDATE messages SET expires_at = datetime('now', '-1 minute') WHERE id = ?;`, message.GetId())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		res, err := server.DoGetDMsWork(s.Db, context.TODO(), &messagingv1.GetDMsRequest{
+			UserA:    "123-456",
+			UserB:    "654-321",
+			FromDate: time.Now().Add(-24 * time.Hour).Format(time.DateTime),
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(res.GetMessages()) != 0 {
+			t.Fatal("Expired message was returned")
+		}
+
+		expired, err := server.DoPurgeExpiredMessagesWork(s.Db, context.TODO())
+		if err != nil {
+			t.Fatal(err)
+		} else if len(expired) != 1 || expired[0].GetId() != message.GetId() {
+			t.Fatalf("Expected message %d to be purged, got %v", message.GetId(), expired)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Streams that stop reading do not block senders", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, phone_number := range []string{"123-456", "654-321"} {
+			_, err = s.Db.Exec(`INSERT INTO users (username, phone_number, password, salt)
+				VALUES(?, ?, '', '');`, phone_number, phone_number)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// A stream whose channel is never read from
+		s.Conns = map[string]chan *messagingv1.GetDMsResponse{
+			"654-321123-456": make(chan *messagingv1.GetDMsResponse),
+		}
+		jwt_str, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := connect.NewRequest(&messagingv1.SendDirectMessageRequest{
+			Message: &messagingv1.Message{Sender: "123-456", Receiver: "654-321", Content: "Hello!!!"},
+		})
+		req.Header().Set("Authorization", jwt_str)
+		// END SETUP
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := s.SendDirectMessage(context.TODO(), req)
+			done <- err
+		}()
+		select {
+		case err = <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("SendDirectMessage blocked on the stream")
+		}
+		os.Remove("./testing.db")
+	})
+
 	t.Run("Login requests", func(t *testing.T) {
 
 		// A SECRET KEY MUST BE SET FOR THIS TEST TO RUN CORRECTLY!!!
-		const (
-			PBKDF_KEY_LEN int = 32
-			PBKDF_ITER    int = 16384
-		)
-
 		req := messagingv1.LoginRequest{
 			PhoneNumber: "123-456",
 			Password:    "123456",
@@ -112,7 +322,7 @@ func TestServer(t *testing.T) {
 		salt := make([]byte, 24)
 		rand.Read(salt)
 
-		hashed_password, err := pbkdf2.Key(sha512.New, req.PhoneNumber, salt, PBKDF_ITER, PBKDF_KEY_LEN)
+		hashed_password, err := pbkdf2.Key(sha512.New, req.Password, salt, server.PBKDF_ITER, server.PBKDF_KEY_LEN)
 
 		_, err = s.Db.Exec(`INSERT INTO users (
 			username,phone_number, password, salt)
@@ -128,6 +338,22 @@ func TestServer(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+
+		wrong := connect.NewRequest(&messagingv1.LoginRequest{PhoneNumber: req.PhoneNumber, Password: "wrong"})
+		if _, err = s.Login(context.TODO(), wrong); err == nil {
+			t.Fatal("Login succeeded with a wrong password")
+		}
+
+		// Suspension is only revealed to callers who know the password
+		if _, err = s.Db.Exec(`UPDATE users SET suspended = 1 WHERE phone_number = ?;`, req.PhoneNumber); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.Login(context.TODO(), wrong); err == nil || connect.CodeOf(err) == connect.CodePermissionDenied {
+			t.Fatalf("Expected a plain login failure, got %v", err)
+		}
+		if _, err = s.Login(context.TODO(), connect.NewRequest(&req)); connect.CodeOf(err) != connect.CodePermissionDenied {
+			t.Fatalf("Expected the suspended user to be denied, got %v", err)
+		}
 		os.Remove("./testing.db")
 	})
 
@@ -152,4 +378,1667 @@ func TestServer(t *testing.T) {
 		os.Remove("./testing.db")
 	})
 
+	t.Run("Admin procedures require the admin role", func(t *testing.T) {
+		// SETUP
+		// A SECRET_KEY MUST BE SET FOR THIS TEST TO RUN CORRECTLY!!!
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := s.RegisterUser(context.TODO(), connect.NewRequest(&messagingv1.RegisterUserRequest{
+			Username:    "John Doe",
+			PhoneNumber: "123-456",
+			Password:    "12345678",
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		req := connect.NewRequest(&adminv1.BackupDatabaseRequest{})
+		req.Header().Set("Authorization", res.Msg.JwtToken)
+		_, err = s.BackupDatabase(context.TODO(), req)
+		if connect.CodeOf(err) != connect.CodePermissionDenied {
+			t.Fatalf("Expected permission denied, got %v", err)
+		}
+
+		if err = server.SetUserRole(s.Db, "123-456", server.ROLE_ADMIN); err != nil {
+			t.Fatal(err)
+		}
+		jwt_str, err := server.GenJWTString(s.TokenAuth, "123-456", "John Doe", server.ROLE_ADMIN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.BackupDir = t.TempDir()
+		req.Header().Set("Authorization", jwt_str)
+		backup, err := s.BackupDatabase(context.TODO(), req)
+		if err != nil {
+			t.Fatal(err)
+		} else if backup.Msg.SizeBytes == 0 {
+			t.Fatal("Backup is empty")
+		}
+
+		// The token still claims the admin role after a demotion
+		if err = server.SetUserRole(s.Db, "123-456", server.ROLE_USER); err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.BackupDatabase(context.TODO(), req)
+		if connect.CodeOf(err) != connect.CodePermissionDenied {
+			t.Fatalf("Expected permission denied after the demotion, got %v", err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Admins manage users and content", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Deleting users must not rely on ON DELETE CASCADE
+		s.Db.Close()
+		opts := data.DefaultOptions()
+		opts.SchemaPath = os.Getenv("DB_SCHEMA_PATH")
+		opts.ForeignKeys = false
+		if s.Db, err = data.SetupDatabase("./testing.db", opts); err != nil {
+			t.Fatal(err)
+		}
+		if err = createTestUsers(s, "111-111", "222-222", "333-333"); err != nil {
+			t.Fatal(err)
+		}
+		if err = server.SetUserRole(s.Db, "111-111", server.ROLE_ADMIN); err != nil {
+			t.Fatal(err)
+		}
+		admin_jwt, err := server.GenJWTString(s.TokenAuth, "111-111", "admin", server.ROLE_ADMIN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user_jwt, err := server.GenJWTString(s.TokenAuth, "222-222", "user", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var message_id uint64
+		err = s.Db.QueryRow(`INSERT INTO messages (sender, receiver, content, timestamp)
+			VALUES ('222-222', '333-333', 'Hello', datetime('now')) RETURNING id;`).Scan(&message_id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Db.Exec(`INSERT INTO messages (sender, receiver, content, timestamp)
+			VALUES ('333-333', '222-222', 'Hi', datetime('now'));`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		admin := func(req connect.AnyRequest) {
+			req.Header().Set("Authorization", admin_jwt)
+		}
+		userCanRead := func() error {
+			req := connect.NewRequest(&messagingv1.GetUserInfoRequest{PhoneNumber: "333-333"})
+			req.Header().Set("Authorization", user_jwt)
+			_, err := s.GetUserInfo(context.TODO(), req)
+			return err
+		}
+		// END SETUP
+
+		list_req := connect.NewRequest(&adminv1.ListUsersRequest{PageSize: 2})
+		admin(list_req)
+		page, err := s.ListUsers(context.TODO(), list_req)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(page.Msg.Users) != 2 || page.Msg.NextPageToken == "" {
+			t.Fatalf("Expected a full first page, got %v", page.Msg)
+		}
+		list_req.Msg.PageToken = page.Msg.NextPageToken
+		page, err = s.ListUsers(context.TODO(), list_req)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(page.Msg.Users) != 1 || page.Msg.Users[0].PhoneNumber != "333-333" || page.Msg.NextPageToken != "" {
+			t.Fatalf("Expected the last user alone, got %v", page.Msg)
+		}
+
+		get_req := connect.NewRequest(&adminv1.GetUserRequest{PhoneNumber: "222-222"})
+		admin(get_req)
+		user, err := s.GetUser(context.TODO(), get_req)
+		if err != nil {
+			t.Fatal(err)
+		} else if user.Msg.User.MessagesSent != 1 || user.Msg.User.MessagesReceived != 1 {
+			t.Fatalf("Wrong message counts %v", user.Msg.User)
+		}
+
+		suspend_req := connect.NewRequest(&adminv1.SuspendUserRequest{PhoneNumber: "222-222", Suspended: true})
+		admin(suspend_req)
+		if _, err = s.SuspendUser(context.TODO(), suspend_req); err != nil {
+			t.Fatal(err)
+		}
+		if connect.CodeOf(userCanRead()) != connect.CodeUnauthenticated {
+			t.Fatal("Suspended user can still use their token")
+		}
+		suspend_req.Msg.Suspended = false
+		if _, err = s.SuspendUser(context.TODO(), suspend_req); err != nil {
+			t.Fatal(err)
+		}
+		if err = userCanRead(); err != nil {
+			t.Fatalf("Suspension was not lifted: %v", err)
+		}
+
+		logout_req := connect.NewRequest(&adminv1.ForceLogoutRequest{PhoneNumber: "222-222"})
+		admin(logout_req)
+		if _, err = s.ForceLogout(context.TODO(), logout_req); err != nil {
+			t.Fatal(err)
+		}
+		if connect.CodeOf(userCanRead()) != connect.CodeUnauthenticated {
+			t.Fatal("Token issued before ForceLogout is still accepted")
+		}
+
+		delete_message_req := connect.NewRequest(&adminv1.DeleteMessageRequest{Id: message_id})
+		admin(delete_message_req)
+		if _, err = s.DeleteMessage(context.TODO(), delete_message_req); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.DeleteMessage(context.TODO(), delete_message_req); connect.CodeOf(err) != connect.CodeNotFound {
+			t.Fatalf("Expected not found, got %v", err)
+		}
+
+		// A bot of the deleted user goes with them
+		if err = createTestUsers(s, "444-444"); err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Db.Exec(`UPDATE users SET bot_owner = '222-222' WHERE phone_number = '444-444';
+			INSERT INTO bot_commands (bot, name, description) VALUES ('444-444', 'help', 'Lists the commands');
+			INSERT INTO webhooks (id, owner, url, secret, events, created_at)
+				VALUES (7, '222-222', 'https://example.com/hook', 'secret', 'message.received', datetime('now'));
+			INSERT INTO webhook_deliveries (webhook_id, event, subject, next_attempt_at, created_at)
+				VALUES (7, 'message.received', '1', datetime('now'), datetime('now'));`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		delete_user_req := connect.NewRequest(&adminv1.DeleteUserRequest{PhoneNumber: "222-222"})
+		admin(delete_user_req)
+		deleted, err := s.DeleteUser(context.TODO(), delete_user_req)
+		if err != nil {
+			t.Fatal(err)
+		} else if deleted.Msg.DeletedMessages != 1 {
+			t.Fatalf("Expected 1 deleted message, got %d", deleted.Msg.DeletedMessages)
+		} else if len(deleted.Msg.DeletedBots) != 1 || deleted.Msg.DeletedBots[0] != "444-444" {
+			t.Fatalf("Expected the bot to be deleted, got %v", deleted.Msg.DeletedBots)
+		}
+		for _, table := range []string{"webhooks", "webhook_deliveries", "bot_commands", "api_keys"} {
+			var left int
+			if err = s.Db.QueryRow(`SELECT COUNT(*) FROM ` + table + `;`).Scan(&left); err != nil {
+				t.Fatal(err)
+			} else if left != 0 {
+				t.Fatalf("Deleted users left %d rows in %s", left, table)
+			}
+		}
+		if _, err = s.GetUser(context.TODO(), get_req); connect.CodeOf(err) != connect.CodeNotFound {
+			t.Fatalf("Expected not found, got %v", err)
+		}
+
+		stats_req := connect.NewRequest(&adminv1.GetStatsRequest{})
+		admin(stats_req)
+		stats, err := s.GetStats(context.TODO(), stats_req)
+		if err != nil {
+			t.Fatal(err)
+		} else if stats.Msg.Users != 2 || stats.Msg.Messages != 0 || stats.Msg.DatabaseSizeBytes == 0 {
+			t.Fatalf("Wrong stats %v", stats.Msg)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Suspending a user ends their open streams", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.ShutdownTimeout = 5 * time.Second
+		s.LoadRoutes()
+		if err = createTestUsers(s, "111-111", "222-222", "333-333"); err != nil {
+			t.Fatal(err)
+		}
+		if err = server.SetUserRole(s.Db, "111-111", server.ROLE_ADMIN); err != nil {
+			t.Fatal(err)
+		}
+		admin_jwt, err := server.GenJWTString(s.TokenAuth, "111-111", "admin", server.ROLE_ADMIN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user_jwt, err := server.GenJWTString(s.TokenAuth, "222-222", "user", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error)
+		go func() { served <- s.Serve(listener) }()
+		base_url := "http://" + listener.Addr().String()
+		// END SETUP
+
+		client := messagingv1connect.NewMessagingServiceClient(http.DefaultClient, base_url)
+		req := connect.NewRequest(&messagingv1.GetDMsRequest{
+			UserA:    "222-222",
+			UserB:    "333-333",
+			FromDate: time.Now().Add(-time.Minute).Format(time.DateTime),
+		})
+		req.Header().Set("Authorization", user_jwt)
+		stream, err := client.GetDMs(context.TODO(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.Close()
+		if !stream.Receive() {
+			t.Fatalf("Stream ended before the history was sent: %v", stream.Err())
+		}
+		events, err := http.Get(base_url + "/events?token=" + user_jwt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer events.Body.Close()
+		ended := make(chan struct{})
+		go func() {
+			io.Copy(io.Discard, events.Body)
+			close(ended)
+		}()
+
+		suspend_req := connect.NewRequest(&adminv1.SuspendUserRequest{PhoneNumber: "222-222", Suspended: true})
+		suspend_req.Header().Set("Authorization", admin_jwt)
+		if _, err = s.SuspendUser(context.TODO(), suspend_req); err != nil {
+			t.Fatal(err)
+		}
+		if stream.Receive() {
+			t.Fatal("Expected the stream to end")
+		}
+		if connect.CodeOf(stream.Err()) != connect.CodeUnauthenticated {
+			t.Fatalf("Expected unauthenticated, got %v", stream.Err())
+		}
+		select {
+		case <-ended:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Events stream of the suspended user is still open")
+		}
+
+		s.Shutdown()
+		if err = <-served; err != nil {
+			t.Fatal(err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Streams outlive the request timeout", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.Router.Use(middleware.Timeout(100 * time.Millisecond))
+		s.ShutdownTimeout = 5 * time.Second
+		s.LoadRoutes()
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		sender_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver_jwt, err := server.GenJWTString(s.TokenAuth, "654-321", "654-321", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error)
+		go func() { served <- s.Serve(listener) }()
+		base_url := "http://" + listener.Addr().String()
+		// END SETUP
+
+		client := messagingv1connect.NewMessagingServiceClient(http.DefaultClient, base_url)
+		req := connect.NewRequest(&messagingv1.GetDMsRequest{
+			UserA:    "654-321",
+			UserB:    "123-456",
+			FromDate: time.Now().Add(-time.Minute).Format(time.DateTime),
+		})
+		req.Header().Set("Authorization", receiver_jwt)
+		stream, err := client.GetDMs(context.TODO(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.Close()
+		if !stream.Receive() {
+			t.Fatalf("Stream ended before the history was sent: %v", stream.Err())
+		}
+
+		time.Sleep(300 * time.Millisecond)
+		send_req := connect.NewRequest(&messagingv1.SendDirectMessageRequest{Message: &messagingv1.Message{
+			Sender: "123-456", Receiver: "654-321", Content: "Still there?",
+		}})
+		send_req.Header().Set("Authorization", sender_jwt)
+		if _, err = s.SendDirectMessage(context.TODO(), send_req); err != nil {
+			t.Fatal(err)
+		}
+		if !stream.Receive() {
+			t.Fatalf("Stream ended at the request timeout: %v", stream.Err())
+		} else if len(stream.Msg().Messages) != 1 || stream.Msg().Messages[0].Content != "Still there?" {
+			t.Fatalf("Unexpected messages %v", stream.Msg().Messages)
+		}
+
+		s.Shutdown()
+		if err = <-served; err != nil {
+			t.Fatal(err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("REST gateway transcodes to the handlers", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.LoadRoutes()
+		if err = createTestUsers(s, "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		call := func(method string, path string, token string, body string) (int, map[string]any) {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				req.Header.Set("Authorization", token)
+			}
+			rec := httptest.NewRecorder()
+			s.Router.ServeHTTP(rec, req)
+			res := map[string]any{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			return rec.Code, res
+		}
+		// END SETUP
+
+		code, res := call(http.MethodPost, "/v1/users", "",
+			`{"username": "John Doe", "phoneNumber": "123-456", "password": "12345678"}`)
+		if code != http.StatusOK || res["jwtToken"] == "" {
+			t.Fatalf("Registration failed with %d: %v", code, res)
+		}
+		token := res["jwtToken"].(string)
+
+		code, res = call(http.MethodPost, "/v1/messages", token,
+			`{"message": {"sender": "123-456", "receiver": "654-321", "content": "Hello over REST"}}`)
+		if code != http.StatusOK {
+			t.Fatalf("Sending failed with %d: %v", code, res)
+		}
+		if code, _ = call(http.MethodPost, "/v1/messages", "", `{"message": {}}`); code == http.StatusOK {
+			t.Fatal("Message without a token was accepted")
+		}
+
+		from := url.QueryEscape(time.Now().UTC().Add(-time.Minute).Format(time.DateTime))
+		code, res = call(http.MethodGet, "/v1/conversations/654-321/messages?user_a=123-456&from_date="+from, token, "")
+		if code != http.StatusOK {
+			t.Fatalf("Listing failed with %d: %v", code, res)
+		}
+		messages, _ := res["messages"].([]any)
+		if len(messages) != 1 || messages[0].(map[string]any)["content"] != "Hello over REST" {
+			t.Fatalf("Unexpected messages %v", res)
+		}
+
+		code, res = call(http.MethodGet, "/v1/users/654-321", token, "")
+		if code != http.StatusOK || res["phoneNumber"] != "654-321" {
+			t.Fatalf("User lookup failed with %d: %v", code, res)
+		}
+
+		code, res = call(http.MethodGet, "/openapi.json", "", "")
+		paths, _ := res["paths"].(map[string]any)
+		if code != http.StatusOK || paths["/v1/conversations/{user_b}/messages"] == nil {
+			t.Fatalf("OpenAPI document is missing paths: %v", paths)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Reflection describes the services when enabled", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.Reflection = true
+		s.LoadRoutes()
+		// Reflection is a bidirectional stream, so the client needs HTTP/2
+		ts := httptest.NewUnstartedServer(s.Router)
+		ts.EnableHTTP2 = true
+		ts.StartTLS()
+		defer ts.Close()
+		// END SETUP
+
+		stream := grpcreflect.NewClient(ts.Client(), ts.URL).NewStream(context.TODO())
+		defer stream.Close()
+		services, err := stream.ListServices()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(services, messagingv1connect.MessagingServiceName) {
+			t.Fatalf("MessagingService is not listed in %v", services)
+		}
+		files, err := stream.FileContainingSymbol("messaging.v1.GetDMsRequest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 || files[0].GetName() != "messaging/v1/messaging.proto" {
+			t.Fatalf("Unexpected files %v", files)
+		}
+
+		s.Router = chi.NewRouter()
+		s.Reflection = false
+		s.LoadRoutes()
+		disabled := httptest.NewUnstartedServer(s.Router)
+		disabled.EnableHTTP2 = true
+		disabled.StartTLS()
+		defer disabled.Close()
+		if _, err := grpcreflect.NewClient(disabled.Client(), disabled.URL).NewStream(context.TODO()).ListServices(); err == nil {
+			t.Fatal("Reflection is served while disabled")
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("WebSocket gateway relays frames between users", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.ShutdownTimeout = 5 * time.Second
+		s.LoadRoutes()
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error)
+		go func() { served <- s.Serve(listener) }()
+		url := "ws://" + listener.Addr().String() + "/ws"
+
+		dial := func(phone_number string, subprotocol string) *websocket.Conn {
+			jwt_str, err := server.GenJWTString(s.TokenAuth, phone_number, phone_number, server.ROLE_USER)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, _, err := websocket.Dial(context.TODO(), url+"?token="+jwt_str, &websocket.DialOptions{
+				Subprotocols: []string{subprotocol},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return conn
+		}
+		send := func(conn *websocket.Conn, frame *messagingv1.ClientFrame) {
+			kind := websocket.MessageText
+			payload, err := protojson.Marshal(frame)
+			if conn.Subprotocol() == server.WS_PROTO_SUBPROTOCOL {
+				kind = websocket.MessageBinary
+				payload, err = proto.Marshal(frame)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := conn.Write(context.TODO(), kind, payload); err != nil {
+				t.Fatal(err)
+			}
+		}
+		receive := func(conn *websocket.Conn) *messagingv1.ServerFrame {
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+			kind, payload, err := conn.Read(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			frame := &messagingv1.ServerFrame{}
+			if kind == websocket.MessageBinary {
+				err = proto.Unmarshal(payload, frame)
+			} else {
+				err = protojson.Unmarshal(payload, frame)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			return frame
+		}
+		// END SETUP
+
+		if _, res, err := websocket.Dial(context.TODO(), url, nil); err == nil || res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected a connection without a token to be refused, got %v", err)
+		}
+
+		alice := dial("123-456", server.WS_PROTO_SUBPROTOCOL)
+		defer alice.CloseNow()
+		bob := dial("654-321", server.WS_JSON_SUBPROTOCOL)
+		defer bob.CloseNow()
+
+		send(bob, &messagingv1.ClientFrame{Id: "1", Frame: &messagingv1.ClientFrame_Subscribe{
+			Subscribe: &messagingv1.GetDMsRequest{
+				UserA:    "654-321",
+				UserB:    "123-456",
+				FromDate: time.Now().UTC().Add(-time.Minute).Format(time.DateTime),
+			},
+		}})
+		if frame := receive(bob); frame.Id != "1" || frame.GetDms() == nil {
+			t.Fatalf("Expected the history, got %v", frame)
+		}
+
+		send(alice, &messagingv1.ClientFrame{Id: "2", Frame: &messagingv1.ClientFrame_Send{
+			Send: &messagingv1.SendDirectMessageRequest{Message: &messagingv1.Message{
+				Sender: "123-456", Receiver: "654-321", Content: "Hello over a WebSocket",
+			}},
+		}})
+		sent := receive(alice)
+		if sent.Id != "2" || sent.GetSent().GetMessage().GetId() == 0 {
+			t.Fatalf("Expected the sent message, got %v", sent)
+		}
+		update := receive(bob)
+		if update.UserB != "123-456" || len(update.GetDms().GetMessages()) != 1 ||
+			update.GetDms().Messages[0].Content != "Hello over a WebSocket" {
+			t.Fatalf("Expected the new message, got %v", update)
+		}
+
+		send(alice, &messagingv1.ClientFrame{Frame: &messagingv1.ClientFrame_Typing{
+			Typing: &messagingv1.Typing{Receiver: "654-321"},
+		}})
+		if update = receive(bob); !update.GetDms().GetTyping() {
+			t.Fatalf("Expected a typing update, got %v", update)
+		}
+
+		send(alice, &messagingv1.ClientFrame{Id: "3", Frame: &messagingv1.ClientFrame_Send{
+			Send: &messagingv1.SendDirectMessageRequest{Message: &messagingv1.Message{
+				Sender: "654-321", Receiver: "123-456", Content: "Impersonation",
+			}},
+		}})
+		if frame := receive(alice); frame.Id != "3" || frame.GetError().GetCode() != connect.CodeUnauthenticated.String() {
+			t.Fatalf("Expected an unauthenticated error, got %v", frame)
+		}
+
+		go s.Shutdown()
+		if _, _, err := bob.Read(context.TODO()); websocket.CloseStatus(err) != websocket.StatusServiceRestart {
+			t.Fatalf("Expected the connection to close for a restart, got %v", err)
+		}
+		if err = <-served; err != nil {
+			t.Fatal(err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Events stream incoming messages and resume from Last-Event-ID", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.ShutdownTimeout = 5 * time.Second
+		s.HeartbeatInterval = 10 * time.Millisecond
+		s.LoadRoutes()
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error)
+		go func() { served <- s.Serve(listener) }()
+		base_url := "http://" + listener.Addr().String()
+
+		sender_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver_jwt, err := server.GenJWTString(s.TokenAuth, "654-321", "654-321", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := messagingv1connect.NewMessagingServiceClient(http.DefaultClient, base_url)
+		send := func(content string) uint64 {
+			req := connect.NewRequest(&messagingv1.SendDirectMessageRequest{Message: &messagingv1.Message{
+				Sender: "123-456", Receiver: "654-321", Content: content,
+			}})
+			req.Header().Set("Authorization", sender_jwt)
+			res, err := client.SendDirectMessage(context.TODO(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return res.Msg.Message.GetId()
+		}
+		// END SETUP
+
+		res, err := http.Get(base_url + "/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 without a token, got %d", res.StatusCode)
+		}
+
+		first := send("Sent while offline")
+		second := send("Also sent while offline")
+
+		req, _ := http.NewRequest(http.MethodGet, base_url+"/events?token="+receiver_jwt, nil)
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(first, 10))
+		res, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Unexpected content type %s", res.Header.Get("Content-Type"))
+		}
+		lines := bufio.NewScanner(res.Body)
+		seen := []string{}
+		expect := func(want string) {
+			for lines.Scan() {
+				seen = append(seen, lines.Text())
+				if lines.Text() == want {
+					return
+				}
+			}
+			t.Fatalf("Stream ended before %q: %v", want, lines.Err())
+		}
+
+		expect(fmt.Sprintf("id: %d", second))
+		third := send("Sent while online")
+		expect(fmt.Sprintf("id: %d", third))
+		expect(": heartbeat")
+
+		for _, line := range seen {
+			if line == fmt.Sprintf("id: %d", first) {
+				t.Fatal("Message before Last-Event-ID was replayed")
+			}
+		}
+
+		go s.Shutdown()
+		for lines.Scan() {
+		}
+		if err = <-served; err != nil {
+			t.Fatal(err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Webhooks receive message events", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetupWebhooks(webhooks.Options{MaxAttempts: 3, Timeout: time.Second, AllowPrivateAddresses: true})
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		jwt_str, err := server.GenJWTString(s.TokenAuth, "654-321", "654-321", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sender_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		received := make(chan map[string]any, 1)
+		var secret string
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if err := webhooks.Verify(secret, r.Header.Get(webhooks.SIGNATURE_HEADER), body, time.Minute); err != nil {
+				t.Error(err)
+			}
+			delivery := map[string]any{}
+			json.Unmarshal(body, &delivery)
+			received <- delivery
+		}))
+		defer receiver.Close()
+		// END SETUP
+
+		create := connect.NewRequest(&messagingv1.CreateWebhookRequest{
+			Url:    receiver.URL,
+			Events: []string{webhooks.EVENT_MESSAGE_RECEIVED},
+			Global: true,
+		})
+		create.Header().Set("Authorization", jwt_str)
+		if _, err = s.CreateWebhook(context.TODO(), create); connect.CodeOf(err) != connect.CodePermissionDenied {
+			t.Fatalf("Expected users to be denied global webhooks, got %v", err)
+		}
+		create.Msg.Global = false
+		created, err := s.CreateWebhook(context.TODO(), create)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret = created.Msg.Secret
+
+		send := connect.NewRequest(&messagingv1.SendDirectMessageRequest{Message: &messagingv1.Message{
+			Sender: "123-456", Receiver: "654-321", Content: "Ticket #42 is resolved",
+		}})
+		send.Header().Set("Authorization", sender_jwt)
+		if _, err = s.SendDirectMessage(context.TODO(), send); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.Webhooks.DeliverDue(context.TODO()); err != nil {
+			t.Fatal(err)
+		}
+
+		delivery := <-received
+		message, _ := delivery["data"].(map[string]any)
+		if delivery["event"] != webhooks.EVENT_MESSAGE_RECEIVED || message["content"] != "Ticket #42 is resolved" {
+			t.Fatalf("Unexpected delivery %v", delivery)
+		}
+
+		list := connect.NewRequest(&messagingv1.ListWebhookDeliveriesRequest{WebhookId: created.Msg.Webhook.Id})
+		list.Header().Set("Authorization", jwt_str)
+		deliveries, err := s.ListWebhookDeliveries(context.TODO(), list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries.Msg.Deliveries) != 1 || deliveries.Msg.Deliveries[0].Status != webhooks.STATUS_DELIVERED {
+			t.Fatalf("Unexpected delivery history %v", deliveries.Msg.Deliveries)
+		}
+		list.Header().Set("Authorization", sender_jwt)
+		if _, err = s.ListWebhookDeliveries(context.TODO(), list); connect.CodeOf(err) != connect.CodeNotFound {
+			t.Fatalf("Expected another user's webhook to be hidden, got %v", err)
+		}
+
+		// A demoted admin's token still claims the role
+		demoted_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_ADMIN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		create.Msg.Global = true
+		create.Header().Set("Authorization", demoted_jwt)
+		if _, err = s.CreateWebhook(context.TODO(), create); connect.CodeOf(err) != connect.CodePermissionDenied {
+			t.Fatalf("Expected a demoted admin to be denied global webhooks, got %v", err)
+		}
+		list.Header().Set("Authorization", demoted_jwt)
+		if _, err = s.ListWebhookDeliveries(context.TODO(), list); connect.CodeOf(err) != connect.CodeNotFound {
+			t.Fatalf("Expected a demoted admin not to see another user's webhook, got %v", err)
+		}
+		delete_req := connect.NewRequest(&messagingv1.DeleteWebhookRequest{Id: created.Msg.Webhook.Id})
+		delete_req.Header().Set("Authorization", demoted_jwt)
+		if _, err = s.DeleteWebhook(context.TODO(), delete_req); connect.CodeOf(err) != connect.CodeNotFound {
+			t.Fatalf("Expected a demoted admin not to delete another user's webhook, got %v", err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("The IP rate limit applies before API keys are looked up", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = createTestUsers(s, "123-456"); err != nil {
+			t.Fatal(err)
+		}
+		owner_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		create_bot := connect.NewRequest(&messagingv1.CreateBotRequest{
+			Name: "support", Username: "Support", Scopes: []string{server.SCOPE_MESSAGES_READ},
+		})
+		create_bot.Header().Set("Authorization", owner_jwt)
+		bot, err := s.CreateBot(context.TODO(), create_bot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler := s.RateLimit(2, 10, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		call := func(token string) int {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			return rec.Code
+		}
+		// END SETUP
+
+		if code := call(bot.Msg.ApiKey); code != http.StatusOK {
+			t.Fatalf("Expected the bot to be let through, got %d", code)
+		}
+		if code := call("gmk_unknown_secret"); code != http.StatusOK {
+			t.Fatalf("Expected the first invalid key to be let through, got %d", code)
+		}
+		if code := call(bot.Msg.ApiKey); code != http.StatusTooManyRequests {
+			t.Fatalf("Expected the IP limit to apply to keys, got %d", code)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Bots authenticate with scoped API keys", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.Router.Use(s.RateLimit(100, 3, time.Minute))
+		s.LoadRoutes()
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		owner_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		call := func(method string, path string, token string, body string) (int, map[string]any) {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", token)
+			rec := httptest.NewRecorder()
+			s.Router.ServeHTTP(rec, req)
+			res := map[string]any{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			return rec.Code, res
+		}
+		// END SETUP
+
+		code, res := call(http.MethodPost, "/v1/bots", owner_jwt,
+			`{"name": "support", "username": "Support", "scopes": ["messages:send"]}`)
+		if code != http.StatusOK {
+			t.Fatalf("Bot creation failed with %d: %v", code, res)
+		}
+		key := res["apiKey"].(string)
+		if !server.IsAPIKey(key) {
+			t.Fatalf("Unexpected API key %q", key)
+		}
+
+		code, res = call(http.MethodPost, "/v1/messages", key,
+			`{"message": {"sender": "bot-support", "receiver": "654-321", "content": "Your ticket was updated"}}`)
+		if code != http.StatusOK {
+			t.Fatalf("Bot could not send with %d: %v", code, res)
+		}
+		if code, _ = call(http.MethodGet, "/v1/users/654-321", key, ""); code != http.StatusForbidden {
+			t.Fatalf("Expected a procedure outside the key's scopes to be denied, got %d", code)
+		}
+
+		// Rotation keeps the old scopes unless new ones are given
+		code, res = call(http.MethodPost, "/v1/bots/bot-support/key", owner_jwt, `{}`)
+		if code != http.StatusOK {
+			t.Fatalf("Rotation failed with %d: %v", code, res)
+		}
+		rotated := res["apiKey"].(string)
+		if code, _ = call(http.MethodPost, "/v1/messages", key,
+			`{"message": {"sender": "bot-support", "receiver": "654-321", "content": "Old key"}}`); code != http.StatusUnauthorized {
+			t.Fatalf("Expected the rotated key to be rejected, got %d", code)
+		}
+
+		// The first use of the new key is its third request in the window
+		code, _ = call(http.MethodPost, "/v1/messages", rotated,
+			`{"message": {"sender": "bot-support", "receiver": "654-321", "content": "New key"}}`)
+		if code != http.StatusOK {
+			t.Fatalf("Rotated key could not send, got %d", code)
+		}
+		if code, _ = call(http.MethodPost, "/v1/messages", rotated, `{"message": {}}`); code != http.StatusTooManyRequests {
+			t.Fatalf("Expected the bot rate limit, got %d", code)
+		}
+		if code, _ = call(http.MethodGet, "/v1/bots", owner_jwt, ""); code != http.StatusOK {
+			t.Fatalf("Expected users to keep their own limit, got %d", code)
+		}
+
+		// Without the password the login fails like any other, not telling that it is a bot
+		login := connect.NewRequest(&messagingv1.LoginRequest{PhoneNumber: "bot-support", Password: "anything"})
+		if _, err = s.Login(context.TODO(), login); err == nil || connect.CodeOf(err) == connect.CodePermissionDenied {
+			t.Fatalf("Expected a plain login failure, got %v", err)
+		}
+
+		other_jwt, err := server.GenJWTString(s.TokenAuth, "654-321", "654-321", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code, _ = call(http.MethodDelete, "/v1/bots/bot-support", other_jwt, ""); code != http.StatusNotFound {
+			t.Fatalf("Expected another user's bot to be hidden, got %d", code)
+		}
+		if code, _ = call(http.MethodDelete, "/v1/bots/bot-support", owner_jwt, ""); code != http.StatusOK {
+			t.Fatalf("Bot deletion failed with %d", code)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Incoming webhooks post into their conversation", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.ShutdownTimeout = 5 * time.Second
+		s.LoadRoutes()
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error)
+		go func() { served <- s.Serve(listener) }()
+		base_url := "http://" + listener.Addr().String()
+
+		owner_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver_jwt, err := server.GenJWTString(s.TokenAuth, "654-321", "654-321", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := messagingv1connect.NewMessagingServiceClient(http.DefaultClient, base_url)
+		post := func(path string, content_type string, body string) (int, string) {
+			res, err := http.Post(base_url+path, content_type, strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			text, _ := io.ReadAll(res.Body)
+			return res.StatusCode, strings.TrimSpace(string(text))
+		}
+		// END SETUP
+
+		create := connect.NewRequest(&messagingv1.CreateIncomingWebhookRequest{Receiver: "654-321", Name: "CI"})
+		create.Header().Set("Authorization", owner_jwt)
+		created, err := client.CreateIncomingWebhook(context.TODO(), create)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := created.Msg.Path
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		subscribe := connect.NewRequest(&messagingv1.GetDMsRequest{
+			UserA:    "654-321",
+			UserB:    "123-456",
+			FromDate: time.Now().UTC().Add(-time.Minute).Format(time.DateTime),
+		})
+		subscribe.Header().Set("Authorization", receiver_jwt)
+		stream, err := client.GetDMs(ctx, subscribe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stream.Receive() {
+			t.Fatal(stream.Err())
+		}
+
+		if code, text := post(path, "application/json", `{"text": "Build #7 passed"}`); code != http.StatusOK || text != "ok" {
+			t.Fatalf("Posting failed with %d: %s", code, text)
+		}
+		if !stream.Receive() {
+			t.Fatal(stream.Err())
+		}
+		messages := stream.Msg().Messages
+		if len(messages) != 1 || messages[0].Content != "Build #7 passed" || messages[0].Sender != "123-456" {
+			t.Fatalf("Unexpected live update %v", stream.Msg())
+		}
+
+		payload := url.Values{"payload": {`{"text": "Build #8 failed"}`}}.Encode()
+		if code, text := post(path, "application/x-www-form-urlencoded", payload); code != http.StatusOK {
+			t.Fatalf("Posting a form failed with %d: %s", code, text)
+		}
+		if code, text := post(path, "application/json", `{"text": ""}`); code != http.StatusBadRequest || text != "no_text" {
+			t.Fatalf("Expected no_text, got %d: %s", code, text)
+		}
+		if code, _ := post(path+"x", "application/json", `{"text": "Forged"}`); code != http.StatusNotFound {
+			t.Fatalf("Expected a wrong token to be rejected, got %d", code)
+		}
+
+		remove := connect.NewRequest(&messagingv1.DeleteIncomingWebhookRequest{Id: created.Msg.IncomingWebhook.Id})
+		remove.Header().Set("Authorization", receiver_jwt)
+		if _, err = client.DeleteIncomingWebhook(context.TODO(), remove); connect.CodeOf(err) != connect.CodeNotFound {
+			t.Fatalf("Expected only the owner to delete the webhook, got %v", err)
+		}
+		remove.Header().Set("Authorization", owner_jwt)
+		if _, err = client.DeleteIncomingWebhook(context.TODO(), remove); err != nil {
+			t.Fatal(err)
+		}
+		if code, _ := post(path, "application/json", `{"text": "After deletion"}`); code != http.StatusNotFound {
+			t.Fatalf("Expected a deleted webhook to be rejected, got %d", code)
+		}
+
+		cancel()
+		s.Shutdown()
+		if err = <-served; err != nil {
+			t.Fatal(err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Commands run built-ins and reach bots", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetupWebhooks(webhooks.Options{MaxAttempts: 3, Timeout: time.Second, AllowPrivateAddresses: true})
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		owner_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user_jwt, err := server.GenJWTString(s.TokenAuth, "654-321", "654-321", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		received := make(chan map[string]any, 4)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			delivery := map[string]any{}
+			json.Unmarshal(body, &delivery)
+			received <- delivery
+		}))
+		defer receiver.Close()
+		send := func(jwt_str string, sender string, receiver string, content string) (*messagingv1.SendDirectMessageResponse, error) {
+			req := connect.NewRequest(&messagingv1.SendDirectMessageRequest{Message: &messagingv1.Message{
+				Sender: sender, Receiver: receiver, Content: content,
+			}})
+			req.Header().Set("Authorization", jwt_str)
+			res, err := s.SendDirectMessage(context.TODO(), req)
+			if err != nil {
+				return nil, err
+			}
+			return res.Msg, nil
+		}
+		// END SETUP
+
+		res, err := send(user_jwt, "654-321", "123-456", "/shrug fine")
+		if err != nil || res.Message.Content != "fine "+server.SHRUG {
+			t.Fatalf("Unexpected /shrug result %v, %v", res, err)
+		}
+		if res, err = send(user_jwt, "654-321", "123-456", "/me waves"); err != nil || res.Message.Content != "* 654-321 waves" {
+			t.Fatalf("Unexpected /me result %v, %v", res, err)
+		}
+		if _, err = send(user_jwt, "654-321", "000-000", "Hello"); connect.CodeOf(err) != connect.CodeNotFound {
+			t.Fatalf("Expected an unknown receiver to be not found, got %v", err)
+		}
+		if res, err = send(user_jwt, "654-321", "123-456", "/etc is full"); err != nil || res.Message.Content != "/etc is full" {
+			t.Fatalf("Expected an unknown command to be sent as text, got %v, %v", res, err)
+		}
+		if res, err = send(user_jwt, "654-321", "123-456", "//shrug"); err != nil || res.Message.Content != "/shrug" {
+			t.Fatalf("Expected // to send the text, got %v, %v", res, err)
+		}
+
+		create_bot := connect.NewRequest(&messagingv1.CreateBotRequest{
+			Name: "deployer", Username: "Deployer", Scopes: []string{server.SCOPE_MESSAGES_READ},
+		})
+		create_bot.Header().Set("Authorization", owner_jwt)
+		if _, err = s.CreateBot(context.TODO(), create_bot); err != nil {
+			t.Fatal(err)
+		}
+		commands := connect.NewRequest(&messagingv1.SetBotCommandsRequest{
+			BotId:    "bot-deployer",
+			Commands: []*messagingv1.BotCommand{{Name: "deploy", Description: "Deploys a branch"}},
+		})
+		commands.Header().Set("Authorization", user_jwt)
+		if _, err = s.SetBotCommands(context.TODO(), commands); connect.CodeOf(err) != connect.CodeNotFound {
+			t.Fatalf("Expected only the owner to set commands, got %v", err)
+		}
+		commands.Header().Set("Authorization", owner_jwt)
+		if _, err = s.SetBotCommands(context.TODO(), commands); err != nil {
+			t.Fatal(err)
+		}
+		create_webhook := connect.NewRequest(&messagingv1.CreateWebhookRequest{
+			Url:    receiver.URL,
+			Events: []string{webhooks.EVENT_COMMAND_INVOKED, webhooks.EVENT_MESSAGE_RECEIVED},
+		})
+		create_webhook.Header().Set("Authorization", owner_jwt)
+		if _, err = s.CreateWebhook(context.TODO(), create_webhook); err != nil {
+			t.Fatal(err)
+		}
+
+		list := connect.NewRequest(&messagingv1.ListCommandsRequest{UserB: "bot-deployer"})
+		list.Header().Set("Authorization", user_jwt)
+		listed, err := s.ListCommands(context.TODO(), list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := listed.Msg.Commands[len(listed.Msg.Commands)-1]; last.Name != "deploy" {
+			t.Fatalf("Expected the bot's commands after the built-ins, got %v", listed.Msg.Commands)
+		}
+
+		if _, err = send(user_jwt, "654-321", "bot-deployer", "/deploy main"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.Webhooks.DeliverDue(context.TODO()); err != nil {
+			t.Fatal(err)
+		}
+		delivery := <-received
+		data, _ := delivery["data"].(map[string]any)
+		if delivery["event"] != webhooks.EVENT_COMMAND_INVOKED || data["name"] != "deploy" || data["args"] != "main" {
+			t.Fatalf("Unexpected delivery %v", delivery)
+		}
+
+		// Muted chats do not trigger message.received
+		if res, err = send(owner_jwt, "123-456", "654-321", "/mute 1h"); err != nil || res.Notice == "" || res.Message != nil {
+			t.Fatalf("Unexpected /mute result %v, %v", res, err)
+		}
+		if _, err = send(user_jwt, "654-321", "123-456", "Are you there?"); err != nil {
+			t.Fatal(err)
+		}
+		if delivered, err := s.Webhooks.DeliverDue(context.TODO()); err != nil || delivered != 0 {
+			t.Fatalf("Expected no deliveries while muted, got %d, %v", delivered, err)
+		}
+		if _, err = send(owner_jwt, "123-456", "654-321", "/unmute"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = send(user_jwt, "654-321", "123-456", "Hello?"); err != nil {
+			t.Fatal(err)
+		}
+		if delivered, err := s.Webhooks.DeliverDue(context.TODO()); err != nil || delivered != 1 {
+			t.Fatalf("Expected a delivery after unmuting, got %d, %v", delivered, err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Web Push notifies receivers without a stream", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sender := &testPushSender{
+			sent: make(chan string, 8),
+			gone: map[string]bool{"https://push.example.com/gone": true},
+		}
+		s.Push = sender
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		sender_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver_jwt, err := server.GenJWTString(s.TokenAuth, "654-321", "654-321", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		browser_key, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		register := func(endpoint string) error {
+			req := connect.NewRequest(&messagingv1.RegisterPushSubscriptionRequest{
+				Subscription: &messagingv1.PushSubscription{
+					Endpoint: endpoint,
+					P256Dh:   base64.RawURLEncoding.EncodeToString(browser_key.PublicKey().Bytes()),
+					Auth:     base64.RawURLEncoding.EncodeToString(make([]byte, 16)),
+				},
+			})
+			req.Header().Set("Authorization", receiver_jwt)
+			_, err := s.RegisterPushSubscription(context.TODO(), req)
+			return err
+		}
+		send := func(jwt_str string, sender string, receiver string, content string) {
+			req := connect.NewRequest(&messagingv1.SendDirectMessageRequest{Message: &messagingv1.Message{
+				Sender: sender, Receiver: receiver, Content: content,
+			}})
+			req.Header().Set("Authorization", jwt_str)
+			if _, err := s.SendDirectMessage(context.TODO(), req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		next := func() string {
+			select {
+			case sent := <-sender.sent:
+				return sent
+			case <-time.After(5 * time.Second):
+				t.Fatal("No push was sent")
+				return ""
+			}
+		}
+		// END SETUP
+
+		if err = register("https://push.example.com/active"); err != nil {
+			t.Fatal(err)
+		}
+		if err = register("https://push.example.com/gone"); err != nil {
+			t.Fatal(err)
+		}
+		if err = register("not a url"); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Fatalf("Expected an invalid endpoint to be refused, got %v", err)
+		}
+
+		send(sender_jwt, "123-456", "654-321", "Are you there?")
+		if sent := next(); sent != "https://push.example.com/active Are you there?" {
+			t.Fatalf("Unexpected push %q", sent)
+		}
+
+		// Muted chats are not pushed, and the gone subscription was removed
+		send(receiver_jwt, "654-321", "123-456", "/mute")
+		send(sender_jwt, "123-456", "654-321", "While muted")
+		send(receiver_jwt, "654-321", "123-456", "/unmute")
+		send(sender_jwt, "123-456", "654-321", "After unmuting")
+		if sent := next(); sent != "https://push.example.com/active After unmuting" {
+			t.Fatalf("Unexpected push %q", sent)
+		}
+		s.Shutdown()
+		select {
+		case sent := <-sender.sent:
+			t.Fatalf("Unexpected push %q", sent)
+		default:
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Digests email unseen messages to verified addresses", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mailer := &testEmailSender{}
+		s.Email = mailer
+		s.DigestPreviews = true
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		sender_jwt, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver_jwt, err := server.GenJWTString(s.TokenAuth, "654-321", "654-321", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		setEmail := func(address string) error {
+			req := connect.NewRequest(&messagingv1.SetEmailRequest{Email: address})
+			req.Header().Set("Authorization", receiver_jwt)
+			_, err := s.SetEmail(context.TODO(), req)
+			return err
+		}
+		verify := func(token string) error {
+			req := connect.NewRequest(&messagingv1.VerifyEmailRequest{Token: token})
+			req.Header().Set("Authorization", receiver_jwt)
+			_, err := s.VerifyEmail(context.TODO(), req)
+			return err
+		}
+		setPreferences := func(msg *messagingv1.SetNotificationPreferencesRequest) (*messagingv1.NotificationPreferences, error) {
+			req := connect.NewRequest(msg)
+			req.Header().Set("Authorization", receiver_jwt)
+			res, err := s.SetNotificationPreferences(context.TODO(), req)
+			if err != nil {
+				return nil, err
+			}
+			return res.Msg.Preferences, nil
+		}
+		send := func(content string) {
+			req := connect.NewRequest(&messagingv1.SendDirectMessageRequest{Message: &messagingv1.Message{
+				Sender: "123-456", Receiver: "654-321", Content: content,
+			}})
+			req.Header().Set("Authorization", sender_jwt)
+			if _, err := s.SendDirectMessage(context.TODO(), req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		digests := func(expected int) {
+			sent, err := s.SendDigests(context.TODO())
+			if err != nil || sent != expected {
+				t.Fatalf("Expected %d digests, got %d, %v", expected, sent, err)
+			}
+		}
+		// END SETUP
+
+		if err = setEmail("not an address"); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Fatalf("Expected an invalid address to be refused, got %v", err)
+		}
+		if err = setEmail("receiver@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if len(mailer.sent) != 1 || mailer.sent[0].To != "receiver@example.com" {
+			t.Fatalf("Expected a verification email, got %v", mailer.sent)
+		}
+		token := strings.Split(mailer.sent[0].Body, "\n")[2]
+		if err = setEmail("receiver@example.com"); connect.CodeOf(err) != connect.CodeResourceExhausted {
+			t.Fatalf("Expected the resend to be refused, got %v", err)
+		}
+		if err = setEmail("other@example.com"); connect.CodeOf(err) != connect.CodeResourceExhausted {
+			t.Fatalf("Expected an email to another address to be refused, got %v", err)
+		}
+
+		// Unverified addresses get no digests
+		send("Hello")
+		digests(0)
+		if err = verify("wrong"); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Fatalf("Expected a wrong token to be refused, got %v", err)
+		}
+		if err = verify(token); err != nil {
+			t.Fatal(err)
+		}
+
+		send("Are you\nthere?")
+		digests(1)
+		digest := mailer.sent[len(mailer.sent)-1]
+		if digest.Subject != "You have 2 unread messages" ||
+			!strings.Contains(digest.Body, "123-456 (123-456): 2 new\n  Are you there?\n") {
+			t.Fatalf("Unexpected digest %q: %q", digest.Subject, digest.Body)
+		}
+		// Not due yet, and then nothing new
+		digests(0)
+		if _, err = s.Db.Exec(`UPDATE users SET last_digest_at = datetime('now', '-2 days');`); err != nil {
+			t.Fatal(err)
+		}
+		digests(0)
+
+		// Messages read in the app are seen
+		send("Seen in the app")
+		req := connect.NewRequest(&messagingv1.GetDMsRequest{UserA: "654-321", UserB: "123-456"})
+		req.Header().Set("Authorization", receiver_jwt)
+		if _, err = s.ListDMs(context.TODO(), req); err != nil {
+			t.Fatal(err)
+		}
+		digests(0)
+
+		off := "off"
+		preferences, err := setPreferences(&messagingv1.SetNotificationPreferencesRequest{EmailDigest: &off})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if preferences.Email != "receiver@example.com" || !preferences.EmailVerified ||
+			preferences.EmailDigest != "off" || !preferences.Push {
+			t.Fatalf("Unexpected preferences %v", preferences)
+		}
+		if _, err = s.Db.Exec(`UPDATE users SET last_active_at = datetime('now', '-1 hour');`); err != nil {
+			t.Fatal(err)
+		}
+		digests(0)
+		hourly, monthly := "hourly", "monthly"
+		if _, err = setPreferences(&messagingv1.SetNotificationPreferencesRequest{EmailDigest: &monthly}); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Fatalf("Expected an unknown frequency to be refused, got %v", err)
+		}
+		if _, err = setPreferences(&messagingv1.SetNotificationPreferencesRequest{EmailDigest: &hourly}); err != nil {
+			t.Fatal(err)
+		}
+		digests(1)
+		if digest = mailer.sent[len(mailer.sent)-1]; digest.Subject != "You have 1 unread message" {
+			t.Fatalf("Unexpected digest %q", digest.Subject)
+		}
+
+		// Removed addresses get nothing
+		if err = setEmail(""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.Db.Exec(`UPDATE users SET last_digest_at = NULL, digest_message_id = 0;`); err != nil {
+			t.Fatal(err)
+		}
+		digests(0)
+
+		// Removing the address does not reset the delay between verification emails
+		if err = setEmail("receiver@example.com"); connect.CodeOf(err) != connect.CodeResourceExhausted {
+			t.Fatalf("Expected the resend to be refused, got %v", err)
+		}
+		if _, err = s.Db.Exec(`UPDATE users SET email_sent_at = datetime('now', '-2 minutes');`); err != nil {
+			t.Fatal(err)
+		}
+		if err = setEmail("receiver@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Readiness fails once shutdown starts", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		rec := httptest.NewRecorder()
+		s.ServeReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+
+		s.Shutdown()
+		if err = s.Ready(context.TODO()); !errors.Is(err, server.ErrDraining) {
+			t.Fatalf("Expected the server to be draining, got %v", err)
+		}
+		rec = httptest.NewRecorder()
+		s.ServeReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected 503, got %d", rec.Code)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Health probes are served on their own plain HTTP address", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.LoadRoutes()
+		probes, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.HealthAddr = probes.Addr().String()
+		probes.Close()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error)
+		go func() { served <- s.Serve(listener) }()
+		// END SETUP
+
+		get := func(path string) int {
+			for range 50 {
+				res, err := http.Get("http://" + s.HealthAddr + path)
+				if err == nil {
+					res.Body.Close()
+					return res.StatusCode
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			t.Fatalf("Health listener did not answer %s", path)
+			return 0
+		}
+		for _, path := range []string{"/healthz", "/readyz"} {
+			if code := get(path); code != http.StatusOK {
+				t.Fatalf("Expected 200 from %s, got %d", path, code)
+			}
+		}
+		if code := get("/login"); code != http.StatusNotFound {
+			t.Fatalf("Expected only the probes on the health address, got %d", code)
+		}
+
+		s.Shutdown()
+		if err = <-served; err != nil {
+			t.Fatal(err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Shutdown ends streams with a reconnect hint", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Router = chi.NewRouter()
+		s.ShutdownTimeout = 5 * time.Second
+		s.LoadRoutes()
+		if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+		jwt_str, err := server.GenJWTString(s.TokenAuth, "123-456", "123-456", server.ROLE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error)
+		go func() { served <- s.Serve(listener) }()
+		// END SETUP
+
+		client := messagingv1connect.NewMessagingServiceClient(http.DefaultClient, "http://"+listener.Addr().String())
+		req := connect.NewRequest(&messagingv1.GetDMsRequest{
+			UserA:    "123-456",
+			UserB:    "654-321",
+			FromDate: time.Now().Add(-time.Minute).Format(time.DateTime),
+		})
+		req.Header().Set("Authorization", jwt_str)
+		stream, err := client.GetDMs(context.TODO(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.Close()
+		if !stream.Receive() {
+			t.Fatalf("Stream ended before the history was sent: %v", stream.Err())
+		}
+
+		go s.Shutdown()
+		if stream.Receive() {
+			t.Fatal("Expected the stream to end")
+		}
+		if connect.CodeOf(stream.Err()) != connect.CodeUnavailable {
+			t.Fatalf("Expected unavailable, got %v", stream.Err())
+		}
+		var connect_err *connect.Error
+		if !errors.As(stream.Err(), &connect_err) || len(connect_err.Details()) == 0 {
+			t.Fatal("Expected a RetryInfo detail")
+		}
+		if err = <-served; err != nil {
+			t.Fatal(err)
+		}
+		os.Remove("./testing.db")
+	})
+
+	t.Run("Frontend is served with caching headers", func(t *testing.T) {
+		// SETUP
+		s, err := newTestingServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		files := map[string]string{
+			"chat.html":                       "<html></html>",
+			"static/_app/immutable/app.js":    "console.log('plain')",
+			"static/_app/immutable/app.js.br": "compressed",
+			"static/_app/version.json":        "{}",
+		}
+		for name, content := range files {
+			os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700)
+			if err = os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s.Router = chi.NewRouter()
+		s.PublicDir = dir
+		s.LoadRoutes()
+		get := func(path string, header ...string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			for i := 0; i+1 < len(header); i += 2 {
+				req.Header.Set(header[i], header[i+1])
+			}
+			rec := httptest.NewRecorder()
+			s.Router.ServeHTTP(rec, req)
+			return rec
+		}
+		// END SETUP
+
+		rec := get("/_app/immutable/app.js", "Accept-Encoding", "gzip, br")
+		if rec.Header().Get("Content-Encoding") != "br" || rec.Body.String() != "compressed" {
+			t.Fatal("Brotli variant was not served")
+		}
+		if !strings.Contains(rec.Header().Get("Content-Type"), "javascript") {
+			t.Fatalf("Wrong content type %s", rec.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(rec.Header().Get("Cache-Control"), "immutable") {
+			t.Fatal("Immutable asset is not cached")
+		}
+
+		rec = get("/_app/immutable/app.js")
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != files["static/_app/immutable/app.js"] {
+			t.Fatal("Plain file was not served to a client without brotli")
+		}
+
+		rec = get("/_app/version.json")
+		if rec.Header().Get("Cache-Control") != "no-cache" {
+			t.Fatal("Mutable file must be revalidated")
+		}
+		if rec = get("/_app/version.json", "If-None-Match", rec.Header().Get("ETag")); rec.Code != http.StatusNotModified {
+			t.Fatalf("Expected 304 for a matching ETag, got %d", rec.Code)
+		}
+
+		if rec = get("/chat"); rec.Body.String() != files["chat.html"] {
+			t.Fatal("Page was not served")
+		}
+		if rec = get("/../server.go"); rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected 404, got %d", rec.Code)
+		}
+
+		s.PublicDir = ""
+		s.Router = chi.NewRouter()
+		s.LoadRoutes()
+		if rec = get("/signup"); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("Embedded page was not served, got %d", rec.Code)
+		}
+		os.Remove("./testing.db")
+	})
+
+}
+
+// Mixes SendDirectMessage writers with GetDMs readers, like a busy chat.
+// Run with: go test ./server -bench . -run ^$
+func BenchmarkMessaging(b *testing.B) {
+	for _, journal_mode := range []string{"DELETE", "WAL"} {
+		b.Run(journal_mode, func(b *testing.B) {
+			// SETUP
+			opts := data.DefaultOptions()
+			opts.SchemaPath = "./../data/database.sql"
+			opts.JournalMode = journal_mode
+			db, err := data.SetupDatabase(b.TempDir()+"/bench.db", opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+			s := &server.MessagingServer{Db: db}
+			if err = createTestUsers(s, "123-456", "654-321"); err != nil {
+				b.Fatal(err)
+			}
+			send_req := &messagingv1.SendDirectMessageRequest{
+				Message: &messagingv1.Message{
+					Sender:   "123-456",
+					Receiver: "654-321",
+					Content:  "Hello!!!",
+				},
+			}
+			get_req := &messagingv1.GetDMsRequest{
+				UserA:    "123-456",
+				UserB:    "654-321",
+				FromDate: time.Now().Add(-time.Minute).Format(time.DateTime),
+			}
+			// END SETUP
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var err error
+				for i := 0; pb.Next(); i++ {
+					if i%4 == 0 {
+						_, err = server.DoSendDirectMessageWork(s.Db, context.TODO(), send_req)
+					} else {
+						_, err = server.DoGetDMsWork(s.Db, context.TODO(), get_req)
+					}
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+		})
+	}
 }
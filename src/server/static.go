@@ -0,0 +1,155 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vl0000/gomessenger/logging"
+)
+
+// SvelteKit puts a content hash in the names of files below this directory,
+// so they never change
+const IMMUTABLE_PREFIX string = "static/_app/immutable/"
+
+// Precompressed variants, in order of preference
+var encodings = []struct{ name, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Serves files from the frontend with ETags and precompressed variants
+type staticFiles struct {
+	fsys fs.FS
+	// Files on disk can change, so their ETags are only cached for embedded files
+	cache bool
+	etags sync.Map
+}
+
+func newStaticFiles(fsys fs.FS, cache bool) *staticFiles {
+	return &staticFiles{fsys: fsys, cache: cache}
+}
+
+// Serves the files in static/ by their path
+func (sf *staticFiles) ServeStatic(w http.ResponseWriter, r *http.Request) {
+	name := path.Join("static", path.Clean("/" + r.URL.Path)[1:])
+	sf.serve(w, r, name)
+}
+
+// Serves a single file whatever the request's path is
+func (sf *staticFiles) ServeFile(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sf.serve(w, r, name)
+	}
+}
+
+func (sf *staticFiles) serve(w http.ResponseWriter, r *http.Request, name string) {
+	content_type := mime.TypeByExtension(path.Ext(name))
+	if content_type == "" {
+		content_type = "application/octet-stream"
+	}
+
+	file, encoding, err := sf.open(name, r.Header.Get("Accept-Encoding"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		logging.FromContext(r.Context()).Warn("Static file is not seekable", "path", name)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	etag, err := sf.etag(name+encodingExt(encoding), content)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("Could not hash static file", "path", name, "error", err)
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", content_type)
+	header.Set("Vary", "Accept-Encoding")
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+	if strings.HasPrefix(name, IMMUTABLE_PREFIX) {
+		header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+
+	// Embedded files have no modification time, ETags make requests conditional
+	http.ServeContent(w, r, "", time.Time{}, content)
+}
+
+// Opens the best precompressed variant of name that the client accepts, or name itself
+func (sf *staticFiles) open(name string, accept_encoding string) (fs.File, string, error) {
+	for _, encoding := range encodings {
+		if !acceptsEncoding(accept_encoding, encoding.name) {
+			continue
+		}
+		if file, err := sf.fsys.Open(name + encoding.ext); err == nil {
+			return file, encoding.name, nil
+		}
+	}
+	file, err := sf.fsys.Open(name)
+	return file, "", err
+}
+
+// Strong ETag from the SHA-256 of the content. Leaves content at its start
+func (sf *staticFiles) etag(key string, content io.ReadSeeker) (string, error) {
+	if etag, ok := sf.etags.Load(key); ok && sf.cache {
+		return etag.(string), nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	if sf.cache {
+		sf.etags.Store(key, etag)
+	}
+	return etag, nil
+}
+
+func encodingExt(encoding string) string {
+	for _, e := range encodings {
+		if e.name == encoding {
+			return e.ext
+		}
+	}
+	return ""
+}
+
+// Reports whether an Accept-Encoding header allows encoding, ignoring q-values except q=0
+func acceptsEncoding(accept_encoding string, encoding string) bool {
+	for _, part := range strings.Split(accept_encoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
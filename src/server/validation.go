@@ -1,25 +1,35 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"regexp"
+	"slices"
 	"time"
 
 	"connectrpc.com/connect"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/vl0000/gomessenger/email"
 	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/push"
+	"github.com/vl0000/gomessenger/webhooks"
 )
 
-func (s *MessagingServer) validateLoginRequest(req *connect.Request[messagingv1.LoginRequest]) error {
+func (s *MessagingServer) validateLoginRequest(ctx context.Context, req *connect.Request[messagingv1.LoginRequest]) error {
 	if req.Msg.Password == "" || req.Msg.PhoneNumber == "" {
 		return connect.NewError(connect.CodeInvalidArgument, &connect.Error{})
 	}
 	return nil
 }
 
-func (s *MessagingServer) validateRegistrationRequest(req *connect.Request[messagingv1.RegisterUserRequest]) error {
+func (s *MessagingServer) validateRegistrationRequest(ctx context.Context, req *connect.Request[messagingv1.RegisterUserRequest]) error {
 	if req.Msg.Password == "" || req.Msg.PhoneNumber == "" || req.Msg.Username == "" {
 		return connect.NewError(connect.CodeInvalidArgument, &connect.Error{})
 	}
-	exists, err := CheckUserExists(s.Db, req.Msg.PhoneNumber)
+	exists, err := CheckUserExists(s.Db, ctx, req.Msg.PhoneNumber)
 	if err != nil || exists {
 		return connect.NewError(connect.CodeAlreadyExists, err)
 	}
@@ -30,6 +40,7 @@ func (s *MessagingServer) validateRegistrationRequest(req *connect.Request[messa
 }
 
 func (s *MessagingServer) validateSendDirectMessageRequest(
+	ctx context.Context,
 	req *connect.Request[messagingv1.SendDirectMessageRequest],
 ) error {
 	if req.Msg.Message.Sender == "" || req.Msg.Message.Receiver == "" || req.Msg.Message.Content == "" {
@@ -38,6 +49,9 @@ func (s *MessagingServer) validateSendDirectMessageRequest(
 	if req.Msg.Message.Sender == req.Msg.Message.Receiver {
 		return connect.NewError(connect.CodeInvalidArgument, nil)
 	}
+	if req.Msg.TtlSeconds != nil && *req.Msg.TtlSeconds == 0 {
+		return connect.NewError(connect.CodeInvalidArgument, nil)
+	}
 
 	jwt_str := req.Header().Get("Authorization")
 	token, err := s.TokenAuth.Decode(jwt_str)
@@ -53,16 +67,14 @@ func (s *MessagingServer) validateSendDirectMessageRequest(
 		return connect.NewError(connect.CodeUnauthenticated, nil)
 	}
 
-	exists, err := CheckUserExists(s.Db, token.Subject())
-	if err != nil || !exists {
+	if err = CheckSession(s.Db, ctx, token.Subject(), token.IssuedAt()); err != nil {
 		return connect.NewError(connect.CodeUnauthenticated, err)
 	}
 
 	return nil
 }
 
-func (s *MessagingServer) validateGetDMsRequest(req *connect.Request[messagingv1.GetDMsRequest]) error {
-
+func (s *MessagingServer) validateGetDMsRequest(ctx context.Context, req *connect.Request[messagingv1.GetDMsRequest]) error {
 	jwt_str := req.Header().Get("Authorization")
 	token, err := s.TokenAuth.Decode(jwt_str)
 	if err != nil {
@@ -73,8 +85,7 @@ func (s *MessagingServer) validateGetDMsRequest(req *connect.Request[messagingv1
 		return connect.NewError(connect.CodeUnauthenticated, nil)
 	}
 
-	exists, err := CheckUserExists(s.Db, token.Subject())
-	if err != nil || !exists {
+	if err = CheckSession(s.Db, ctx, token.Subject(), token.IssuedAt()); err != nil {
 		return connect.NewError(connect.CodeUnauthenticated, err)
 	}
 
@@ -85,22 +96,336 @@ func (s *MessagingServer) validateGetDMsRequest(req *connect.Request[messagingv1
 	return nil
 }
 
-func (s *MessagingServer) validateGetUserInfo(req *connect.Request[messagingv1.GetUserInfoRequest]) error {
-
+func (s *MessagingServer) validateGetUserInfo(ctx context.Context, req *connect.Request[messagingv1.GetUserInfoRequest]) error {
 	jwt_str := req.Header().Get("Authorization")
 	token, err := s.TokenAuth.Decode(jwt_str)
 	if err != nil {
 		return connect.NewError(connect.CodeUnauthenticated, err)
 	}
 
-	exists, err := CheckUserExists(s.Db, token.Subject())
-	if err != nil || !exists {
+	err = CheckSession(s.Db, ctx, token.Subject(), token.IssuedAt())
+	if errors.Is(err, ErrUserNotFound) {
 		return connect.NewError(connect.CodeNotFound, err)
+	} else if err != nil {
+		return connect.NewError(connect.CodeUnauthenticated, err)
+	}
+
+	if token.Expiration().Before(time.Now()) {
+		return connect.NewError(connect.CodeUnauthenticated, nil)
+	}
+
+	return nil
+}
+
+// Browsers cannot set headers on WebSockets and EventSources, so the token may
+// also be sent as the token parameter
+func streamToken(r *http.Request) string {
+	if jwt_str := r.Header.Get("Authorization"); jwt_str != "" {
+		return jwt_str
+	}
+	return r.URL.Query().Get("token")
+}
+
+// Authenticates the requests that open /ws and /events. API keys are accepted
+// when they have scope, and never when scope is empty
+func (s *MessagingServer) validateStreamRequest(ctx context.Context, r *http.Request, scope string) (token jwt.Token, err error) {
+	jwt_str := streamToken(r)
+	if IsAPIKey(jwt_str) {
+		if jwt_str, err = s.exchangeAPIKey(ctx, jwt_str, scope); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err = s.TokenAuth.Decode(jwt_str)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, err)
+	}
+
+	if token.Expiration().Before(time.Now()) {
+		return nil, connect.NewError(connect.CodeUnauthenticated, nil)
+	}
+
+	if err = CheckSession(s.Db, ctx, token.Subject(), token.IssuedAt()); err != nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, err)
+	}
+
+	return token, nil
+}
+
+// Shared by procedures that act on resources of the caller, like webhooks and
+// bots. Returns the caller's token so they can tell who that is
+func (s *MessagingServer) validateSessionRequest(ctx context.Context, header http.Header) (token jwt.Token, err error) {
+	token, err = s.TokenAuth.Decode(header.Get("Authorization"))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, err)
+	}
+
+	if token.Expiration().Before(time.Now()) {
+		return nil, connect.NewError(connect.CodeUnauthenticated, nil)
+	}
+
+	if err = CheckSession(s.Db, ctx, token.Subject(), token.IssuedAt()); err != nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, err)
+	}
+
+	return token, nil
+}
+
+func (s *MessagingServer) validateCreateWebhookRequest(
+	ctx context.Context,
+	req *connect.Request[messagingv1.CreateWebhookRequest],
+) (token jwt.Token, err error) {
+	if token, err = s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(req.Msg.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("URL must be absolute http or https"))
+	}
+	if err = webhooks.ValidateEvents(req.Msg.Events); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	if req.Msg.Global {
+		if admin, err := s.isAdmin(ctx, token); err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		} else if !admin {
+			return nil, connect.NewError(connect.CodePermissionDenied, nil)
+		}
+	}
+	if !req.Msg.Global && slices.Contains(req.Msg.Events, webhooks.EVENT_USER_REGISTERED) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("Only global webhooks receive user.registered"))
+	}
+
+	return token, nil
+}
+
+func (s *MessagingServer) validateCreateIncomingWebhookRequest(
+	ctx context.Context,
+	req *connect.Request[messagingv1.CreateIncomingWebhookRequest],
+) (token jwt.Token, err error) {
+	if token, err = s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if req.Msg.Receiver == "" || req.Msg.Receiver == token.Subject() {
+		return nil, connect.NewError(connect.CodeInvalidArgument, nil)
+	}
+
+	exists, err := CheckUserExists(s.Db, ctx, req.Msg.Receiver)
+	if err != nil || !exists {
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	}
+
+	return token, nil
+}
+
+func (s *MessagingServer) validateRegisterPushSubscriptionRequest(
+	ctx context.Context,
+	req *connect.Request[messagingv1.RegisterPushSubscriptionRequest],
+) (token jwt.Token, err error) {
+	if token, err = s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if s.Push == nil {
+		return nil, connect.NewError(connect.CodeUnimplemented, ErrPushDisabled)
+	}
+
+	sub := req.Msg.Subscription
+	if sub == nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, nil)
+	}
+	target, err := url.Parse(sub.Endpoint)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("Endpoint must be absolute http or https"))
+	}
+	err = push.ValidateSubscription(push.Subscription{Endpoint: sub.Endpoint, P256dh: sub.P256Dh, Auth: sub.Auth})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return token, nil
+}
+
+func (s *MessagingServer) validateSetEmailRequest(
+	ctx context.Context,
+	req *connect.Request[messagingv1.SetEmailRequest],
+) (token jwt.Token, err error) {
+	if token, err = s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	// Addresses can be removed while email is disabled
+	if req.Msg.Email == "" {
+		return token, nil
+	}
+	if s.Email == nil {
+		return nil, connect.NewError(connect.CodeUnimplemented, ErrEmailDisabled)
+	}
+	if _, err = email.ValidateAddress(req.Msg.Email); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return token, nil
+}
+
+func (s *MessagingServer) validateSetNotificationPreferencesRequest(
+	ctx context.Context,
+	req *connect.Request[messagingv1.SetNotificationPreferencesRequest],
+) (token jwt.Token, err error) {
+	if token, err = s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if req.Msg.EmailDigest != nil {
+		if _, ok := DIGEST_FREQUENCIES[*req.Msg.EmailDigest]; !ok {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("email_digest must be off, hourly, daily or weekly"))
+		}
+	}
+
+	return token, nil
+}
+
+var bot_command_regex = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+const (
+	MAX_BOT_COMMANDS            int = 50
+	MAX_BOT_COMMAND_DESCRIPTION int = 200
+)
+
+func (s *MessagingServer) validateSetBotCommandsRequest(
+	ctx context.Context,
+	req *connect.Request[messagingv1.SetBotCommandsRequest],
+) (token jwt.Token, err error) {
+	if token, err = s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if req.Msg.BotId == "" || len(req.Msg.Commands) > MAX_BOT_COMMANDS {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("Bots can have at most %d commands", MAX_BOT_COMMANDS))
+	}
+
+	names := map[string]bool{}
+	for _, command := range req.Msg.Commands {
+		if !bot_command_regex.MatchString(command.Name) {
+			return nil, connect.NewError(connect.CodeInvalidArgument,
+				fmt.Errorf("Command %q must be up to 32 lowercase letters, digits, dashes and underscores", command.Name))
+		}
+		if isBuiltinCommand(command.Name) || names[command.Name] {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("Command /%s is already taken", command.Name))
+		}
+		if len(command.Description) > MAX_BOT_COMMAND_DESCRIPTION {
+			return nil, connect.NewError(connect.CodeInvalidArgument,
+				fmt.Errorf("Descriptions can have at most %d bytes", MAX_BOT_COMMAND_DESCRIPTION))
+		}
+		names[command.Name] = true
+	}
+
+	return token, nil
+}
+
+var bot_name_regex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{2,31}$`)
+
+// Returns an error unless there is at least one scope and every scope is one of SCOPES
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("An API key needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(SCOPES, scope) {
+			return fmt.Errorf("Unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func (s *MessagingServer) validateCreateBotRequest(
+	ctx context.Context,
+	req *connect.Request[messagingv1.CreateBotRequest],
+) (token jwt.Token, err error) {
+	if token, err = s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if role, _ := token.Get("role"); role == ROLE_BOT {
+		return nil, connect.NewError(connect.CodePermissionDenied, errors.New("Bots cannot own bots"))
+	}
+
+	if !bot_name_regex.MatchString(req.Msg.Name) || req.Msg.Username == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument,
+			errors.New("Name must be 3 to 32 lowercase letters, digits and dashes, and username is required"))
+	}
+	if err = validateScopes(req.Msg.Scopes); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	exists, err := CheckUserExists(s.Db, ctx, BOT_ID_PREFIX+req.Msg.Name)
+	if err != nil || exists {
+		return nil, connect.NewError(connect.CodeAlreadyExists, err)
+	}
+
+	var owned int
+	err = s.Db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE bot_owner = ?;`, token.Subject()).Scan(&owned)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+	if owned >= MAX_BOTS_PER_OWNER {
+		return nil, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("Users can own at most %d bots", MAX_BOTS_PER_OWNER))
+	}
+
+	return token, nil
+}
+
+func (s *MessagingServer) validateRotateBotKeyRequest(
+	ctx context.Context,
+	req *connect.Request[messagingv1.RotateBotKeyRequest],
+) (token jwt.Token, err error) {
+	if token, err = s.validateSessionRequest(ctx, req.Header()); err != nil {
+		return nil, err
+	}
+	if req.Msg.BotId == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, nil)
+	}
+	if len(req.Msg.Scopes) > 0 {
+		if err = validateScopes(req.Msg.Scopes); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
+
+	return token, nil
+}
+
+// Shared by every AdminService procedure
+func (s *MessagingServer) validateAdminRequest(ctx context.Context, header http.Header) error {
+	jwt_str := header.Get("Authorization")
+	token, err := s.TokenAuth.Decode(jwt_str)
+	if err != nil {
+		return connect.NewError(connect.CodeUnauthenticated, err)
 	}
 
 	if token.Expiration().Before(time.Now()) {
 		return connect.NewError(connect.CodeUnauthenticated, nil)
 	}
 
+	if role, ok := token.Get("role"); !ok || role != ROLE_ADMIN {
+		return connect.NewError(connect.CodePermissionDenied, nil)
+	}
+
+	if err = CheckSession(s.Db, ctx, token.Subject(), token.IssuedAt()); err != nil {
+		return connect.NewError(connect.CodeUnauthenticated, err)
+	}
+
+	if admin, err := s.isAdmin(ctx, token); err != nil {
+		return connect.NewError(connect.CodeInternal, err)
+	} else if !admin {
+		return connect.NewError(connect.CodePermissionDenied, nil)
+	}
+
 	return nil
 }
+
+// Admin rights need the role claim and the role the user has now, so that
+// demoting an admin takes effect before their tokens expire
+func (s *MessagingServer) isAdmin(ctx context.Context, token jwt.Token) (bool, error) {
+	if role, _ := token.Get("role"); role != ROLE_ADMIN {
+		return false, nil
+	}
+	role, err := UserRole(s.Db, ctx, token.Subject())
+	return role == ROLE_ADMIN, err
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"connectrpc.com/connect"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/webhooks"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Creates the dispatcher that Serve() runs
+func (s *MessagingServer) SetupWebhooks(opts webhooks.Options) {
+	s.Webhooks = webhooks.New(s.Db, opts, s.webhookPayload)
+}
+
+// Builds the data of a webhook delivery when it is attempted, so that message
+// contents are not kept in the outbox
+func (s *MessagingServer) webhookPayload(ctx context.Context, event string, subject string) (any, error) {
+	var data proto.Message
+	var err error
+	switch event {
+	case webhooks.EVENT_MESSAGE_RECEIVED, webhooks.EVENT_MESSAGE_SENT:
+		data, err = s.webhookMessage(ctx, subject)
+	case webhooks.EVENT_COMMAND_INVOKED:
+		data, err = s.webhookCommand(ctx, subject)
+	case webhooks.EVENT_USER_REGISTERED:
+		data, err = DoGetUserInfoWork(s.Db, ctx, &messagingv1.GetUserInfoRequest{PhoneNumber: subject})
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			err = webhooks.ErrGone
+		}
+	default:
+		err = fmt.Errorf("Unknown webhook event %s", event)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := protojson.Marshal(data)
+	return json.RawMessage(payload), err
+}
+
+// Messages that were deleted or expired before delivery are not sent
+func (s *MessagingServer) webhookMessage(ctx context.Context, subject string) (*messagingv1.Message, error) {
+	id, err := strconv.ParseUint(subject, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &messagingv1.Message{Id: &id}
+	var timestamp string
+	var encrypted bool
+	var expires_at sql.NullString
+	err = s.Db.QueryRowContext(ctx, `SELECT sender, receiver, content, encrypted, timestamp, expires_at FROM messages
+		WHERE id = ? AND (expires_at IS NULL OR expires_at > datetime('now'));`, id).
+		Scan(&message.Sender, &message.Receiver, &message.Content, &encrypted, &timestamp, &expires_at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, webhooks.ErrGone
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Retrying will not make the message readable
+	message.Content, err = s.Db.DecryptContent(ctx, message.Sender, message.Receiver, message.Content, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("%w -> %s", webhooks.ErrGone, err)
+	}
+	message.Timestamp = &timestamp
+	if expires_at.Valid {
+		message.ExpiresAt = &expires_at.String
+	}
+	return message, nil
+}
+
+func (s *MessagingServer) webhookCommand(ctx context.Context, subject string) (*messagingv1.CommandInvocation, error) {
+	message, err := s.webhookMessage(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	command, _ := ParseCommand(message.Content)
+	return &messagingv1.CommandInvocation{
+		Bot:     message.Receiver,
+		Name:    command.Name,
+		Args:    command.Args,
+		Message: message,
+	}, nil
+}
+
+// Wakes the dispatcher after an event was added to the outbox
+func (s *MessagingServer) notifyWebhooks() {
+	if s.Webhooks != nil {
+		s.Webhooks.Notify()
+	}
+}
+
+func (s *MessagingServer) CreateWebhook(
+	ctx context.Context,
+	req *connect.Request[messagingv1.CreateWebhookRequest],
+) (*connect.Response[messagingv1.CreateWebhookResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateCreateWebhookRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := token.Subject()
+	if req.Msg.Global {
+		owner = ""
+	}
+	response, err := DoCreateWebhookWork(s.Db, ctx, owner, req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) ListWebhooks(
+	ctx context.Context,
+	req *connect.Request[messagingv1.ListWebhooksRequest],
+) (*connect.Response[messagingv1.ListWebhooksResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := s.isAdmin(ctx, token)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	response, err := DoListWebhooksWork(s.Db, ctx, token.Subject(), admin)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnknown, err)
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) DeleteWebhook(
+	ctx context.Context,
+	req *connect.Request[messagingv1.DeleteWebhookRequest],
+) (*connect.Response[messagingv1.DeleteWebhookResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := s.isAdmin(ctx, token)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	response, err := DoDeleteWebhookWork(s.Db, ctx, token.Subject(), admin, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (s *MessagingServer) ListWebhookDeliveries(
+	ctx context.Context,
+	req *connect.Request[messagingv1.ListWebhookDeliveriesRequest],
+) (*connect.Response[messagingv1.ListWebhookDeliveriesResponse], error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := s.validateSessionRequest(ctx, req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := s.isAdmin(ctx, token)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	response, err := DoListWebhookDeliveriesWork(s.Db, ctx, token.Subject(), admin, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(response), nil
+}
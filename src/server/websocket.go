@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/coder/websocket"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	messagingv1 "github.com/vl0000/gomessenger/gen/messaging/v1"
+	"github.com/vl0000/gomessenger/logging"
+	"github.com/vl0000/gomessenger/metrics"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Subprotocols of /ws. Server frames are JSON unless the client asks for protobuf
+const (
+	WS_JSON_SUBPROTOCOL  string = "gomessenger.v1.json"
+	WS_PROTO_SUBPROTOCOL string = "gomessenger.v1.proto"
+)
+
+// Label of /ws connections in the active streams metric
+const WS_METRICS_LABEL string = "/ws"
+
+// How long writing a frame may take before the connection is given up on
+const STREAM_SEND_TIMEOUT time.Duration = 5 * time.Second
+
+// Gateway for clients that cannot keep a Connect stream open. Every frame is
+// checked by the same validation as the matching procedure, and subscriptions
+// receive the updates GetDMs() streams would through s.Conns
+func (s *MessagingServer) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Frames are validated with the connection's token, so API keys would need
+	// a token that outlives BOT_TOKEN_DURATION. Bots use /events instead
+	token, err := s.validateStreamRequest(r.Context(), r, "")
+	if err != nil {
+		http.Error(w, "Unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		Subprotocols: []string{WS_JSON_SUBPROTOCOL, WS_PROTO_SUBPROTOCOL},
+	})
+	if err != nil {
+		// Accept already answered the request
+		return
+	}
+	defer conn.CloseNow()
+
+	metrics.ActiveStreams.WithLabelValues(WS_METRICS_LABEL).Inc()
+	defer metrics.ActiveStreams.WithLabelValues(WS_METRICS_LABEL).Dec()
+
+	// The connection is hijacked, so it must outlive the request timeout
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+	ctx = logging.Annotate(ctx, logging.PROCEDURE_KEY, WS_METRICS_LABEL, logging.USER_KEY, token.Subject())
+
+	session := &wsSession{
+		s:       s,
+		conn:    conn,
+		token:   token,
+		jwt_str: streamToken(r),
+		binary:  conn.Subprotocol() == WS_PROTO_SUBPROTOCOL,
+		subs:    map[string]context.CancelFunc{},
+	}
+	ended := s.sessionEnded(token.Subject())
+	go func() {
+		select {
+		case <-s.stop:
+			conn.Close(websocket.StatusServiceRestart, ErrDraining.Error())
+		case <-ended:
+			conn.Close(websocket.StatusPolicyViolation, ErrSessionEnded.Error())
+		case <-ctx.Done():
+		}
+	}()
+
+	s.markActive(ctx, token.Subject())
+	logging.FromContext(ctx).Debug("stream opened")
+	err = session.readFrames(ctx)
+	logging.FromContext(ctx).Debug("stream closed", "reason", err)
+}
+
+type wsSession struct {
+	s     *MessagingServer
+	conn  *websocket.Conn
+	token jwt.Token
+	// Sent with the frames that are validated like procedures
+	jwt_str string
+	binary  bool
+	// Cancels the subscription to the chat with each user
+	subs   map[string]context.CancelFunc
+	subsMu sync.Mutex
+}
+
+// Handles client frames until the connection closes
+func (ws *wsSession) readFrames(ctx context.Context) error {
+	defer ws.unsubscribeAll()
+
+	for {
+		kind, payload, err := ws.conn.Read(ctx)
+		if err != nil {
+			return err
+		}
+
+		frame := &messagingv1.ClientFrame{}
+		if kind == websocket.MessageBinary {
+			err = proto.Unmarshal(payload, frame)
+		} else {
+			err = protojson.Unmarshal(payload, frame)
+		}
+		if err != nil {
+			ws.writeError(ctx, "", connect.NewError(connect.CodeInvalidArgument, err))
+			continue
+		}
+
+		if err = ws.handleFrame(ctx, frame); err != nil {
+			ws.writeError(ctx, frame.Id, err)
+		}
+	}
+}
+
+func (ws *wsSession) handleFrame(ctx context.Context, frame *messagingv1.ClientFrame) error {
+	switch f := frame.Frame.(type) {
+	case *messagingv1.ClientFrame_Subscribe:
+		return ws.subscribe(ctx, frame.Id, f.Subscribe)
+
+	case *messagingv1.ClientFrame_Send:
+		if f.Send.Message == nil {
+			return connect.NewError(connect.CodeInvalidArgument, nil)
+		}
+		req := connect.NewRequest(f.Send)
+		req.Header().Set("Authorization", ws.jwt_str)
+		res, err := ws.s.SendDirectMessage(ctx, req)
+		if err != nil {
+			return err
+		}
+		return ws.write(ctx, &messagingv1.ServerFrame{
+			Id:    frame.Id,
+			UserB: f.Send.Message.Receiver,
+			Frame: &messagingv1.ServerFrame_Sent{Sent: res.Msg},
+		})
+
+	case *messagingv1.ClientFrame_Typing:
+		if err := ws.checkSession(ctx, f.Typing.Receiver); err != nil {
+			return err
+		}
+		ws.s.notifyStream(f.Typing.Receiver, ws.token.Subject(), &messagingv1.GetDMsResponse{Typing: true})
+		return nil
+
+	case *messagingv1.ClientFrame_Ack:
+		if err := ws.checkSession(ctx, f.Ack.Sender); err != nil {
+			return err
+		}
+		ws.s.notifyStream(f.Ack.Sender, ws.token.Subject(), &messagingv1.GetDMsResponse{AckedIds: f.Ack.MessageIds})
+		return nil
+	}
+
+	return connect.NewError(connect.CodeInvalidArgument, errors.New("Empty frame"))
+}
+
+// Sends the history like GetDMs() does, then forwards the chat's updates until
+// the connection closes or the chat is subscribed to again
+func (ws *wsSession) subscribe(ctx context.Context, id string, msg *messagingv1.GetDMsRequest) error {
+	req := connect.NewRequest(msg)
+	req.Header().Set("Authorization", ws.jwt_str)
+	if err := ws.s.validateGetDMsRequest(ctx, req); err != nil {
+		return err
+	}
+
+	res, err := DoGetDMsWork(ws.s.Db, ctx, msg)
+	if err != nil {
+		return connect.NewError(connect.CodeUnknown, err)
+	}
+	if err = ws.write(ctx, &messagingv1.ServerFrame{
+		Id:    id,
+		UserB: msg.UserB,
+		Frame: &messagingv1.ServerFrame_Dms{Dms: res},
+	}); err != nil {
+		return err
+	}
+
+	key := msg.UserA + msg.UserB
+	channel := make(chan *messagingv1.GetDMsResponse, CHANNEL_SIZE)
+	ws.s.ConnsMu.Lock()
+	ws.s.Conns[key] = channel
+	ws.s.ConnsMu.Unlock()
+
+	sub_ctx, cancel := context.WithCancel(ctx)
+	ws.subsMu.Lock()
+	if previous, ok := ws.subs[msg.UserB]; ok {
+		previous()
+	}
+	ws.subs[msg.UserB] = cancel
+	ws.subsMu.Unlock()
+
+	go func() {
+		defer ws.s.removeStream(key, channel)
+		for {
+			select {
+			case res := <-channel:
+				err := ws.write(sub_ctx, &messagingv1.ServerFrame{
+					UserB: msg.UserB,
+					Frame: &messagingv1.ServerFrame_Dms{Dms: res},
+				})
+				if err != nil {
+					return
+				}
+			case <-sub_ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (ws *wsSession) unsubscribeAll() {
+	ws.subsMu.Lock()
+	defer ws.subsMu.Unlock()
+	for _, cancel := range ws.subs {
+		cancel()
+	}
+}
+
+// Typing and ack frames have no procedure to validate them, so they only
+// check that the connection's token is still valid
+func (ws *wsSession) checkSession(ctx context.Context, other string) error {
+	if other == "" || other == ws.token.Subject() {
+		return connect.NewError(connect.CodeInvalidArgument, nil)
+	}
+	if err := CheckSession(ws.s.Db, ctx, ws.token.Subject(), ws.token.IssuedAt()); err != nil {
+		return connect.NewError(connect.CodeUnauthenticated, err)
+	}
+	return nil
+}
+
+func (ws *wsSession) write(ctx context.Context, frame *messagingv1.ServerFrame) error {
+	ctx, cancel := context.WithTimeout(ctx, STREAM_SEND_TIMEOUT)
+	defer cancel()
+
+	if ws.binary {
+		payload, err := proto.Marshal(frame)
+		if err != nil {
+			return err
+		}
+		return ws.conn.Write(ctx, websocket.MessageBinary, payload)
+	}
+	payload, err := protojson.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return ws.conn.Write(ctx, websocket.MessageText, payload)
+}
+
+func (ws *wsSession) writeError(ctx context.Context, id string, err error) {
+	logging.FromContext(ctx).Warn("frame failed", "code", connect.CodeOf(err).String(), "error", err)
+
+	message := err.Error()
+	var connect_err *connect.Error
+	if errors.As(err, &connect_err) {
+		message = connect_err.Message()
+	}
+	ws.write(ctx, &messagingv1.ServerFrame{
+		Id: id,
+		Frame: &messagingv1.ServerFrame_Error{Error: &messagingv1.FrameError{
+			Code:    connect.CodeOf(err).String(),
+			Message: message,
+		}},
+	})
+}
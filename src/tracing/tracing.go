@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACER_NAME string = "github.com/vl0000/gomessenger"
+
+type Options struct {
+	// none, stdout or otlp
+	Exporter string
+	// host:port of an OTLP/HTTP collector, e.g. localhost:4318
+	Endpoint string
+	// Sends to the collector over plain HTTP
+	Insecure    bool
+	ServiceName string
+	// Fraction of new traces that are recorded. Traces started by clients follow their decision
+	SampleRatio float64
+	// Where the stdout exporter writes, os.Stdout when nil
+	Writer io.Writer
+}
+
+// Installs the global tracer provider and the W3C trace context propagator.
+// The returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(opts.Exporter) {
+	case "none", "":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		stdout_opts := []stdouttrace.Option{stdouttrace.WithPrettyPrint()}
+		if opts.Writer != nil {
+			stdout_opts = append(stdout_opts, stdouttrace.WithWriter(opts.Writer))
+		}
+		exporter, err = stdouttrace.New(stdout_opts...)
+	case "otlp":
+		otlp_opts := []otlptracehttp.Option{}
+		if opts.Endpoint != "" {
+			otlp_opts = append(otlp_opts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			otlp_opts = append(otlp_opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, otlp_opts...)
+	default:
+		return nil, fmt.Errorf("Unknown trace exporter %q, expected none, stdout or otlp", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Tracing -> %s", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(opts.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Starts a child span of whatever span ctx carries
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TRACER_NAME).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Ends span, marking it as failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Wraps HTTP handlers that are not Connect procedures in a server span named
+// name. The W3C trace context of the request is honoured like otelconnect does
+func Middleware(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := otel.Tracer(TRACER_NAME).Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method)),
+			)
+			defer span.End()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
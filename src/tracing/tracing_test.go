@@ -0,0 +1,89 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vl0000/gomessenger/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	t.Run("Spans are exported with their errors", func(t *testing.T) {
+		// SETUP
+		var out bytes.Buffer
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+			Exporter:    "stdout",
+			ServiceName: "gomessenger-test",
+			SampleRatio: 1,
+			Writer:      &out,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		ctx, parent := tracing.Start(context.Background(), "parent")
+		_, child := tracing.Start(ctx, "child")
+		tracing.End(child, errors.New("child failed"))
+		tracing.End(parent, nil)
+
+		if err := shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		exported := out.String()
+		for _, want := range []string{`"Name": "parent"`, `"Name": "child"`, "child failed", "gomessenger-test"} {
+			if !strings.Contains(exported, want) {
+				t.Errorf("Exported spans do not contain %s", want)
+			}
+		}
+		if child.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Error("Child span is not part of the parent's trace")
+		}
+	})
+
+	t.Run("Middleware continues the trace of the request", func(t *testing.T) {
+		// SETUP
+		var out bytes.Buffer
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+			Exporter:    "stdout",
+			ServiceName: "gomessenger-test",
+			SampleRatio: 1,
+			Writer:      &out,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// END SETUP
+
+		var span trace.SpanContext
+		handler := tracing.Middleware("/events")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span = trace.SpanContextFromContext(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if err := shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if span.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatalf("Expected the client's trace, got %s", span.TraceID())
+		}
+		if !strings.Contains(out.String(), `"Name": "/events"`) {
+			t.Fatal("Span of the request was not exported")
+		}
+	})
+
+	t.Run("Unknown exporters are rejected", func(t *testing.T) {
+		_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "zipkin"})
+		if err == nil {
+			t.Fatal("Expected an error")
+		}
+	})
+}
@@ -0,0 +1,356 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/vl0000/gomessenger/data"
+)
+
+// Events webhooks can subscribe to
+const (
+	EVENT_MESSAGE_RECEIVED string = "message.received"
+	EVENT_MESSAGE_SENT     string = "message.sent"
+	// Only sent to webhooks without an owner
+	EVENT_USER_REGISTERED string = "user.registered"
+	// Sent to the owner of the bot whose command was typed
+	EVENT_COMMAND_INVOKED string = "command.invoked"
+)
+
+var EVENTS = []string{EVENT_MESSAGE_RECEIVED, EVENT_MESSAGE_SENT, EVENT_USER_REGISTERED, EVENT_COMMAND_INVOKED}
+
+// Values of webhook_deliveries.status
+const (
+	STATUS_PENDING   string = "pending"
+	STATUS_DELIVERED string = "delivered"
+	STATUS_FAILED    string = "failed"
+)
+
+// Headers of every delivery. The signature header is "t=<unix time>,v1=<hex HMAC>"
+const (
+	SIGNATURE_HEADER string = "X-Gomessenger-Signature"
+	EVENT_HEADER     string = "X-Gomessenger-Event"
+	DELIVERY_HEADER  string = "X-Gomessenger-Delivery"
+)
+
+// How often the outbox is checked for retries when no new event woke the dispatcher
+const POLL_INTERVAL time.Duration = time.Second
+
+var (
+	ErrPrivateAddress = errors.New("Webhook URL resolves to a private address")
+	ErrBadSignature   = errors.New("Webhook signature does not match")
+	// Returned by PayloadFunc when the subject of an event no longer exists
+	ErrGone = errors.New("Subject of the event no longer exists")
+)
+
+type Options struct {
+	// Deliveries are marked failed after this many attempts
+	MaxAttempts int
+	// Wait before the first retry. It doubles with every attempt up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// Timeout of a single attempt
+	Timeout time.Duration
+	// Allows URLs on loopback and private networks. Otherwise users could make
+	// the server send requests to internal services
+	AllowPrivateAddresses bool
+}
+
+// Returns the data of a payload. subject is the value given to Enqueue()
+type PayloadFunc func(ctx context.Context, event string, subject string) (any, error)
+
+// Sends the deliveries in the outbox and retries the failed ones
+type Dispatcher struct {
+	db      *data.Store
+	opts    Options
+	payload PayloadFunc
+	client  *http.Client
+	wake    chan struct{}
+}
+
+func New(db *data.Store, opts Options, payload PayloadFunc) *Dispatcher {
+	return &Dispatcher{
+		db:      db,
+		opts:    opts,
+		payload: payload,
+		client:  NewClient(opts.Timeout, opts.AllowPrivateAddresses),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Returns a client for URLs that users chose. Unless allow_private is set it
+// refuses loopback and private addresses, so users can not make the server
+// send requests to internal services
+func NewClient(timeout time.Duration, allow_private bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	// Checked on the resolved address so DNS can not be used to get around it
+	if !allow_private {
+		dialer.Control = func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		// A redirect could lead to a private address or drop the signature
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast()
+}
+
+// Returns a random key for the signatures of a new webhook
+func NewSecret() string {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return hex.EncodeToString(secret)
+}
+
+// HMAC-SHA256 of "<timestamp>.<body>" in hex
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Checks a SIGNATURE_HEADER value the way receivers should. Signatures older
+// than tolerance are rejected so that captured requests can not be replayed
+func Verify(secret string, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == 0 || time.Since(time.Unix(timestamp, 0)).Abs() > tolerance {
+		return ErrBadSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Returns an error unless every event is one of EVENTS
+func ValidateEvents(events []string) error {
+	if len(events) == 0 {
+		return errors.New("A webhook needs at least one event")
+	}
+	for _, event := range events {
+		if !slices.Contains(EVENTS, event) {
+			return fmt.Errorf("Unknown event %q", event)
+		}
+	}
+	return nil
+}
+
+// Implemented by *sql.Tx and *data.Store
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Adds a delivery of event to the outbox for every webhook of owner, and every
+// webhook without an owner, that subscribed to it. Call it in the transaction
+// that stores the event so neither is saved without the other
+func Enqueue(ctx context.Context, tx Execer, event string, owner string, subject string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO webhook_deliveries
+		(webhook_id, event, subject, next_attempt_at, created_at)
+		SELECT id, ?, ?, datetime('now'), datetime('now') FROM webhooks WHERE
+		(owner IS NULL OR owner = ?) AND
+		',' || events || ',' LIKE '%,' || ? || ',%';`,
+		event, subject, owner, event)
+	if err != nil {
+		return fmt.Errorf("Webhooks -> %s", err)
+	}
+	return nil
+}
+
+// Makes Run() check the outbox now instead of at the next poll
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Sends due deliveries until stop is closed
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(POLL_INTERVAL)
+	defer ticker.Stop()
+
+	// Aborts the delivery in flight when stop is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Could not deliver webhooks", "error", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+type delivery struct {
+	id       int64
+	event    string
+	subject  string
+	attempts int
+	// When the event happened, in UTC
+	created_at string
+	url        string
+	secret     string
+}
+
+// Attempts every pending delivery that is due and returns how many succeeded
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT d.id, d.event, d.subject, d.attempts, d.created_at, w.url, w.secret
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = ? AND d.next_attempt_at <= datetime('now')
+		ORDER BY d.id LIMIT 100;`, STATUS_PENDING)
+	if err != nil {
+		return 0, err
+	}
+	due := []delivery{}
+	for rows.Next() {
+		var dl delivery
+		if err := rows.Scan(&dl.id, &dl.event, &dl.subject, &dl.attempts, &dl.created_at, &dl.url, &dl.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, dl)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, dl := range due {
+		code, err := d.attempt(ctx, dl)
+		// Attempts cut short by ctx are not counted and are retried later
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		if err = d.record(ctx, dl, code, err); err != nil {
+			return delivered, err
+		}
+		if code >= 200 && code < 300 {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, dl delivery) (int, error) {
+	payload, err := d.payload(ctx, dl.event, dl.subject)
+	if err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(map[string]any{
+		"id":         dl.id,
+		"event":      dl.event,
+		"created_at": dl.created_at,
+		"data":       payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gomessenger-webhooks")
+	req.Header.Set(EVENT_HEADER, dl.event)
+	req.Header.Set(DELIVERY_HEADER, strconv.FormatInt(dl.id, 10))
+	req.Header.Set(SIGNATURE_HEADER, fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(dl.secret, timestamp, body)))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("Receiver answered %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// Stores the outcome of an attempt and schedules the next one
+func (d *Dispatcher) record(ctx context.Context, dl delivery, code int, attempt_err error) error {
+	attempts := dl.attempts + 1
+	status, message := STATUS_DELIVERED, ""
+	next_attempt := time.Now().UTC()
+
+	if attempt_err != nil {
+		message = attempt_err.Error()
+		status = STATUS_PENDING
+		next_attempt = next_attempt.Add(d.RetryDelay(attempts))
+		if attempts >= d.opts.MaxAttempts || errors.Is(attempt_err, ErrGone) {
+			status = STATUS_FAILED
+		}
+		slog.Warn("Webhook delivery failed", "delivery", dl.id, "attempts", attempts, "status", status, "error", attempt_err)
+	}
+
+	_, err := d.db.ExecContext(ctx, `UPDATE webhook_deliveries SET
+		status = ?, attempts = ?, next_attempt_at = ?, response_code = ?, error = ?
+		WHERE id = ?;`,
+		status, attempts, next_attempt.Format(time.DateTime), code, message, dl.id)
+	return err
+}
+
+// Wait after the given number of failed attempts
+func (d *Dispatcher) RetryDelay(attempts int) time.Duration {
+	delay := d.opts.RetryDelay
+	for i := 1; i < attempts && delay < d.opts.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.opts.MaxRetryDelay)
+}
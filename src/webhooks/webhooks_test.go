@@ -0,0 +1,215 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vl0000/gomessenger/data"
+	"github.com/vl0000/gomessenger/webhooks"
+)
+
+func TestWebhooks(t *testing.T) {
+	// SETUP
+	os.Setenv("DB_SCHEMA_PATH", "./../data/database.sql")
+	db, err := data.SetupTestDatabase(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`INSERT INTO users (username, phone_number, password, salt) VALUES ('a', '123-456', '', '');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := func(ctx context.Context, event string, subject string) (any, error) {
+		if subject == "gone" {
+			return nil, webhooks.ErrGone
+		}
+		return map[string]string{"subject": subject}, nil
+	}
+	addWebhook := func(url string, owner any, events string) {
+		_, err := db.Exec(`INSERT INTO webhooks (owner, url, secret, events, created_at)
+			VALUES (?, ?, 'secret', ?, datetime('now'));`, owner, url, events)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	status := func(subject string) (string, int) {
+		var status string
+		var attempts int
+		err := db.QueryRow(`SELECT status, attempts FROM webhook_deliveries WHERE subject = ?;`, subject).
+			Scan(&status, &attempts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status, attempts
+	}
+	// END SETUP
+
+	t.Run("Signatures verify and expire", func(t *testing.T) {
+		body := []byte(`{"event":"message.sent"}`)
+		now := time.Now().Unix()
+		header := "t=" + strconv.FormatInt(now, 10) + ",v1=" + webhooks.Sign("secret", now, body)
+
+		if err := webhooks.Verify("secret", header, body, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if err := webhooks.Verify("other", header, body, time.Minute); !errors.Is(err, webhooks.ErrBadSignature) {
+			t.Fatal("Signature with the wrong secret was accepted")
+		}
+		if err := webhooks.Verify("secret", header, []byte(`{}`), time.Minute); !errors.Is(err, webhooks.ErrBadSignature) {
+			t.Fatal("Signature of another body was accepted")
+		}
+		old := now - 3600
+		header = "t=" + strconv.FormatInt(old, 10) + ",v1=" + webhooks.Sign("secret", old, body)
+		if err := webhooks.Verify("secret", header, body, time.Minute); !errors.Is(err, webhooks.ErrBadSignature) {
+			t.Fatal("Expired signature was accepted")
+		}
+	})
+
+	t.Run("Retry delay doubles up to the maximum", func(t *testing.T) {
+		d := webhooks.New(db, webhooks.Options{RetryDelay: time.Second, MaxRetryDelay: 5 * time.Second}, payload)
+		for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 5 * time.Second} {
+			if got := d.RetryDelay(attempts); got != want {
+				t.Errorf("Expected %s after %d attempts, got %s", want, attempts, got)
+			}
+		}
+	})
+
+	t.Run("Deliveries are signed, retried and given up on", func(t *testing.T) {
+		var mu sync.Mutex
+		received := map[string]string{}
+		fail := true
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if err := webhooks.Verify("secret", r.Header.Get(webhooks.SIGNATURE_HEADER), body, time.Minute); err != nil {
+				t.Error(err)
+			}
+			var delivery struct {
+				Event string            `json:"event"`
+				Data  map[string]string `json:"data"`
+			}
+			json.Unmarshal(body, &delivery)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if fail && delivery.Data["subject"] == "flaky" {
+				fail = false
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			received[delivery.Data["subject"]] = delivery.Event
+		}))
+		defer receiver.Close()
+		addWebhook(receiver.URL, "123-456", webhooks.EVENT_MESSAGE_SENT)
+		addWebhook(receiver.URL, nil, webhooks.EVENT_USER_REGISTERED)
+
+		d := webhooks.New(db, webhooks.Options{
+			MaxAttempts:           2,
+			Timeout:               time.Second,
+			AllowPrivateAddresses: true,
+		}, payload)
+		ctx := context.Background()
+		for subject, event := range map[string]string{
+			"flaky": webhooks.EVENT_MESSAGE_SENT,
+			"gone":  webhooks.EVENT_MESSAGE_SENT,
+			"other": webhooks.EVENT_MESSAGE_RECEIVED,
+		} {
+			if err := webhooks.Enqueue(ctx, db, event, "123-456", subject); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := webhooks.Enqueue(ctx, db, webhooks.EVENT_USER_REGISTERED, "", "654-321"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := d.DeliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if s, attempts := status("flaky"); s != webhooks.STATUS_PENDING || attempts != 1 {
+			t.Fatalf("Expected a pending retry, got %s after %d attempts", s, attempts)
+		}
+		if s, _ := status("gone"); s != webhooks.STATUS_FAILED {
+			t.Fatalf("Expected a delivery of a deleted subject to fail, got %s", s)
+		}
+
+		if _, err := d.DeliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if s, attempts := status("flaky"); s != webhooks.STATUS_DELIVERED || attempts != 2 {
+			t.Fatalf("Expected the retry to succeed, got %s after %d attempts", s, attempts)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if received["flaky"] != webhooks.EVENT_MESSAGE_SENT || received["654-321"] != webhooks.EVENT_USER_REGISTERED {
+			t.Fatalf("Unexpected deliveries %v", received)
+		}
+		if _, ok := received["other"]; ok {
+			t.Fatal("Event the webhook did not subscribe to was delivered")
+		}
+	})
+
+	t.Run("Private addresses are refused", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Private receiver was called")
+		}))
+		defer receiver.Close()
+		db.Exec(`DELETE FROM webhooks;`)
+		addWebhook(receiver.URL, "123-456", webhooks.EVENT_MESSAGE_RECEIVED)
+
+		d := webhooks.New(db, webhooks.Options{MaxAttempts: 1, Timeout: time.Second}, payload)
+		if err := webhooks.Enqueue(context.Background(), db, webhooks.EVENT_MESSAGE_RECEIVED, "123-456", "private"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.DeliverDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if s, _ := status("private"); s != webhooks.STATUS_FAILED {
+			t.Fatalf("Expected the delivery to fail, got %s", s)
+		}
+	})
+
+	t.Run("Run stops without counting the attempt in flight", func(t *testing.T) {
+		// SETUP
+		started, release := make(chan struct{}), make(chan struct{})
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}))
+		defer receiver.Close()
+		defer close(release)
+		db.Exec(`DELETE FROM webhooks;`)
+		addWebhook(receiver.URL, "123-456", webhooks.EVENT_MESSAGE_RECEIVED)
+		if err := webhooks.Enqueue(context.Background(), db, webhooks.EVENT_MESSAGE_RECEIVED, "123-456", "slow"); err != nil {
+			t.Fatal(err)
+		}
+		d := webhooks.New(db, webhooks.Options{MaxAttempts: 3, Timeout: time.Minute, AllowPrivateAddresses: true}, payload)
+		// END SETUP
+
+		stop, done := make(chan struct{}), make(chan struct{})
+		go func() {
+			d.Run(stop)
+			close(done)
+		}()
+		<-started
+		close(stop)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after stop was closed")
+		}
+		if s, attempts := status("slow"); s != webhooks.STATUS_PENDING || attempts != 0 {
+			t.Fatalf("Expected the delivery to stay pending, got %s after %d attempts", s, attempts)
+		}
+	})
+}